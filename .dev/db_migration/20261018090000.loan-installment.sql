@@ -0,0 +1,14 @@
+ALTER TABLE public.loan ADD COLUMN IF NOT EXISTS tenor INT NOT NULL DEFAULT 12;
+ALTER TABLE public.loan ADD COLUMN IF NOT EXISTS repayment_method VARCHAR NOT NULL DEFAULT 'annuity';
+
+CREATE TABLE IF NOT EXISTS public.loan_installment (
+	ID SERIAL PRIMARY KEY,
+    loan_id INTEGER NOT NULL,
+    installment_number INTEGER NOT NULL,
+    due_date TIMESTAMPTZ NOT NULL,
+    principal_amount FLOAT NOT NULL,
+    interest_amount FLOAT NOT NULL,
+    total_amount FLOAT NOT NULL,
+    outstanding_balance FLOAT NOT NULL,
+    CONSTRAINT unique_loan_installment_number UNIQUE (loan_id, installment_number)
+);
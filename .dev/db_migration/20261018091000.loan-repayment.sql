@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS public.loan_repayment (
+	ID SERIAL PRIMARY KEY,
+    loan_id INTEGER NOT NULL,
+    amount FLOAT NOT NULL,
+    principal_amount FLOAT NOT NULL,
+    interest_amount FLOAT NOT NULL,
+    payment_date TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_loan_repayment_loan_id ON public.loan_repayment (loan_id);
@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS public.loan_payout (
+	ID SERIAL PRIMARY KEY,
+    loan_id INTEGER NOT NULL,
+    repayment_id INTEGER NOT NULL,
+    invest_id INTEGER NOT NULL,
+    investor_id INTEGER NOT NULL,
+    principal_amount FLOAT NOT NULL,
+    return_amount FLOAT NOT NULL,
+    amount FLOAT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_loan_payout_investor_id ON public.loan_payout (investor_id);
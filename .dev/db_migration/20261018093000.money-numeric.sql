@@ -0,0 +1,17 @@
+-- money is stored as exact NUMERIC with 2 decimals, existing FLOAT values are rounded to the nearest minor unit
+ALTER TABLE public.loan ALTER COLUMN principal_amount TYPE NUMERIC(20,2) USING ROUND(principal_amount::NUMERIC, 2);
+
+ALTER TABLE public.loan_investment ALTER COLUMN amount TYPE NUMERIC(20,2) USING ROUND(amount::NUMERIC, 2);
+
+ALTER TABLE public.loan_installment ALTER COLUMN principal_amount TYPE NUMERIC(20,2) USING ROUND(principal_amount::NUMERIC, 2);
+ALTER TABLE public.loan_installment ALTER COLUMN interest_amount TYPE NUMERIC(20,2) USING ROUND(interest_amount::NUMERIC, 2);
+ALTER TABLE public.loan_installment ALTER COLUMN total_amount TYPE NUMERIC(20,2) USING ROUND(total_amount::NUMERIC, 2);
+ALTER TABLE public.loan_installment ALTER COLUMN outstanding_balance TYPE NUMERIC(20,2) USING ROUND(outstanding_balance::NUMERIC, 2);
+
+ALTER TABLE public.loan_repayment ALTER COLUMN amount TYPE NUMERIC(20,2) USING ROUND(amount::NUMERIC, 2);
+ALTER TABLE public.loan_repayment ALTER COLUMN principal_amount TYPE NUMERIC(20,2) USING ROUND(principal_amount::NUMERIC, 2);
+ALTER TABLE public.loan_repayment ALTER COLUMN interest_amount TYPE NUMERIC(20,2) USING ROUND(interest_amount::NUMERIC, 2);
+
+ALTER TABLE public.loan_payout ALTER COLUMN principal_amount TYPE NUMERIC(20,2) USING ROUND(principal_amount::NUMERIC, 2);
+ALTER TABLE public.loan_payout ALTER COLUMN return_amount TYPE NUMERIC(20,2) USING ROUND(return_amount::NUMERIC, 2);
+ALTER TABLE public.loan_payout ALTER COLUMN amount TYPE NUMERIC(20,2) USING ROUND(amount::NUMERIC, 2);
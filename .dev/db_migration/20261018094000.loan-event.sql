@@ -0,0 +1,17 @@
+CREATE TABLE IF NOT EXISTS public.loan_event (
+	ID BIGSERIAL PRIMARY KEY,
+    loan_id INTEGER NOT NULL,
+    event VARCHAR NOT NULL,
+    actor VARCHAR NOT NULL DEFAULT '',
+    from_status INT,
+    to_status INT,
+    payload JSONB NOT NULL DEFAULT '{}',
+    request_id VARCHAR NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_loan_event_loan_id ON public.loan_event (loan_id);
+
+-- events are immutable, updates and deletes are silently ignored
+CREATE OR REPLACE RULE loan_event_no_update AS ON UPDATE TO public.loan_event DO INSTEAD NOTHING;
+CREATE OR REPLACE RULE loan_event_no_delete AS ON DELETE TO public.loan_event DO INSTEAD NOTHING;
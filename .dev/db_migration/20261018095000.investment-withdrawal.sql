@@ -0,0 +1,2 @@
+ALTER TABLE public.loan_investment ADD COLUMN IF NOT EXISTS withdrawn_at TIMESTAMPTZ;
+ALTER TABLE public.loan_investment ADD COLUMN IF NOT EXISTS withdrawal_reason VARCHAR;
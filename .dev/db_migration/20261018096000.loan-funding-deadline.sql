@@ -0,0 +1,3 @@
+ALTER TABLE public.loan ADD COLUMN IF NOT EXISTS funding_deadline TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS idx_loan_status_funding_deadline ON public.loan (status, funding_deadline);
@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS public.borrower (
+	ID SERIAL PRIMARY KEY,
+    name VARCHAR NOT NULL,
+    email VARCHAR NOT NULL UNIQUE,
+    phone_number VARCHAR NOT NULL DEFAULT '',
+    kyc_status VARCHAR NOT NULL DEFAULT 'pending',
+    credit_limit NUMERIC(20,2) NOT NULL DEFAULT 0,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_loan_borrower_id ON public.loan (borrower_id);
@@ -0,0 +1,27 @@
+CREATE TABLE IF NOT EXISTS public.investor (
+	ID SERIAL PRIMARY KEY,
+    name VARCHAR NOT NULL,
+    email VARCHAR NOT NULL UNIQUE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS public.investor_wallet (
+    investor_id INTEGER PRIMARY KEY REFERENCES public.investor (id),
+    balance NUMERIC(20,2) NOT NULL DEFAULT 0 CHECK (balance >= 0),
+    held NUMERIC(20,2) NOT NULL DEFAULT 0 CHECK (held >= 0 AND held <= balance),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS public.investor_wallet_transaction (
+	ID BIGSERIAL PRIMARY KEY,
+    investor_id INTEGER NOT NULL,
+    type VARCHAR NOT NULL,
+    amount NUMERIC(20,2) NOT NULL,
+    loan_id INTEGER,
+    invest_id INTEGER,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_investor_wallet_transaction_investor_id ON public.investor_wallet_transaction (investor_id);
+
+ALTER TABLE public.loan_investment ALTER COLUMN investor_id TYPE INTEGER USING investor_id::INTEGER;
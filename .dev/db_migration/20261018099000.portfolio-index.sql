@@ -0,0 +1,2 @@
+CREATE INDEX IF NOT EXISTS idx_loan_investment_investor_id ON public.loan_investment (investor_id);
+CREATE INDEX IF NOT EXISTS idx_loan_payout_invest_id ON public.loan_payout (invest_id);
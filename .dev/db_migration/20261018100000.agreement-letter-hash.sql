@@ -0,0 +1,2 @@
+ALTER TABLE public.loan ADD COLUMN IF NOT EXISTS agreement_letter_sha256 VARCHAR(64) NOT NULL DEFAULT '';
+ALTER TABLE public.loan ADD COLUMN IF NOT EXISTS agreement_letter_version VARCHAR NOT NULL DEFAULT '';
@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS public.outbox (
+	ID BIGSERIAL PRIMARY KEY,
+    topic VARCHAR NOT NULL,
+    channel VARCHAR NOT NULL,
+    recipient_id INTEGER NOT NULL,
+    payload JSONB NOT NULL DEFAULT '{}',
+    status VARCHAR NOT NULL DEFAULT 'pending',
+    attempts INT NOT NULL DEFAULT 0,
+    max_attempts INT NOT NULL DEFAULT 5,
+    next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    last_error TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    sent_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_outbox_status_next_attempt_at ON public.outbox (status, next_attempt_at);
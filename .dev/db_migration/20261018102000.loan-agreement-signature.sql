@@ -0,0 +1,17 @@
+CREATE TABLE IF NOT EXISTS public.loan_agreement_signature (
+	ID SERIAL PRIMARY KEY,
+    loan_id INTEGER NOT NULL REFERENCES public.loan (id),
+    borrower_id INTEGER NOT NULL,
+    token_hash VARCHAR(64) NOT NULL UNIQUE,
+    agreement_letter_sha256 VARCHAR(64) NOT NULL,
+    agreement_letter_version VARCHAR NOT NULL,
+    signer_name VARCHAR NOT NULL DEFAULT '',
+    signer_ip VARCHAR NOT NULL DEFAULT '',
+    signed_document_sha256 VARCHAR(64) NOT NULL DEFAULT '',
+    signed_document_url VARCHAR NOT NULL DEFAULT '',
+    expires_at TIMESTAMPTZ NOT NULL,
+    signed_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_loan_agreement_signature_loan_id ON public.loan_agreement_signature (loan_id);
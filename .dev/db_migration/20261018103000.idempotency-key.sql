@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS public.idempotency_key (
+    idempotency_key VARCHAR(255) PRIMARY KEY,
+    request_hash VARCHAR(64) NOT NULL,
+    status VARCHAR NOT NULL DEFAULT 'in_progress',
+    response_code INT NOT NULL DEFAULT 0,
+    response_body BYTEA NOT NULL DEFAULT '',
+    content_type VARCHAR NOT NULL DEFAULT '',
+    expires_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
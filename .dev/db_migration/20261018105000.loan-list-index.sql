@@ -0,0 +1,4 @@
+CREATE INDEX IF NOT EXISTS loan_status_id_idx ON public.loan (status, id);
+CREATE INDEX IF NOT EXISTS loan_borrower_id_idx ON public.loan (borrower_id, id);
+CREATE INDEX IF NOT EXISTS loan_principal_amount_idx ON public.loan (principal_amount, id);
+CREATE INDEX IF NOT EXISTS loan_approval_date_idx ON public.loan ((COALESCE(approval_date, '-infinity')), id);
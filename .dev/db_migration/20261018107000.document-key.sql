@@ -0,0 +1,8 @@
+-- stored documents are kept by their key in the blob store, a download URL that expires is made when they are shown.
+-- Documents of this service were kept by their URL <blob.base_url>/<key>, any other URL is kept as it is
+ALTER TABLE public.loan RENAME COLUMN agreement_letter_url TO agreement_letter_key;
+ALTER TABLE public.loan_agreement_signature RENAME COLUMN signed_document_url TO signed_document_key;
+ALTER TABLE public.loan_disbursement RENAME COLUMN signed_agreement_url TO signed_agreement_key;
+UPDATE public.loan SET agreement_letter_key = substring(agreement_letter_key FROM '(agreement-letters/.*)$') WHERE agreement_letter_key ~ '/agreement-letters/';
+UPDATE public.loan_agreement_signature SET signed_document_key = substring(signed_document_key FROM '(agreement-signatures/.*)$') WHERE signed_document_key ~ '/agreement-signatures/';
+UPDATE public.loan_disbursement SET signed_agreement_key = substring(signed_agreement_key FROM '((agreement-signatures|uploads/signed-agreements)/.*)$') WHERE signed_agreement_key ~ '/(agreement-signatures|uploads/signed-agreements)/';
@@ -0,0 +1,36 @@
+-- investments made before 20261018098000.investor-wallet have no investor, wallet or held amount, so they could not
+-- be captured on disbursement nor released on withdrawal, cancellation or expiry. Their investors are added with a
+-- placeholder email, and the amount of every investment of a loan that is still funding or waiting for disbursement
+-- is deposited and held like it was invested from the wallet
+INSERT INTO public.investor (id, name, email)
+SELECT DISTINCT i.investor_id, 'Investor ' || i.investor_id, 'investor-' || i.investor_id || '@backfill.invalid'
+FROM public.loan_investment i
+WHERE NOT EXISTS (SELECT 1 FROM public.investor v WHERE v.id = i.investor_id);
+
+SELECT setval(pg_get_serial_sequence('public.investor', 'id'), GREATEST((SELECT MAX(id) FROM public.investor), 1));
+
+INSERT INTO public.investor_wallet (investor_id)
+SELECT v.id FROM public.investor v
+WHERE NOT EXISTS (SELECT 1 FROM public.investor_wallet w WHERE w.investor_id = v.id);
+
+-- the wallets are updated before the ledger, both pick the open investments that have no hold yet
+UPDATE public.investor_wallet w
+SET balance = w.balance + b.amount, held = w.held + b.amount, updated_at = NOW()
+FROM (
+    SELECT i.investor_id, SUM(i.amount) AS amount
+    FROM public.loan_investment i
+    JOIN public.loan l ON l.id = i.loan_id
+    WHERE i.withdrawn_at IS NULL AND l.status IN (2, 3)
+    AND NOT EXISTS (SELECT 1 FROM public.investor_wallet_transaction t WHERE t.invest_id = i.id AND t.type = 'hold')
+    GROUP BY i.investor_id
+) b
+WHERE w.investor_id = b.investor_id;
+
+INSERT INTO public.investor_wallet_transaction (investor_id, type, amount, loan_id, invest_id)
+SELECT i.investor_id, m.type, i.amount, i.loan_id, i.id
+FROM public.loan_investment i
+JOIN public.loan l ON l.id = i.loan_id
+CROSS JOIN (VALUES ('deposit'), ('hold')) AS m (type)
+WHERE i.withdrawn_at IS NULL AND l.status IN (2, 3)
+AND NOT EXISTS (SELECT 1 FROM public.investor_wallet_transaction t WHERE t.invest_id = i.id AND t.type = 'hold')
+ORDER BY i.id, m.type;
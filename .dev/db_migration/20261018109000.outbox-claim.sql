@@ -0,0 +1,2 @@
+-- a dispatcher claims due messages until claimed_until and commits, then delivers them and records each one in its own transaction
+ALTER TABLE public.outbox ADD COLUMN IF NOT EXISTS claimed_until TIMESTAMPTZ;
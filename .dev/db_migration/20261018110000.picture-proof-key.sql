@@ -0,0 +1,4 @@
+-- the picture proof of approval is kept by its key in the blob store like the other documents.
+-- Picture proofs uploaded to this service were kept by their URL <blob.base_url>/<key>, any other URL is kept as it is
+ALTER TABLE public.loan RENAME COLUMN picture_proof_url TO picture_proof_key;
+UPDATE public.loan SET picture_proof_key = substring(picture_proof_key FROM '(uploads/picture-proofs/.*)$') WHERE picture_proof_key ~ '/uploads/picture-proofs/';
@@ -0,0 +1,179 @@
+-- the schema of .dev/db_migration up to 20261018105000 for mysql 8.0.16 or newer, new changes are added on both directories
+CREATE TABLE IF NOT EXISTS loan (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    borrower_id INT NOT NULL,
+    principal_amount DECIMAL(20,2) NOT NULL,
+    rate DOUBLE NOT NULL,
+    roi DOUBLE NOT NULL,
+    status INT NOT NULL DEFAULT 1,
+    agreement_letter_url TEXT,
+    picture_proof_url TEXT,
+    approver_id INT,
+    approval_date DATETIME(6),
+    tenor INT NOT NULL DEFAULT 12,
+    repayment_method VARCHAR(255) NOT NULL DEFAULT 'annuity',
+    funding_deadline DATETIME(6),
+    agreement_letter_sha256 VARCHAR(64) NOT NULL DEFAULT '',
+    agreement_letter_version VARCHAR(255) NOT NULL DEFAULT '',
+    version INT NOT NULL DEFAULT 1,
+    INDEX idx_loan_status_funding_deadline (status, funding_deadline),
+    INDEX idx_loan_borrower_id (borrower_id),
+    INDEX loan_status_id_idx (status, id),
+    INDEX loan_borrower_id_idx (borrower_id, id),
+    INDEX loan_principal_amount_idx (principal_amount, id),
+    -- the expression must be the same as the approval_date sort of the loan repository
+    INDEX loan_approval_date_idx ((COALESCE(approval_date, CAST('1000-01-01' AS DATETIME(6)))), id)
+);
+
+CREATE TABLE IF NOT EXISTS loan_investment (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    loan_id INT NOT NULL,
+    investor_id INT NOT NULL,
+    amount DECIMAL(20,2) NOT NULL,
+    withdrawn_at DATETIME(6),
+    withdrawal_reason VARCHAR(255),
+    INDEX idx_loan_investment_investor_id (investor_id)
+);
+
+CREATE TABLE IF NOT EXISTS loan_disbursement (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    loan_id INT NOT NULL,
+    signed_agreement_url TEXT NOT NULL,
+    disburser_employee_id VARCHAR(255) NOT NULL,
+    disbursement_date DATETIME(6) NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS loan_installment (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    loan_id INT NOT NULL,
+    installment_number INT NOT NULL,
+    due_date DATETIME(6) NOT NULL,
+    principal_amount DECIMAL(20,2) NOT NULL,
+    interest_amount DECIMAL(20,2) NOT NULL,
+    total_amount DECIMAL(20,2) NOT NULL,
+    outstanding_balance DECIMAL(20,2) NOT NULL,
+    CONSTRAINT unique_loan_installment_number UNIQUE (loan_id, installment_number)
+);
+
+CREATE TABLE IF NOT EXISTS loan_repayment (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    loan_id INT NOT NULL,
+    amount DECIMAL(20,2) NOT NULL,
+    principal_amount DECIMAL(20,2) NOT NULL,
+    interest_amount DECIMAL(20,2) NOT NULL,
+    payment_date DATETIME(6) NOT NULL,
+    INDEX idx_loan_repayment_loan_id (loan_id)
+);
+
+CREATE TABLE IF NOT EXISTS loan_payout (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    loan_id INT NOT NULL,
+    repayment_id INT NOT NULL,
+    invest_id INT NOT NULL,
+    investor_id INT NOT NULL,
+    principal_amount DECIMAL(20,2) NOT NULL,
+    return_amount DECIMAL(20,2) NOT NULL,
+    amount DECIMAL(20,2) NOT NULL,
+    created_at DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+    INDEX idx_loan_payout_investor_id (investor_id),
+    INDEX idx_loan_payout_invest_id (invest_id)
+);
+
+CREATE TABLE IF NOT EXISTS loan_event (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    loan_id INT NOT NULL,
+    event VARCHAR(255) NOT NULL,
+    actor VARCHAR(255) NOT NULL DEFAULT '',
+    from_status INT,
+    to_status INT,
+    payload JSON NOT NULL DEFAULT (JSON_OBJECT()),
+    request_id VARCHAR(255) NOT NULL DEFAULT '',
+    created_at DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+    INDEX idx_loan_event_loan_id (loan_id)
+);
+
+-- events are immutable, mysql has no rule to ignore updates and deletes so they are rejected instead
+CREATE TRIGGER loan_event_no_update BEFORE UPDATE ON loan_event FOR EACH ROW SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'loan_event is immutable';
+CREATE TRIGGER loan_event_no_delete BEFORE DELETE ON loan_event FOR EACH ROW SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'loan_event is immutable';
+
+CREATE TABLE IF NOT EXISTS borrower (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    email VARCHAR(255) NOT NULL UNIQUE,
+    phone_number VARCHAR(255) NOT NULL DEFAULT '',
+    kyc_status VARCHAR(255) NOT NULL DEFAULT 'pending',
+    credit_limit DECIMAL(20,2) NOT NULL DEFAULT 0,
+    created_at DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6)
+);
+
+CREATE TABLE IF NOT EXISTS investor (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    email VARCHAR(255) NOT NULL UNIQUE,
+    created_at DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6)
+);
+
+CREATE TABLE IF NOT EXISTS investor_wallet (
+    investor_id INT PRIMARY KEY,
+    balance DECIMAL(20,2) NOT NULL DEFAULT 0 CHECK (balance >= 0),
+    held DECIMAL(20,2) NOT NULL DEFAULT 0 CHECK (held >= 0),
+    updated_at DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+    CONSTRAINT investor_wallet_held_check CHECK (held <= balance),
+    CONSTRAINT investor_wallet_investor_id_fkey FOREIGN KEY (investor_id) REFERENCES investor (id)
+);
+
+CREATE TABLE IF NOT EXISTS investor_wallet_transaction (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    investor_id INT NOT NULL,
+    type VARCHAR(255) NOT NULL,
+    amount DECIMAL(20,2) NOT NULL,
+    loan_id INT,
+    invest_id INT,
+    created_at DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+    INDEX idx_investor_wallet_transaction_investor_id (investor_id)
+);
+
+CREATE TABLE IF NOT EXISTS outbox (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    topic VARCHAR(255) NOT NULL,
+    channel VARCHAR(255) NOT NULL,
+    recipient_id INT NOT NULL,
+    payload JSON NOT NULL DEFAULT (JSON_OBJECT()),
+    status VARCHAR(255) NOT NULL DEFAULT 'pending',
+    attempts INT NOT NULL DEFAULT 0,
+    max_attempts INT NOT NULL DEFAULT 5,
+    next_attempt_at DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+    last_error TEXT NOT NULL DEFAULT (''),
+    created_at DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+    sent_at DATETIME(6),
+    INDEX idx_outbox_status_next_attempt_at (status, next_attempt_at)
+);
+
+CREATE TABLE IF NOT EXISTS loan_agreement_signature (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    loan_id INT NOT NULL,
+    borrower_id INT NOT NULL,
+    token_hash VARCHAR(64) NOT NULL UNIQUE,
+    agreement_letter_sha256 VARCHAR(64) NOT NULL,
+    agreement_letter_version VARCHAR(255) NOT NULL,
+    signer_name VARCHAR(255) NOT NULL DEFAULT '',
+    signer_ip VARCHAR(255) NOT NULL DEFAULT '',
+    signed_document_sha256 VARCHAR(64) NOT NULL DEFAULT '',
+    signed_document_url VARCHAR(255) NOT NULL DEFAULT '',
+    expires_at DATETIME(6) NOT NULL,
+    signed_at DATETIME(6),
+    created_at DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+    INDEX idx_loan_agreement_signature_loan_id (loan_id),
+    CONSTRAINT loan_agreement_signature_loan_id_fkey FOREIGN KEY (loan_id) REFERENCES loan (id)
+);
+
+CREATE TABLE IF NOT EXISTS idempotency_key (
+    idempotency_key VARCHAR(255) PRIMARY KEY,
+    request_hash VARCHAR(64) NOT NULL,
+    status VARCHAR(255) NOT NULL DEFAULT 'in_progress',
+    response_code INT NOT NULL DEFAULT 0,
+    response_body LONGBLOB NOT NULL DEFAULT (''),
+    content_type VARCHAR(255) NOT NULL DEFAULT '',
+    expires_at DATETIME(6) NOT NULL,
+    created_at DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6)
+);
@@ -0,0 +1,33 @@
+-- investments made before 20261018098000.investor-wallet have no investor, wallet or held amount, so they could not
+-- be captured on disbursement nor released on withdrawal, cancellation or expiry. Their investors are added with a
+-- placeholder email, and the amount of every investment of a loan that is still funding or waiting for disbursement
+-- is deposited and held like it was invested from the wallet
+INSERT INTO investor (id, name, email)
+SELECT DISTINCT i.investor_id, CONCAT('Investor ', i.investor_id), CONCAT('investor-', i.investor_id, '@backfill.invalid')
+FROM loan_investment i
+WHERE NOT EXISTS (SELECT 1 FROM investor v WHERE v.id = i.investor_id);
+
+INSERT INTO investor_wallet (investor_id)
+SELECT v.id FROM investor v
+WHERE NOT EXISTS (SELECT 1 FROM investor_wallet w WHERE w.investor_id = v.id);
+
+-- the wallets are updated before the ledger, both pick the open investments that have no hold yet
+UPDATE investor_wallet w
+JOIN (
+    SELECT i.investor_id, SUM(i.amount) AS amount
+    FROM loan_investment i
+    JOIN loan l ON l.id = i.loan_id
+    WHERE i.withdrawn_at IS NULL AND l.status IN (2, 3)
+    AND NOT EXISTS (SELECT 1 FROM investor_wallet_transaction t WHERE t.invest_id = i.id AND t.type = 'hold')
+    GROUP BY i.investor_id
+) b ON b.investor_id = w.investor_id
+SET w.balance = w.balance + b.amount, w.held = w.held + b.amount, w.updated_at = CURRENT_TIMESTAMP(6);
+
+INSERT INTO investor_wallet_transaction (investor_id, type, amount, loan_id, invest_id)
+SELECT i.investor_id, m.type, i.amount, i.loan_id, i.id
+FROM loan_investment i
+JOIN loan l ON l.id = i.loan_id
+CROSS JOIN (SELECT 'deposit' AS type UNION ALL SELECT 'hold') m
+WHERE i.withdrawn_at IS NULL AND l.status IN (2, 3)
+AND NOT EXISTS (SELECT 1 FROM investor_wallet_transaction t WHERE t.invest_id = i.id AND t.type = 'hold')
+ORDER BY i.id, m.type;
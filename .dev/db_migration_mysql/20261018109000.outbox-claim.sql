@@ -0,0 +1,2 @@
+-- a dispatcher claims due messages until claimed_until and commits, then delivers them and records each one in its own transaction
+ALTER TABLE outbox ADD COLUMN claimed_until DATETIME(6);
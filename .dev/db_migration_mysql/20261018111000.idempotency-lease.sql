@@ -0,0 +1,3 @@
+-- an in-progress key is claimed until claimed_until, a retry takes it over afterwards when the process of the first request is gone.
+-- Keys in progress before this column have no claim and can be taken over at once
+ALTER TABLE idempotency_key ADD COLUMN claimed_until DATETIME(6);
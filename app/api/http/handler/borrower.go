@@ -0,0 +1,85 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"simple-app/internal/model"
+	"simple-app/internal/pkg/response"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterBorrower is a handler that register borrower
+func (h *Handler) RegisterBorrower(c *gin.Context) {
+	var borrower model.Borrower
+	err := c.ShouldBindJSON(&borrower)
+	if err != nil {
+		response.Err(c, response.WrapErrCode(err, response.BadRequestErrCode), err.Error())
+		return
+	}
+
+	val := h.validator.ValidateStruct(borrower)
+	if len(val) > 0 {
+		response.Err(c, response.InvalidRequestPayloadCode(val...))
+		return
+	}
+
+	data, err := h.borrower.Register(c.Request.Context(), borrower)
+	if err != nil {
+		response.Err(c, response.WrapErrCode(err, errCode(err)), err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, data)
+}
+
+// UpdateBorrowerKYC is a handler that update the KYC status of borrower
+func (h *Handler) UpdateBorrowerKYC(c *gin.Context) {
+	var kyc model.KYC
+	id := c.Param("id")
+	idInt, err := strconv.Atoi(id)
+	if err != nil {
+		response.Err(c, response.WrapErrCode(errors.New("id is invalid"), response.BadRequestErrCode))
+		return
+	}
+
+	kyc.ID = idInt
+	err = c.ShouldBindJSON(&kyc)
+	if err != nil {
+		response.Err(c, response.WrapErrCode(err, response.BadRequestErrCode), err.Error())
+		return
+	}
+
+	val := h.validator.ValidateStruct(kyc)
+	if len(val) > 0 {
+		response.Err(c, response.InvalidRequestPayloadCode(val...))
+		return
+	}
+
+	data, err := h.borrower.UpdateKYC(c.Request.Context(), kyc)
+	if err != nil {
+		response.Err(c, response.WrapErrCode(err, errCode(err)), err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, data)
+}
+
+// GetBorrower is a handler that get borrower
+func (h *Handler) GetBorrower(c *gin.Context) {
+	id := c.Param("id")
+	idInt, err := strconv.Atoi(id)
+	if err != nil {
+		response.Err(c, response.WrapErrCode(errors.New("id is invalid"), response.BadRequestErrCode))
+		return
+	}
+
+	data, err := h.borrower.Get(c.Request.Context(), idInt)
+	if err != nil {
+		response.Err(c, response.WrapErrCode(err, errCode(err)), err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, data)
+}
@@ -1,5 +1,44 @@
 package handler
 
+import (
+	"errors"
+	"simple-app/internal/model"
+	"simple-app/internal/pkg/response"
+)
+
 const (
 	ErrInternalServerError = "internal server error"
 )
+
+// errCode maps error from usecase to response code
+func errCode(err error) response.Code {
+	switch {
+	case errors.Is(err, model.ErrVersionConflict):
+		return response.ConflictCode
+	case errors.Is(err, model.ErrInvalidTransition),
+		errors.Is(err, model.ErrFundingClosed),
+		errors.Is(err, model.ErrAgreementMismatch):
+		return response.StateConflictCode
+	case errors.Is(err, model.ErrInvalidSignatureToken),
+		errors.Is(err, model.ErrInvalidDownloadSignature):
+		return response.UnauthorizedCode
+	case errors.Is(err, model.ErrNotFound):
+		return response.NotFoundCode
+	case errors.Is(err, model.ErrBorrowerNotRegistered),
+		errors.Is(err, model.ErrBorrowerNotVerified),
+		errors.Is(err, model.ErrCreditLimitExceeded),
+		errors.Is(err, model.ErrInvestorNotRegistered),
+		errors.Is(err, model.ErrInsufficientBalance),
+		errors.Is(err, model.ErrSignatureRequired),
+		errors.Is(err, model.ErrInvalidFile):
+		return response.UnprocessableCode
+	case errors.Is(err, model.ErrAmountExceedsRemaining),
+		errors.Is(err, model.ErrOverpayment),
+		errors.Is(err, model.ErrUnsupportedFileType),
+		errors.Is(err, model.ErrFileTooLarge),
+		errors.Is(err, model.ErrInvalidCursor):
+		return response.BadRequestErrCode
+	}
+
+	return response.InternalErrCode
+}
@@ -0,0 +1,85 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"simple-app/internal/model"
+	"simple-app/internal/pkg/response"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestErrCode(t *testing.T) {
+	tests := []struct {
+		name         string
+		err          error
+		wantCode     string
+		wantHTTPCode int
+	}{
+		{
+			name:         "invalid transition",
+			err:          &model.TransitionError{From: model.PROPOSED, To: model.INVESTED},
+			wantCode:     "STATE_CONFLICT",
+			wantHTTPCode: http.StatusConflict,
+		}, {
+			name:         "wrapped invalid transition",
+			err:          fmt.Errorf("failed to disburse loan: %w", &model.TransitionError{From: model.APPROVED, To: model.DISBURSED}),
+			wantCode:     "STATE_CONFLICT",
+			wantHTTPCode: http.StatusConflict,
+		}, {
+			name:         "version conflict",
+			err:          &model.VersionConflictError{LoanID: 1, Expected: 1, Actual: 2},
+			wantCode:     "CONFLICT",
+			wantHTTPCode: http.StatusConflict,
+		}, {
+			name:         "wrapped version conflict",
+			err:          fmt.Errorf("failed to approve loan: %w", &model.VersionConflictError{LoanID: 1, Expected: 1, Actual: 2}),
+			wantCode:     "CONFLICT",
+			wantHTTPCode: http.StatusConflict,
+		}, {
+			name:         "funding closed",
+			err:          model.ErrFundingClosed,
+			wantCode:     "STATE_CONFLICT",
+			wantHTTPCode: http.StatusConflict,
+		}, {
+			name:         "not found",
+			err:          model.ErrNotFound,
+			wantCode:     "NOT_FOUND",
+			wantHTTPCode: http.StatusNotFound,
+		}, {
+			name:         "invalid signature token",
+			err:          model.ErrInvalidSignatureToken,
+			wantCode:     "UNAUTHORIZED",
+			wantHTTPCode: http.StatusUnauthorized,
+		}, {
+			name:         "amount exceeds remaining",
+			err:          model.ErrAmountExceedsRemaining,
+			wantCode:     "BAD_REQUEST",
+			wantHTTPCode: http.StatusBadRequest,
+		}, {
+			name:         "unknown error",
+			err:          errors.New("connection refused"),
+			wantCode:     "INTERNAL_SERVER_ERROR",
+			wantHTTPCode: http.StatusInternalServerError,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// the error is written the way the handlers write it
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			response.Err(c, response.WrapErrCode(tt.err, errCode(tt.err)), tt.err.Error())
+
+			var body response.Response
+			assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+			assert.Equal(t, tt.wantCode, body.Code)
+			assert.Equal(t, tt.wantHTTPCode, w.Code)
+		})
+	}
+}
@@ -0,0 +1,44 @@
+package handler
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+
+	"simple-app/internal/pkg/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+var (
+	errIfMatchRequired = errors.New("If-Match header with the ETag of loan is required")
+	errIfMatchInvalid  = errors.New("If-Match header is not an ETag of loan")
+)
+
+// etag is the version of loan as an entity tag
+func etag(version int) string {
+	return strconv.Quote(strconv.Itoa(version))
+}
+
+// ifMatch get the version of loan from If-Match header, the request is aborted when it is missing or invalid
+func ifMatch(c *gin.Context) (version int, ok bool) {
+	header := c.GetHeader("If-Match")
+	if header == "" {
+		response.Err(c, response.WrapErrCode(errIfMatchRequired, response.PreconditionRequiredCode), errIfMatchRequired.Error())
+		return version, false
+	}
+
+	tag, err := strconv.Unquote(strings.TrimPrefix(header, "W/"))
+	if err != nil {
+		response.Err(c, response.WrapErrCode(errIfMatchInvalid, response.BadRequestErrCode), errIfMatchInvalid.Error())
+		return version, false
+	}
+
+	version, err = strconv.Atoi(tag)
+	if err != nil {
+		response.Err(c, response.WrapErrCode(errIfMatchInvalid, response.BadRequestErrCode), errIfMatchInvalid.Error())
+		return version, false
+	}
+
+	return version, true
+}
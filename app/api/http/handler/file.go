@@ -0,0 +1,93 @@
+package handler
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"simple-app/internal/model"
+	"simple-app/internal/pkg/response"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxUploadSize is the largest request body of upload, the limit of every kind of file is checked by the usecase
+const maxUploadSize = 10 << 20
+
+// UploadPictureProof is a handler that upload the picture proof of approval, JPEG or PNG
+func (h *Handler) UploadPictureProof(c *gin.Context) {
+	h.upload(c, model.FilePictureProof)
+}
+
+// UploadSignedAgreement is a handler that upload the scan of signed agreement letter, PDF
+func (h *Handler) UploadSignedAgreement(c *gin.Context) {
+	h.upload(c, model.FileSignedAgreement)
+}
+
+// upload read the multipart field "file" and store it
+func (h *Handler) upload(c *gin.Context, kind model.FileKind) {
+	// leave room for the multipart headers
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxUploadSize+1<<20)
+
+	header, err := c.FormFile("file")
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			err = model.ErrFileTooLarge
+		}
+		response.Err(c, response.WrapErrCode(err, response.BadRequestErrCode), err.Error())
+		return
+	}
+
+	if header.Size > maxUploadSize {
+		response.Err(c, response.WrapErrCode(model.ErrFileTooLarge, response.BadRequestErrCode), model.ErrFileTooLarge.Error())
+		return
+	}
+
+	f, err := header.Open()
+	if err != nil {
+		response.Err(c, response.WrapErrCode(err, response.BadRequestErrCode), err.Error())
+		return
+	}
+	defer f.Close()
+
+	data, err := io.ReadAll(io.LimitReader(f, maxUploadSize+1))
+	if err != nil {
+		response.Err(c, response.WrapErrCode(err, response.BadRequestErrCode), err.Error())
+		return
+	}
+
+	file, err := h.file.Upload(c.Request.Context(), kind, data)
+	if err != nil {
+		response.Err(c, response.WrapErrCode(err, errCode(err)), err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": file})
+}
+
+// DownloadFile is a handler that download a stored file with the signed URL
+func (h *Handler) DownloadFile(c *gin.Context) {
+	expires, _ := strconv.ParseInt(c.Query("expires"), 10, 64)
+	download := model.Download{
+		Key:       strings.TrimPrefix(c.Param("key"), "/"),
+		Expires:   expires,
+		Signature: c.Query("signature"),
+	}
+
+	val := h.validator.ValidateStruct(download)
+	if len(val) > 0 {
+		response.Err(c, response.WrapErrCode(model.ErrInvalidDownloadSignature, response.UnauthorizedCode), model.ErrInvalidDownloadSignature.Error())
+		return
+	}
+
+	data, contentType, err := h.file.Download(c.Request.Context(), download)
+	if err != nil {
+		response.Err(c, response.WrapErrCode(err, errCode(err)), err.Error())
+		return
+	}
+
+	c.Header("Cache-Control", "private, no-store")
+	c.Data(http.StatusOK, contentType, data)
+}
@@ -9,15 +9,23 @@ import (
 type Handler struct {
 	validator *validate.Validate
 	loan      app.LoanUseCase
+	payout    app.PayoutUseCase
+	borrower  app.BorrowerUseCase
+	investor  app.InvestorUseCase
+	file      app.FileUseCase
 }
 
 // New will instantiate http blog package
-func New(loanUc app.LoanUseCase) *Handler {
+func New(loanUc app.LoanUseCase, payoutUc app.PayoutUseCase, borrowerUc app.BorrowerUseCase, investorUc app.InvestorUseCase, fileUc app.FileUseCase) *Handler {
 	v := validate.New(
 		&validate.Options{})
 
 	return &Handler{
 		validator: v,
 		loan:      loanUc,
+		payout:    payoutUc,
+		borrower:  borrowerUc,
+		investor:  investorUc,
+		file:      fileUc,
 	}
 }
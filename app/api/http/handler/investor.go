@@ -0,0 +1,142 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"simple-app/internal/model"
+	"simple-app/internal/pkg/response"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterInvestor is a handler that register investor
+func (h *Handler) RegisterInvestor(c *gin.Context) {
+	var investor model.Investor
+	err := c.ShouldBindJSON(&investor)
+	if err != nil {
+		response.Err(c, response.WrapErrCode(err, response.BadRequestErrCode), err.Error())
+		return
+	}
+
+	val := h.validator.ValidateStruct(investor)
+	if len(val) > 0 {
+		response.Err(c, response.InvalidRequestPayloadCode(val...))
+		return
+	}
+
+	data, err := h.investor.Register(c.Request.Context(), investor)
+	if err != nil {
+		response.Err(c, response.WrapErrCode(err, errCode(err)), err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, data)
+}
+
+// GetInvestor is a handler that get investor
+func (h *Handler) GetInvestor(c *gin.Context) {
+	id := c.Param("id")
+	idInt, err := strconv.Atoi(id)
+	if err != nil {
+		response.Err(c, response.WrapErrCode(errors.New("id is invalid"), response.BadRequestErrCode))
+		return
+	}
+
+	data, err := h.investor.Get(c.Request.Context(), idInt)
+	if err != nil {
+		response.Err(c, response.WrapErrCode(err, errCode(err)), err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, data)
+}
+
+// GetInvestorWallet is a handler that get the wallet of investor and its transactions
+func (h *Handler) GetInvestorWallet(c *gin.Context) {
+	id := c.Param("id")
+	idInt, err := strconv.Atoi(id)
+	if err != nil {
+		response.Err(c, response.WrapErrCode(errors.New("id is invalid"), response.BadRequestErrCode))
+		return
+	}
+
+	wallet, transactions, err := h.investor.GetWallet(c.Request.Context(), idInt)
+	if err != nil {
+		response.Err(c, response.WrapErrCode(err, errCode(err)), err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"investor_id":  wallet.InvestorID,
+		"balance":      wallet.Balance,
+		"held":         wallet.Held,
+		"available":    wallet.Available(),
+		"transactions": transactions,
+	})
+}
+
+// GetInvestorPortfolio is a handler that get the investments of investor with their returns
+func (h *Handler) GetInvestorPortfolio(c *gin.Context) {
+	id := c.Param("id")
+	idInt, err := strconv.Atoi(id)
+	if err != nil {
+		response.Err(c, response.WrapErrCode(errors.New("id is invalid"), response.BadRequestErrCode))
+		return
+	}
+
+	portfolio, err := h.investor.GetPortfolio(c.Request.Context(), idInt)
+	if err != nil {
+		response.Err(c, response.WrapErrCode(err, errCode(err)), err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, portfolio)
+}
+
+// DepositWallet is a handler that add money to the wallet of investor
+func (h *Handler) DepositWallet(c *gin.Context) {
+	h.fundWallet(c, h.investor.Deposit)
+}
+
+// WithdrawWallet is a handler that take money out of the wallet of investor
+func (h *Handler) WithdrawWallet(c *gin.Context) {
+	h.fundWallet(c, h.investor.Withdraw)
+}
+
+func (h *Handler) fundWallet(c *gin.Context, fund func(ctx context.Context, param model.WalletFund) (model.Wallet, error)) {
+	var param model.WalletFund
+	id := c.Param("id")
+	idInt, err := strconv.Atoi(id)
+	if err != nil {
+		response.Err(c, response.WrapErrCode(errors.New("id is invalid"), response.BadRequestErrCode))
+		return
+	}
+
+	err = c.ShouldBindJSON(&param)
+	if err != nil {
+		response.Err(c, response.WrapErrCode(err, response.BadRequestErrCode), err.Error())
+		return
+	}
+	param.InvestorID = idInt
+
+	val := h.validator.ValidateStruct(param)
+	if len(val) > 0 {
+		response.Err(c, response.InvalidRequestPayloadCode(val...))
+		return
+	}
+
+	wallet, err := fund(c.Request.Context(), param)
+	if err != nil {
+		response.Err(c, response.WrapErrCode(err, errCode(err)), err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"investor_id": wallet.InvestorID,
+		"balance":     wallet.Balance,
+		"held":        wallet.Held,
+		"available":   wallet.Available(),
+	})
+}
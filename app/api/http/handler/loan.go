@@ -6,6 +6,7 @@ import (
 	"simple-app/internal/model"
 	"simple-app/internal/pkg/response"
 	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 )
@@ -27,7 +28,7 @@ func (h *Handler) CreateLoan(c *gin.Context) {
 
 	data, err := h.loan.Create(c.Request.Context(), loan)
 	if err != nil {
-		response.Err(c, response.WrapErrCode(err, response.InternalErrCode), err.Error())
+		response.Err(c, response.WrapErrCode(err, errCode(err)), err.Error())
 		return
 	}
 
@@ -46,12 +47,18 @@ func (h *Handler) ApproveLoan(c *gin.Context) {
 		return
 	}
 
+	version, ok := ifMatch(c)
+	if !ok {
+		return
+	}
+
 	loan.ID = idInt
 	err = c.ShouldBindJSON(&loan)
 	if err != nil {
 		response.Err(c, response.WrapErrCode(err, response.BadRequestErrCode), err.Error())
 		return
 	}
+	loan.Version = version
 
 	val := h.validator.ValidateStruct(loan)
 	if len(val) > 0 {
@@ -61,12 +68,7 @@ func (h *Handler) ApproveLoan(c *gin.Context) {
 
 	res, err := h.loan.Approve(c.Request.Context(), loan)
 	if err != nil {
-		if err.Error() == "status is already approved" {
-			response.Err(c, response.WrapErrCode(err, response.BadRequestErrCode), err.Error())
-			return
-		}
-
-		response.Err(c, response.WrapErrCode(err, response.InternalErrCode), err.Error())
+		response.Err(c, response.WrapErrCode(err, errCode(err)), err.Error())
 		return
 	}
 
@@ -83,12 +85,18 @@ func (h *Handler) InvestLoan(c *gin.Context) {
 		return
 	}
 
+	version, ok := ifMatch(c)
+	if !ok {
+		return
+	}
+
 	invest.LoanID = idInt
 	err = c.ShouldBindJSON(&invest)
 	if err != nil {
 		response.Err(c, response.WrapErrCode(err, response.BadRequestErrCode), err.Error())
 		return
 	}
+	invest.Version = version
 
 	val := h.validator.ValidateStruct(invest)
 	if len(val) > 0 {
@@ -98,18 +106,135 @@ func (h *Handler) InvestLoan(c *gin.Context) {
 
 	idLoan, total, status, err := h.loan.Invest(c.Request.Context(), invest)
 	if err != nil {
-		if err.Error() == "status of loan is invalid" {
-			response.Err(c, response.WrapErrCode(err, response.BadRequestErrCode), err.Error())
-			return
-		}
-
-		response.Err(c, response.WrapErrCode(err, response.InternalErrCode), err.Error())
+		response.Err(c, response.WrapErrCode(err, errCode(err)), err.Error())
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{"loan_id": idLoan, "total_of_invested": total, "status": status})
 }
 
+// WithdrawInvestment is a handler that withdraw investment before the loan is fully funded
+func (h *Handler) WithdrawInvestment(c *gin.Context) {
+	var withdraw model.Withdraw
+	id := c.Param("id")
+	idInt, err := strconv.Atoi(id)
+	if err != nil {
+		response.Err(c, response.WrapErrCode(errors.New("id is invalid"), response.BadRequestErrCode))
+		return
+	}
+
+	investID := c.Param("investment_id")
+	investIDInt, err := strconv.Atoi(investID)
+	if err != nil {
+		response.Err(c, response.WrapErrCode(errors.New("investment_id is invalid"), response.BadRequestErrCode))
+		return
+	}
+
+	version, ok := ifMatch(c)
+	if !ok {
+		return
+	}
+
+	err = c.ShouldBindJSON(&withdraw)
+	if err != nil {
+		response.Err(c, response.WrapErrCode(err, response.BadRequestErrCode), err.Error())
+		return
+	}
+	withdraw.LoanID = idInt
+	withdraw.ID = investIDInt
+	withdraw.Version = version
+
+	val := h.validator.ValidateStruct(withdraw)
+	if len(val) > 0 {
+		response.Err(c, response.InvalidRequestPayloadCode(val...))
+		return
+	}
+
+	data, total, err := h.loan.Withdraw(c.Request.Context(), withdraw)
+	if err != nil {
+		response.Err(c, response.WrapErrCode(err, errCode(err)), err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"loan_id":           data.LoanID,
+		"investment_id":     data.ID,
+		"amount":            data.Amount,
+		"total_of_invested": total,
+		"status":            model.APPROVED.ToString(),
+	})
+}
+
+// RequestSignature is a handler that sends the token to sign the agreement letter to the borrower
+func (h *Handler) RequestSignature(c *gin.Context) {
+	id := c.Param("id")
+	idInt, err := strconv.Atoi(id)
+	if err != nil {
+		response.Err(c, response.WrapErrCode(errors.New("id is invalid"), response.BadRequestErrCode))
+		return
+	}
+
+	version, ok := ifMatch(c)
+	if !ok {
+		return
+	}
+
+	request, err := h.loan.RequestSignature(c.Request.Context(), idInt, version)
+	if err != nil {
+		response.Err(c, response.WrapErrCode(err, errCode(err)), err.Error())
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{"data": request})
+}
+
+// SignAgreement is a handler that sign the agreement letter by borrower, the token is given as bearer token
+func (h *Handler) SignAgreement(c *gin.Context) {
+	var sign model.Sign
+	id := c.Param("id")
+	idInt, err := strconv.Atoi(id)
+	if err != nil {
+		response.Err(c, response.WrapErrCode(errors.New("id is invalid"), response.BadRequestErrCode))
+		return
+	}
+
+	token, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+	if !ok || token == "" {
+		response.Err(c, response.WrapErrCode(model.ErrInvalidSignatureToken, response.UnauthorizedCode), model.ErrInvalidSignatureToken.Error())
+		return
+	}
+
+	version, ok := ifMatch(c)
+	if !ok {
+		return
+	}
+
+	err = c.ShouldBindJSON(&sign)
+	if err != nil {
+		response.Err(c, response.WrapErrCode(err, response.BadRequestErrCode), err.Error())
+		return
+	}
+
+	sign.LoanID = idInt
+	sign.Token = token
+	sign.SignerIP = c.ClientIP()
+	sign.Version = version
+
+	val := h.validator.ValidateStruct(sign)
+	if len(val) > 0 {
+		response.Err(c, response.InvalidRequestPayloadCode(val...))
+		return
+	}
+
+	signature, err := h.loan.Sign(c.Request.Context(), sign)
+	if err != nil {
+		response.Err(c, response.WrapErrCode(err, errCode(err)), err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": signature})
+}
+
 // DisburseLoan is a handler that disburse by borrower
 func (h *Handler) DisburseLoan(c *gin.Context) {
 	var disburse model.Disburse
@@ -120,12 +245,18 @@ func (h *Handler) DisburseLoan(c *gin.Context) {
 		return
 	}
 
+	version, ok := ifMatch(c)
+	if !ok {
+		return
+	}
+
 	disburse.LoanID = idInt
 	err = c.ShouldBindJSON(&disburse)
 	if err != nil {
 		response.Err(c, response.WrapErrCode(err, response.BadRequestErrCode), err.Error())
 		return
 	}
+	disburse.Version = version
 
 	val := h.validator.ValidateStruct(disburse)
 	if len(val) > 0 {
@@ -135,18 +266,134 @@ func (h *Handler) DisburseLoan(c *gin.Context) {
 
 	idLoan, err := h.loan.Disburse(c.Request.Context(), disburse)
 	if err != nil {
-		if err.Error() == "status of loan is invalid" {
-			response.Err(c, response.WrapErrCode(err, response.BadRequestErrCode), err.Error())
-			return
-		}
-
-		response.Err(c, response.WrapErrCode(err, response.InternalErrCode), err.Error())
+		response.Err(c, response.WrapErrCode(err, errCode(err)), err.Error())
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{"loan_id": idLoan, "status": model.DISBURSED.ToString()})
 }
 
+// RepayLoan is a handler that record repayment by borrower
+func (h *Handler) RepayLoan(c *gin.Context) {
+	var repayment model.Repayment
+	id := c.Param("id")
+	idInt, err := strconv.Atoi(id)
+	if err != nil {
+		response.Err(c, response.WrapErrCode(errors.New("id is invalid"), response.BadRequestErrCode))
+		return
+	}
+
+	version, ok := ifMatch(c)
+	if !ok {
+		return
+	}
+
+	repayment.LoanID = idInt
+	err = c.ShouldBindJSON(&repayment)
+	if err != nil {
+		response.Err(c, response.WrapErrCode(err, response.BadRequestErrCode), err.Error())
+		return
+	}
+	repayment.Version = version
+
+	val := h.validator.ValidateStruct(repayment)
+	if len(val) > 0 {
+		response.Err(c, response.InvalidRequestPayloadCode(val...))
+		return
+	}
+
+	data, outstanding, status, err := h.loan.Repay(c.Request.Context(), repayment)
+	if err != nil {
+		response.Err(c, response.WrapErrCode(err, errCode(err)), err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"loan_id":            data.LoanID,
+		"repayment_id":       data.ID,
+		"principal_amount":   data.PrincipalAmount,
+		"interest_amount":    data.InterestAmount,
+		"outstanding_amount": outstanding,
+		"status":             status,
+	})
+}
+
+// RejectLoan is a handler that reject proposed loan
+func (h *Handler) RejectLoan(c *gin.Context) {
+	id := c.Param("id")
+	idInt, err := strconv.Atoi(id)
+	if err != nil {
+		response.Err(c, response.WrapErrCode(errors.New("id is invalid"), response.BadRequestErrCode))
+		return
+	}
+
+	version, ok := ifMatch(c)
+	if !ok {
+		return
+	}
+
+	var change model.StatusChange
+	change.ID = idInt
+	err = c.ShouldBindJSON(&change)
+	if err != nil {
+		response.Err(c, response.WrapErrCode(err, response.BadRequestErrCode), err.Error())
+		return
+	}
+	change.Version = version
+
+	val := h.validator.ValidateStruct(change)
+	if len(val) > 0 {
+		response.Err(c, response.InvalidRequestPayloadCode(val...))
+		return
+	}
+
+	err = h.loan.Reject(c.Request.Context(), change)
+	if err != nil {
+		response.Err(c, response.WrapErrCode(err, errCode(err)), err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"loan_id": idInt, "status": model.REJECTED.ToString()})
+}
+
+// CancelLoan is a handler that cancel loan which is not invested yet
+func (h *Handler) CancelLoan(c *gin.Context) {
+	id := c.Param("id")
+	idInt, err := strconv.Atoi(id)
+	if err != nil {
+		response.Err(c, response.WrapErrCode(errors.New("id is invalid"), response.BadRequestErrCode))
+		return
+	}
+
+	version, ok := ifMatch(c)
+	if !ok {
+		return
+	}
+
+	var change model.StatusChange
+	change.ID = idInt
+	err = c.ShouldBindJSON(&change)
+	if err != nil {
+		response.Err(c, response.WrapErrCode(err, response.BadRequestErrCode), err.Error())
+		return
+	}
+	change.Version = version
+
+	val := h.validator.ValidateStruct(change)
+	if len(val) > 0 {
+		response.Err(c, response.InvalidRequestPayloadCode(val...))
+		return
+	}
+
+	err = h.loan.Cancel(c.Request.Context(), change)
+	if err != nil {
+		response.Err(c, response.WrapErrCode(err, errCode(err)), err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"loan_id": idInt, "status": model.CANCELLED.ToString()})
+}
+
 // GetDetail is a handler that get the detail of loan
 func (h *Handler) GetDetail(c *gin.Context) {
 	id := c.Param("id")
@@ -158,20 +405,53 @@ func (h *Handler) GetDetail(c *gin.Context) {
 
 	detail, err := h.loan.GetDetail(c.Request.Context(), idInt)
 	if err != nil {
-		response.Err(c, response.WrapErrCode(err, response.InternalErrCode), err.Error())
+		response.Err(c, response.WrapErrCode(err, errCode(err)), err.Error())
 		return
 	}
 
+	// the ETag is sent back in If-Match header to update the loan
+	c.Header("ETag", etag(detail.Version))
 	c.JSON(http.StatusOK, detail)
 }
 
-// GetList is a handler that get list of loan
+// GetHistory is a handler that get the status history of loan
+func (h *Handler) GetHistory(c *gin.Context) {
+	id := c.Param("id")
+	idInt, err := strconv.Atoi(id)
+	if err != nil {
+		response.Err(c, response.WrapErrCode(errors.New("id is invalid"), response.BadRequestErrCode))
+		return
+	}
+
+	events, err := h.loan.GetHistory(c.Request.Context(), idInt)
+	if err != nil {
+		response.Err(c, response.WrapErrCode(err, errCode(err)), err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"loan_id": idInt, "data": events})
+}
+
+// GetList is a handler that get a page of loans, filtered and sorted by the query
 func (h *Handler) GetList(c *gin.Context) {
-	list, err := h.loan.GetList(c.Request.Context())
+	var param model.LoanListParam
+	err := c.ShouldBindQuery(&param)
+	if err != nil {
+		response.Err(c, response.WrapErrCode(err, response.BadRequestErrCode), err.Error())
+		return
+	}
+
+	val := h.validator.ValidateStruct(param)
+	if len(val) > 0 {
+		response.Err(c, response.InvalidRequestPayloadCode(val...))
+		return
+	}
+
+	page, err := h.loan.GetList(c.Request.Context(), param)
 	if err != nil {
-		response.Err(c, response.WrapErrCode(err, response.InternalErrCode), err.Error())
+		response.Err(c, response.WrapErrCode(err, errCode(err)), err.Error())
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"data": list})
+	c.JSON(http.StatusOK, page)
 }
@@ -0,0 +1,28 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"simple-app/internal/pkg/response"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetInvestorPayouts is a handler that get list of payouts received by investor
+func (h *Handler) GetInvestorPayouts(c *gin.Context) {
+	id := c.Param("id")
+	idInt, err := strconv.Atoi(id)
+	if err != nil {
+		response.Err(c, response.WrapErrCode(errors.New("id is invalid"), response.BadRequestErrCode))
+		return
+	}
+
+	list, err := h.payout.GetByInvestor(c.Request.Context(), idInt)
+	if err != nil {
+		response.Err(c, response.WrapErrCode(err, errCode(err)), err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": list})
+}
@@ -0,0 +1,87 @@
+package http
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"simple-app/internal/pkg/sqldb"
+
+	"github.com/gin-gonic/gin"
+)
+
+// pinCookie keeps the time until which reads of the client go to master DB, in unix milliseconds
+const pinCookie = "db_pinned_until"
+
+// pinWriter sets pinCookie right before the response is written, when the request has written to DB
+type pinWriter struct {
+	gin.ResponseWriter
+	c    *gin.Context
+	done bool
+}
+
+func (w *pinWriter) setCookie() {
+	if w.done {
+		return
+	}
+	w.done = true
+
+	until := sqldb.PinnedUntil(w.c.Request.Context())
+	if !until.After(time.Now()) {
+		return
+	}
+
+	http.SetCookie(w.ResponseWriter, &http.Cookie{
+		Name:     pinCookie,
+		Value:    strconv.FormatInt(until.UnixMilli(), 10),
+		Path:     "/",
+		Expires:  until,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+func (w *pinWriter) WriteHeader(code int) {
+	w.setCookie()
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *pinWriter) WriteHeaderNow() {
+	w.setCookie()
+	w.ResponseWriter.WriteHeaderNow()
+}
+
+func (w *pinWriter) Write(data []byte) (int, error) {
+	w.setCookie()
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *pinWriter) WriteString(s string) (int, error) {
+	w.setCookie()
+	return w.ResponseWriter.WriteString(s)
+}
+
+// readYourWrites pins the reads of a client to master DB for the window after it writes,
+// so the client reads what it has just written even when the follower is behind
+func readYourWrites(window time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if window <= 0 {
+			c.Next()
+			return
+		}
+
+		var until time.Time
+		if v, err := c.Cookie(pinCookie); err == nil {
+			ms, err := strconv.ParseInt(v, 10, 64)
+			// a cookie can not pin longer than the window
+			if err == nil && time.UnixMilli(ms).Before(time.Now().Add(window)) {
+				until = time.UnixMilli(ms)
+			}
+		}
+
+		c.Request = c.Request.WithContext(sqldb.WithPin(c.Request.Context(), until))
+		c.Writer = &pinWriter{ResponseWriter: c.Writer, c: c}
+
+		c.Next()
+	}
+}
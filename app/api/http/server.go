@@ -2,6 +2,7 @@ package http
 
 import (
 	"context"
+	"expvar"
 	"net/http"
 	"os"
 	"os/signal"
@@ -17,11 +18,22 @@ import (
 )
 
 type Server struct {
-	handler *handler.Handler
+	handler     *handler.Handler
+	idempotency gin.HandlerFunc
+	pinWindow   time.Duration
 }
 
 type Dependencies struct {
-	LoanUC app.LoanUseCase
+	LoanUC     app.LoanUseCase
+	PayoutUC   app.PayoutUseCase
+	BorrowerUC app.BorrowerUseCase
+	InvestorUC app.InvestorUseCase
+	FileUC     app.FileUseCase
+
+	IdempotencyStore response.IdempotencyStore
+
+	// PinWindow is how long reads of a client go to master DB after it writes
+	PinWindow time.Duration
 }
 
 var (
@@ -31,10 +43,12 @@ var (
 // Init will initialize this http package
 func Init(deps Dependencies) {
 	// add more uc here
-	h := handler.New(deps.LoanUC)
+	h := handler.New(deps.LoanUC, deps.PayoutUC, deps.BorrowerUC, deps.InvestorUC, deps.FileUC)
 
 	s = Server{
-		handler: h,
+		handler:     h,
+		idempotency: response.Idempotency(deps.IdempotencyStore, response.DefaultIdempotencyTTL, response.DefaultIdempotencyLease),
+		pinWindow:   deps.PinWindow,
 	}
 }
 
@@ -42,20 +56,52 @@ func Init(deps Dependencies) {
 func Run(port string) {
 	r := gin.Default()
 
-	r.Use(response.Middleware)
+	r.Use(response.Middleware, readYourWrites(s.pinWindow))
 
 	r.GET("/ping", func(c *gin.Context) {
 		c.String(http.StatusOK, "You know, for checking...")
 	})
 
+	// metrics, e.g. sqldb_tx_retries
+	r.GET("/debug/vars", gin.WrapH(expvar.Handler()))
+
 	loans := r.Group("/loans")
 	loans.GET("", s.handler.GetList)
 	loans.GET("/:id/detail", s.handler.GetDetail)
-
-	loans.POST("", s.handler.CreateLoan)
-	loans.PATCH("/:id/approve", s.handler.ApproveLoan)
-	loans.POST("/:id/invest", s.handler.InvestLoan)
-	loans.POST("/:id/disburse", s.handler.DisburseLoan)
+	loans.GET("/:id/history", s.handler.GetHistory)
+
+	// mutations can be retried safely with Idempotency-Key header
+	loans.POST("", s.idempotency, s.handler.CreateLoan)
+	loans.PATCH("/:id/approve", s.idempotency, s.handler.ApproveLoan)
+	loans.PATCH("/:id/reject", s.idempotency, s.handler.RejectLoan)
+	loans.PATCH("/:id/cancel", s.idempotency, s.handler.CancelLoan)
+	loans.POST("/:id/invest", s.idempotency, s.handler.InvestLoan)
+	loans.POST("/:id/investments/:investment_id/withdraw", s.idempotency, s.handler.WithdrawInvestment)
+	loans.POST("/:id/signature-requests", s.idempotency, s.handler.RequestSignature)
+	loans.POST("/:id/sign", s.idempotency, s.handler.SignAgreement)
+	loans.POST("/:id/disburse", s.idempotency, s.handler.DisburseLoan)
+	loans.POST("/:id/repay", s.idempotency, s.handler.RepayLoan)
+
+	borrowers := r.Group("/borrowers")
+	borrowers.GET("/:id", s.handler.GetBorrower)
+	borrowers.POST("", s.handler.RegisterBorrower)
+	borrowers.PATCH("/:id/kyc", s.handler.UpdateBorrowerKYC)
+
+	investors := r.Group("/investors")
+	investors.GET("/:id", s.handler.GetInvestor)
+	investors.GET("/:id/payouts", s.handler.GetInvestorPayouts)
+	investors.GET("/:id/wallet", s.handler.GetInvestorWallet)
+	investors.GET("/:id/portfolio", s.handler.GetInvestorPortfolio)
+
+	investors.POST("", s.handler.RegisterInvestor)
+	investors.POST("/:id/wallet/deposit", s.handler.DepositWallet)
+	investors.POST("/:id/wallet/withdraw", s.handler.WithdrawWallet)
+
+	files := r.Group("/files")
+	files.GET("/*key", s.handler.DownloadFile)
+
+	files.POST("/picture-proofs", s.handler.UploadPictureProof)
+	files.POST("/signed-agreements", s.handler.UploadSignedAgreement)
 
 	/* End of registering router */
 
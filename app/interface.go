@@ -2,16 +2,56 @@ package app
 
 import (
 	"context"
+	"time"
 
 	"simple-app/internal/model"
+	"simple-app/internal/pkg/money"
 )
 
 type LoanUseCase interface {
 	GetDetail(ctx context.Context, id int) (detail model.Detail, err error)
-	GetList(ctx context.Context) (loans []model.Loan, err error)
+	GetList(ctx context.Context, param model.LoanListParam) (page model.LoanPage, err error)
+	GetHistory(ctx context.Context, id int) (events []model.LoanEvent, err error)
 
 	Create(ctx context.Context, param model.Loan) (data model.Loan, err error)
 	Approve(ctx context.Context, param model.Approve) (id int, err error)
-	Invest(ctx context.Context, param model.Invest) (id int, total float32, status string, err error)
+	Invest(ctx context.Context, param model.Invest) (id int, total money.Money, status string, err error)
+	Withdraw(ctx context.Context, param model.Withdraw) (data model.Withdraw, total money.Money, err error)
+	RequestSignature(ctx context.Context, id, version int) (request model.SignatureRequest, err error)
+	Sign(ctx context.Context, param model.Sign) (data model.Signature, err error)
 	Disburse(ctx context.Context, param model.Disburse) (id int, err error)
+	Repay(ctx context.Context, param model.Repayment) (data model.Repayment, outstanding money.Money, status string, err error)
+	Reject(ctx context.Context, param model.StatusChange) (err error)
+	Cancel(ctx context.Context, param model.StatusChange) (err error)
+	Expire(ctx context.Context, now time.Time) (ids []int, err error)
+}
+
+type BorrowerUseCase interface {
+	Get(ctx context.Context, id int) (data model.Borrower, err error)
+
+	Register(ctx context.Context, param model.Borrower) (data model.Borrower, err error)
+	UpdateKYC(ctx context.Context, param model.KYC) (data model.Borrower, err error)
+}
+
+type InvestorUseCase interface {
+	Get(ctx context.Context, id int) (data model.Investor, err error)
+	GetWallet(ctx context.Context, id int) (wallet model.Wallet, transactions []model.WalletTransaction, err error)
+	GetPortfolio(ctx context.Context, id int) (portfolio model.Portfolio, err error)
+
+	Register(ctx context.Context, param model.Investor) (data model.Investor, err error)
+	Deposit(ctx context.Context, param model.WalletFund) (wallet model.Wallet, err error)
+	Withdraw(ctx context.Context, param model.WalletFund) (wallet model.Wallet, err error)
+}
+
+type PayoutUseCase interface {
+	GetByInvestor(ctx context.Context, investorID int) (payouts []model.Payout, err error)
+}
+
+type FileUseCase interface {
+	Upload(ctx context.Context, kind model.FileKind, data []byte) (file model.File, err error)
+	Download(ctx context.Context, param model.Download) (data []byte, contentType string, err error)
+}
+
+type OutboxUseCase interface {
+	Dispatch(ctx context.Context, now time.Time) (sent, dead int, err error)
 }
@@ -0,0 +1,45 @@
+package job
+
+import (
+	"context"
+	"time"
+
+	"simple-app/app"
+	"simple-app/internal/pkg/log"
+)
+
+// DefaultExpiryInterval is the time between runs of the expiry job when no interval is configured
+const DefaultExpiryInterval = time.Minute
+
+// RunExpiry expires loans that are over their funding deadline, once right away and then every interval until ctx is done
+func RunExpiry(ctx context.Context, loanUC app.LoanUseCase, interval time.Duration) {
+	if interval <= 0 {
+		interval = DefaultExpiryInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		ExpireOnce(ctx, loanUC)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// ExpireOnce runs the expiry job one time
+func ExpireOnce(ctx context.Context, loanUC app.LoanUseCase) {
+	ids, err := loanUC.Expire(ctx, time.Now())
+	if err != nil {
+		log.Errorf("failed to expire loans: %v", err)
+		return
+	}
+
+	if len(ids) > 0 {
+		log.Infof("expired loans: %v", ids)
+	}
+}
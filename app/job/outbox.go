@@ -0,0 +1,45 @@
+package job
+
+import (
+	"context"
+	"time"
+
+	"simple-app/app"
+	"simple-app/internal/pkg/log"
+)
+
+// DefaultDispatchInterval is the time between runs of the outbox dispatcher when no interval is configured
+const DefaultDispatchInterval = 10 * time.Second
+
+// RunDispatcher delivers the outbox messages that are due, once right away and then every interval until ctx is done
+func RunDispatcher(ctx context.Context, outboxUC app.OutboxUseCase, interval time.Duration) {
+	if interval <= 0 {
+		interval = DefaultDispatchInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		DispatchOnce(ctx, outboxUC)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// DispatchOnce runs the outbox dispatcher one time
+func DispatchOnce(ctx context.Context, outboxUC app.OutboxUseCase) {
+	sent, dead, err := outboxUC.Dispatch(ctx, time.Now())
+	if err != nil {
+		log.Errorf("failed to dispatch outbox: %v", err)
+		return
+	}
+
+	if sent > 0 || dead > 0 {
+		log.Infof("dispatched outbox messages: %d sent, %d dead", sent, dead)
+	}
+}
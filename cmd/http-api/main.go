@@ -4,14 +4,27 @@ import (
 	"context"
 	"flag"
 	"os"
+	"time"
 
 	"simple-app/app/api/http"
+	"simple-app/app/job"
 	"simple-app/config"
+	"simple-app/internal/app"
+	agrmnt "simple-app/internal/pkg/agreementLetter"
+	"simple-app/internal/pkg/blob"
 	"simple-app/internal/pkg/log"
 	"simple-app/internal/pkg/response"
 	"simple-app/internal/pkg/sqldb"
+	brRepo "simple-app/internal/repository/borrower"
+	idRepo "simple-app/internal/repository/idempotency"
+	ivRepo "simple-app/internal/repository/investor"
 	lnRepo "simple-app/internal/repository/loan"
+	poRepo "simple-app/internal/repository/payout"
+	bruc "simple-app/internal/usecase/borrower"
+	fluc "simple-app/internal/usecase/file"
+	ivuc "simple-app/internal/usecase/investor"
 	lnuc "simple-app/internal/usecase/loan"
+	pouc "simple-app/internal/usecase/payout"
 )
 
 var (
@@ -43,19 +56,28 @@ func main() {
 
 	/* initialize services */
 
-	// initialize db
+	// initialize db, the engine runs on postgres or mysql depending on databases.driver
+	driver, sqlCfg := cfg.Databases.SQL()
 	db, err := sqldb.Connect(ctx, sqldb.DBConfig{
-		Driver:             "postgres",
-		MasterDSN:          cfg.Databases.Postgres.Master,
-		FollowerDSN:        cfg.Databases.Postgres.Slave,
-		MaxOpenConnections: cfg.Databases.Postgres.MaxCon,
-		Retry:              cfg.Databases.Postgres.Retry,
+		Driver:             driver,
+		MasterDSN:          sqlCfg.Master,
+		FollowerDSN:        sqlCfg.Slave,
+		MaxOpenConnections: sqlCfg.MaxCon,
+		Retry:              sqlCfg.Retry,
 	})
 	if err != nil {
 		log.Fatal("Could not get Database connection :" + err.Error())
 		return
 	}
 
+	// reads go to slave, unless the client has just written or slave is behind
+	pinWindow := time.Duration(sqlCfg.PinWindow) * time.Second
+	db.Route(ctx, sqldb.RoutingConfig{
+		PinWindow:         pinWindow,
+		MaxReplicationLag: time.Duration(sqlCfg.MaxReplicationLag) * time.Second,
+		LagCheckInterval:  time.Duration(sqlCfg.LagCheckInterval) * time.Second,
+	})
+
 	withStackTrace := os.Getenv("APP_ENV") == "development"
 
 	response.Init(response.Opts{
@@ -67,13 +89,72 @@ func main() {
 		DB: db,
 	})
 
+	payoutRepo := poRepo.New(poRepo.Param{
+		DB: db,
+	})
+
+	borrowerRepo := brRepo.New(brRepo.Param{
+		DB: db,
+	})
+
+	investorRepo := ivRepo.New(ivRepo.Param{
+		DB: db,
+	})
+
+	idempotencyRepo := idRepo.New(idRepo.Param{
+		DB: db,
+	})
+
+	store := blob.NewLocal(cfg.Blob.Dir, cfg.Blob.BaseURL)
+
+	agreementLetter, err := agrmnt.New(agrmnt.Param{
+		Store:   store,
+		Version: cfg.Loan.AgreementLetterVersion,
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+
 	/* initialize usecase */
-	loanUc := lnuc.New(&loanRepo)
+	// repositories called inside WithTx join its transaction
+	txManager := sqldb.NewTxManager(db, sqldb.TxConfig{
+		MaxRetries:  sqlCfg.TxMaxRetries,
+		BaseBackoff: time.Duration(sqlCfg.TxBaseBackoff) * time.Millisecond,
+		MaxBackoff:  time.Duration(sqlCfg.TxMaxBackoff) * time.Millisecond,
+	})
+	payoutUc := pouc.New(&payoutRepo)
+	investorUc := ivuc.New(txManager, &investorRepo)
+	borrowerUc := bruc.New(&borrowerRepo)
+	signingKey, err := app.SigningKey(cfg.Blob)
+	if err != nil {
+		log.Fatal(err)
+		return
+	}
+
+	outboxUc := app.Outbox(cfg, db, txManager, store, signingKey, investorUc, borrowerUc)
+	fileUc := fluc.New(store, signingKey, time.Duration(cfg.Blob.DownloadTTL)*time.Second)
+	loanUc := lnuc.New(txManager, &loanRepo, &borrowerRepo, payoutUc, investorUc, agreementLetter, outboxUc, fileUc, time.Duration(cfg.Loan.FundingDays)*24*time.Hour)
+
+	/* initialize job */
+	if cfg.Loan.RunExpiry {
+		go job.RunExpiry(ctx, loanUc, time.Duration(cfg.Loan.ExpiryInterval)*time.Second)
+	}
+
+	if cfg.Outbox.RunDispatcher {
+		go job.RunDispatcher(ctx, outboxUc, time.Duration(cfg.Outbox.Interval)*time.Second)
+	}
 
 	/* initialize http handler */
 
 	http.Init(http.Dependencies{
-		LoanUC: loanUc,
+		LoanUC:     loanUc,
+		PayoutUC:   payoutUc,
+		BorrowerUC: borrowerUc,
+		InvestorUC: investorUc,
+		FileUC:     fileUc,
+
+		IdempotencyStore: &idempotencyRepo,
+		PinWindow:        pinWindow,
 	})
 
 	// run server
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"simple-app/app/job"
+	"simple-app/config"
+	agrmnt "simple-app/internal/pkg/agreementLetter"
+	"simple-app/internal/pkg/blob"
+	"simple-app/internal/pkg/log"
+	"simple-app/internal/pkg/sqldb"
+	brRepo "simple-app/internal/repository/borrower"
+	ivRepo "simple-app/internal/repository/investor"
+	lnRepo "simple-app/internal/repository/loan"
+	obRepo "simple-app/internal/repository/outbox"
+	poRepo "simple-app/internal/repository/payout"
+	fluc "simple-app/internal/usecase/file"
+	ivuc "simple-app/internal/usecase/investor"
+	lnuc "simple-app/internal/usecase/loan"
+	obuc "simple-app/internal/usecase/outbox"
+	pouc "simple-app/internal/usecase/payout"
+)
+
+var (
+	errLogPath   string
+	infoLogPath  string
+	debugLogPath string
+	once         bool
+	appName      = "simple-app-loan-expiry"
+)
+
+func main() {
+	flag.StringVar(&infoLogPath, "l", "", "info log")
+	flag.StringVar(&errLogPath, "e", "", "error log")
+	flag.StringVar(&debugLogPath, "d", "", "debug log")
+	flag.BoolVar(&once, "once", false, "run the expiry job once and exit, e.g. from cron")
+	flag.Parse()
+
+	log.SetLog(log.ErrorLevel, errLogPath, appName)
+	log.SetLog(log.InfoLevel, infoLogPath, appName)
+	log.SetLog(log.DebugLevel, debugLogPath, appName)
+
+	err := config.Init()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	cfg := config.Get()
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// initialize db, the engine runs on postgres or mysql depending on databases.driver
+	driver, sqlCfg := cfg.Databases.SQL()
+	db, err := sqldb.Connect(ctx, sqldb.DBConfig{
+		Driver:             driver,
+		MasterDSN:          sqlCfg.Master,
+		FollowerDSN:        sqlCfg.Slave,
+		MaxOpenConnections: sqlCfg.MaxCon,
+		Retry:              sqlCfg.Retry,
+	})
+	if err != nil {
+		log.Fatal("Could not get Database connection :" + err.Error())
+		return
+	}
+
+	/* initialize repo */
+	loanRepo := lnRepo.New(lnRepo.Param{
+		DB: db,
+	})
+
+	payoutRepo := poRepo.New(poRepo.Param{
+		DB: db,
+	})
+
+	borrowerRepo := brRepo.New(brRepo.Param{
+		DB: db,
+	})
+
+	investorRepo := ivRepo.New(ivRepo.Param{
+		DB: db,
+	})
+
+	outboxRepo := obRepo.New(obRepo.Param{
+		DB: db,
+	})
+
+	store := blob.NewLocal(cfg.Blob.Dir, cfg.Blob.BaseURL)
+
+	agreementLetter, err := agrmnt.New(agrmnt.Param{
+		Store:   store,
+		Version: cfg.Loan.AgreementLetterVersion,
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	/* initialize usecase */
+	// repositories called inside WithTx join its transaction
+	txManager := sqldb.NewTxManager(db, sqldb.TxConfig{
+		MaxRetries:  sqlCfg.TxMaxRetries,
+		BaseBackoff: time.Duration(sqlCfg.TxBaseBackoff) * time.Millisecond,
+		MaxBackoff:  time.Duration(sqlCfg.TxMaxBackoff) * time.Millisecond,
+	})
+	payoutUc := pouc.New(&payoutRepo)
+	investorUc := ivuc.New(txManager, &investorRepo)
+	// messages are only queued here, cmd/outbox-dispatcher delivers them
+	outboxUc := obuc.New(txManager, &outboxRepo, nil, cfg.Outbox.Routes, nil, cfg.Outbox.MaxAttempts, cfg.Outbox.BatchSize)
+	// no download URLs are signed by this job
+	fileUc := fluc.New(store, "", 0)
+	loanUc := lnuc.New(txManager, &loanRepo, &borrowerRepo, payoutUc, investorUc, agreementLetter, outboxUc, fileUc, time.Duration(cfg.Loan.FundingDays)*24*time.Hour)
+
+	if once {
+		job.ExpireOnce(ctx, loanUc)
+		return
+	}
+
+	job.RunExpiry(ctx, loanUc, time.Duration(cfg.Loan.ExpiryInterval)*time.Second)
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"simple-app/app/job"
+	"simple-app/config"
+	"simple-app/internal/app"
+	"simple-app/internal/pkg/blob"
+	"simple-app/internal/pkg/log"
+	"simple-app/internal/pkg/sqldb"
+	brRepo "simple-app/internal/repository/borrower"
+	ivRepo "simple-app/internal/repository/investor"
+	bruc "simple-app/internal/usecase/borrower"
+	ivuc "simple-app/internal/usecase/investor"
+)
+
+var (
+	errLogPath   string
+	infoLogPath  string
+	debugLogPath string
+	once         bool
+	appName      = "simple-app-outbox-dispatcher"
+)
+
+func main() {
+	flag.StringVar(&infoLogPath, "l", "", "info log")
+	flag.StringVar(&errLogPath, "e", "", "error log")
+	flag.StringVar(&debugLogPath, "d", "", "debug log")
+	flag.BoolVar(&once, "once", false, "run the dispatcher once and exit, e.g. from cron")
+	flag.Parse()
+
+	log.SetLog(log.ErrorLevel, errLogPath, appName)
+	log.SetLog(log.InfoLevel, infoLogPath, appName)
+	log.SetLog(log.DebugLevel, debugLogPath, appName)
+
+	err := config.Init()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	cfg := config.Get()
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// initialize db, the engine runs on postgres or mysql depending on databases.driver
+	driver, sqlCfg := cfg.Databases.SQL()
+	db, err := sqldb.Connect(ctx, sqldb.DBConfig{
+		Driver:             driver,
+		MasterDSN:          sqlCfg.Master,
+		FollowerDSN:        sqlCfg.Slave,
+		MaxOpenConnections: sqlCfg.MaxCon,
+		Retry:              sqlCfg.Retry,
+	})
+	if err != nil {
+		log.Fatal("Could not get Database connection :" + err.Error())
+		return
+	}
+
+	/* initialize repo */
+	investorRepo := ivRepo.New(ivRepo.Param{
+		DB: db,
+	})
+
+	borrowerRepo := brRepo.New(brRepo.Param{
+		DB: db,
+	})
+
+	store := blob.NewLocal(cfg.Blob.Dir, cfg.Blob.BaseURL)
+
+	/* initialize usecase */
+	// repositories called inside WithTx join its transaction
+	txManager := sqldb.NewTxManager(db, sqldb.TxConfig{
+		MaxRetries:  sqlCfg.TxMaxRetries,
+		BaseBackoff: time.Duration(sqlCfg.TxBaseBackoff) * time.Millisecond,
+		MaxBackoff:  time.Duration(sqlCfg.TxMaxBackoff) * time.Millisecond,
+	})
+	investorUc := ivuc.New(txManager, &investorRepo)
+	borrowerUc := bruc.New(&borrowerRepo)
+	signingKey, err := app.SigningKey(cfg.Blob)
+	if err != nil {
+		log.Fatal(err)
+		return
+	}
+
+	outboxUc := app.Outbox(cfg, db, txManager, store, signingKey, investorUc, borrowerUc)
+
+	if once {
+		job.DispatchOnce(ctx, outboxUc)
+		return
+	}
+
+	job.RunDispatcher(ctx, outboxUc, time.Duration(cfg.Outbox.Interval)*time.Second)
+}
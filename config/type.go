@@ -1,9 +1,14 @@
 package config
 
+import "os"
+
 // Config struct
 type Config struct {
 	App       AppConfig       `yaml:"app"`
 	Databases DatabasesConfig `yaml:"databases"`
+	Loan      LoanConfig      `yaml:"loan"`
+	Blob      BlobConfig      `yaml:"blob"`
+	Outbox    OutboxConfig    `yaml:"outbox"`
 }
 
 // AppConfig struct
@@ -11,8 +16,74 @@ type AppConfig struct {
 	Port string `yaml:"port"`
 }
 
+// LoanConfig struct
+type LoanConfig struct {
+	// FundingDays is how long an approved loan waits for investors before it expires
+	FundingDays int `yaml:"funding_days"`
+	// ExpiryInterval is the number of seconds between runs of the expiry job
+	ExpiryInterval int `yaml:"expiry_interval"`
+	// RunExpiry runs the expiry job inside the http process, otherwise use cmd/loan-expiry
+	RunExpiry bool `yaml:"run_expiry"`
+	// AgreementLetterVersion is the template of new agreement letters, e.g. "v1"
+	AgreementLetterVersion string `yaml:"agreement_letter_version"`
+}
+
+// BlobConfig struct
+type BlobConfig struct {
+	// Dir is where the local blob store writes documents
+	Dir string `yaml:"dir"`
+	// BaseURL is the prefix of the URL of stored documents
+	BaseURL string `yaml:"base_url"`
+	// SigningKey signs download URLs, SECRET_KEY of the environment is used when it is empty. One of them is required
+	SigningKey string `yaml:"signing_key"`
+	// DownloadTTL is the number of seconds a download URL is valid
+	DownloadTTL int `yaml:"download_ttl"`
+	// AttachmentTTL is the number of seconds the download URL of an email attachment is valid
+	AttachmentTTL int `yaml:"attachment_ttl"`
+}
+
+// DownloadSigningKey is the key that signs download URLs, SECRET_KEY of the environment when SigningKey is empty
+func (b BlobConfig) DownloadSigningKey() string {
+	if b.SigningKey != "" {
+		return b.SigningKey
+	}
+
+	return os.Getenv("SECRET_KEY")
+}
+
+// OutboxConfig struct
+type OutboxConfig struct {
+	// Interval is the number of seconds between runs of the dispatcher
+	Interval int `yaml:"interval"`
+	// BatchSize is how many messages are delivered per run
+	BatchSize int `yaml:"batch_size"`
+	// MaxAttempts is how many times a message is tried before it is dead
+	MaxAttempts int `yaml:"max_attempts"`
+	// RunDispatcher runs the dispatcher inside the http process, otherwise use cmd/outbox-dispatcher
+	RunDispatcher bool `yaml:"run_dispatcher"`
+	// Routes is the channel of every topic, e.g. agreement_letter: email
+	Routes  map[string]string `yaml:"routes"`
+	SMTP    SMTPConfig        `yaml:"smtp"`
+	Webhook WebhookConfig     `yaml:"webhook"`
+}
+
+// SMTPConfig struct, the email channel is only enabled when Addr is set
+type SMTPConfig struct {
+	Addr string `yaml:"addr"`
+	From string `yaml:"from"`
+}
+
+// WebhookConfig struct, the webhook channel is only enabled when URL is set
+type WebhookConfig struct {
+	URL string `yaml:"url"`
+	// Timeout is the number of seconds to wait for the receiver
+	Timeout int `yaml:"timeout"`
+}
+
 // DatabasesConfig struct
 type DatabasesConfig struct {
+	// Driver is the database the engine runs on, postgres or mysql. Empty is postgres
+	Driver   string         `yaml:"driver"`
 	Postgres PostgresConfig `yaml:"postgres"`
 	MySQL    MySQLConfig    `yaml:"mysql"`
 	Redis    RedisConfig    `yaml:"redis"`
@@ -32,12 +103,29 @@ type PostgresConfig struct {
 	Slave  string `yaml:"slave"`
 	MaxCon int    `yaml:"max_con"`
 	Retry  int    `yaml:"retry"`
+	// PinWindow is the number of seconds reads of a client go to master after it writes
+	PinWindow int `yaml:"pin_window"`
+	// MaxReplicationLag is the number of seconds slave can be behind before every read goes to master, 0 disables the check
+	MaxReplicationLag int `yaml:"max_replication_lag"`
+	// LagCheckInterval is the number of seconds between checks of the replication lag
+	LagCheckInterval int `yaml:"lag_check_interval"`
+	// TxMaxRetries is how many times a transaction failing on serialization or deadlock is run again, -1 disables the retry
+	TxMaxRetries int `yaml:"tx_max_retries"`
+	// TxBaseBackoff is the number of milliseconds waited before the first retry, doubled every retry with jitter
+	TxBaseBackoff int `yaml:"tx_base_backoff"`
+	// TxMaxBackoff is the number of milliseconds the wait between retries is capped at
+	TxMaxBackoff int `yaml:"tx_max_backoff"`
 }
 
-// MySQLConfig struct
-type MySQLConfig struct {
-	Master string `yaml:"master"`
-	Slave  string `yaml:"slave"`
+// MySQLConfig struct, it has the same settings as PostgresConfig. The DSN must have parseTime=true
+type MySQLConfig PostgresConfig
+
+// SQL get the driver and settings of the database selected by Driver
+func (d DatabasesConfig) SQL() (driver string, cfg PostgresConfig) {
+	if d.Driver == "mysql" {
+		return "mysql", PostgresConfig(d.MySQL)
+	}
+	return "postgres", d.Postgres
 }
 
 // ElasticConfig struct
@@ -3,6 +3,7 @@ module simple-app
 go 1.21.11
 
 require (
+	github.com/DATA-DOG/go-sqlmock v1.5.2
 	github.com/gin-gonic/gin v1.9.1
 	github.com/go-errors/errors v1.5.1
 	github.com/go-playground/locales v0.14.1
@@ -0,0 +1,75 @@
+// Package app holds the wiring shared by the commands in cmd
+package app
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"simple-app/config"
+	"simple-app/internal/model"
+	"simple-app/internal/pkg/blob"
+	"simple-app/internal/pkg/channel"
+	"simple-app/internal/pkg/sqldb"
+	obRepo "simple-app/internal/repository/outbox"
+	bruc "simple-app/internal/usecase/borrower"
+	fluc "simple-app/internal/usecase/file"
+	ivuc "simple-app/internal/usecase/investor"
+	obuc "simple-app/internal/usecase/outbox"
+)
+
+// ErrNoSigningKey is returned when neither blob.signing_key nor SECRET_KEY is set
+var ErrNoSigningKey = errors.New("blob.signing_key or SECRET_KEY must be set to sign download URLs")
+
+// SigningKey is the key that signs download URLs, it is required
+// since download URLs signed with an empty key could be made by anyone
+func SigningKey(cfg config.BlobConfig) (string, error) {
+	signingKey := cfg.DownloadSigningKey()
+	if signingKey == "" {
+		return "", ErrNoSigningKey
+	}
+
+	return signingKey, nil
+}
+
+// Outbox build the outbox usecase that delivers messages through the configured channels,
+// the attachments are linked with download URLs signed with signingKey
+func Outbox(cfg *config.Config, db *sqldb.DB, tx *sqldb.TxManager, store *blob.Local, signingKey string, investorUc *ivuc.Usecase, borrowerUc *bruc.Usecase) *obuc.Usecase {
+	outboxRepo := obRepo.New(obRepo.Param{
+		DB: db,
+	})
+
+	// attachments of emails are read later than a response, their download URLs are valid longer
+	attachmentTTL := time.Duration(cfg.Blob.AttachmentTTL) * time.Second
+	if attachmentTTL <= 0 {
+		attachmentTTL = fluc.DefaultAttachmentTTL
+	}
+	attachments := fluc.New(store, signingKey, attachmentTTL)
+
+	return obuc.New(tx, &outboxRepo, channels(cfg.Outbox, investorUc, borrowerUc), cfg.Outbox.Routes, attachments, cfg.Outbox.MaxAttempts, cfg.Outbox.BatchSize)
+}
+
+// channels build the delivery channels of outbox, log is always available
+func channels(cfg config.OutboxConfig, investorUc *ivuc.Usecase, borrowerUc *bruc.Usecase) map[string]obuc.Channel {
+	channels := map[string]obuc.Channel{
+		channel.Log: channel.NewLog(),
+	}
+
+	if cfg.SMTP.Addr != "" {
+		channels[channel.Email] = channel.NewSMTP(cfg.SMTP.Addr, cfg.SMTP.From, func(ctx context.Context, topic string, recipientID int) (string, error) {
+			if topic == model.TopicSignatureRequest {
+				borrower, err := borrowerUc.Get(ctx, recipientID)
+				return borrower.Email, err
+			}
+
+			investor, err := investorUc.Get(ctx, recipientID)
+			return investor.Email, err
+		})
+	}
+
+	if cfg.Webhook.URL != "" {
+		channels[channel.Webhook] = channel.NewWebhook(cfg.Webhook.URL, time.Duration(cfg.Webhook.Timeout)*time.Second)
+	}
+
+	return channels
+}
@@ -0,0 +1,76 @@
+package app
+
+import (
+	"testing"
+
+	"simple-app/config"
+	"simple-app/internal/pkg/channel"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSigningKey(t *testing.T) {
+	testCases := []struct {
+		name      string
+		cfg       config.BlobConfig
+		secretKey string
+		want      string
+		wantErr   error
+	}{
+		{
+			name: "signing key",
+			cfg:  config.BlobConfig{SigningKey: "blob-key"},
+			want: "blob-key",
+		},
+		{
+			name:      "secret key",
+			secretKey: "secret-key",
+			want:      "secret-key",
+		},
+		{
+			name:    "no key",
+			wantErr: ErrNoSigningKey,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Setenv("SECRET_KEY", tc.secretKey)
+
+			key, err := SigningKey(tc.cfg)
+			require.ErrorIs(t, err, tc.wantErr)
+			require.Equal(t, tc.want, key)
+		})
+	}
+}
+
+func TestChannels(t *testing.T) {
+	testCases := []struct {
+		name string
+		cfg  config.OutboxConfig
+		want []string
+	}{
+		{
+			name: "log only",
+			want: []string{channel.Log},
+		},
+		{
+			name: "email and webhook",
+			cfg: config.OutboxConfig{
+				SMTP:    config.SMTPConfig{Addr: "localhost:1025"},
+				Webhook: config.WebhookConfig{URL: "http://localhost/hook"},
+			},
+			want: []string{channel.Email, channel.Log, channel.Webhook},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var names []string
+			for name := range channels(tc.cfg, nil, nil) {
+				names = append(names, name)
+			}
+			require.ElementsMatch(t, tc.want, names)
+		})
+	}
+}
@@ -0,0 +1,8 @@
+package model
+
+// AgreementLetter is the stored document of the agreement letter of loan, Key is where it is in the blob store
+type AgreementLetter struct {
+	Key     string `json:"key"`
+	SHA256  string `json:"sha256"`
+	Version string `json:"version"`
+}
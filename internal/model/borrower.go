@@ -0,0 +1,31 @@
+package model
+
+import (
+	"simple-app/internal/pkg/money"
+	"time"
+)
+
+// KYCStatus is the result of verifying the identity of borrower
+type KYCStatus string
+
+const (
+	KYCPending  KYCStatus = "pending"
+	KYCVerified KYCStatus = "verified"
+	KYCRejected KYCStatus = "rejected"
+)
+
+type Borrower struct {
+	ID          int         `json:"id" db:"id"`
+	Name        string      `json:"name" db:"name" validate:"required"`
+	Email       string      `json:"email" db:"email" validate:"required,email"`
+	PhoneNumber string      `json:"phone_number" db:"phone_number"`
+	KYCStatus   KYCStatus   `json:"kyc_status" db:"kyc_status"`
+	CreditLimit money.Money `json:"credit_limit" db:"credit_limit" validate:"required,gt=0"`
+	CreatedAt   *time.Time  `json:"created_at,omitempty" db:"created_at"`
+}
+
+// KYC is the request to update the KYC status of borrower
+type KYC struct {
+	ID        int       `json:"id" db:"id"`
+	KYCStatus KYCStatus `json:"kyc_status" db:"kyc_status" validate:"required,oneof=pending verified rejected"`
+}
@@ -0,0 +1,59 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrNotFound                 = errors.New("not found")
+	ErrInvalidTransition        = errors.New("status of loan is invalid")
+	ErrAmountExceedsRemaining   = errors.New("amount exceeds remaining amount")
+	ErrFundingClosed            = errors.New("funding deadline of loan has passed")
+	ErrOverpayment              = errors.New("amount exceeds outstanding balance")
+	ErrBorrowerNotRegistered    = errors.New("borrower is not registered")
+	ErrBorrowerNotVerified      = errors.New("borrower is not KYC verified")
+	ErrCreditLimitExceeded      = errors.New("principal amount exceeds credit limit of borrower")
+	ErrInvestorNotRegistered    = errors.New("investor is not registered")
+	ErrInsufficientBalance      = errors.New("available balance of investor is not enough")
+	ErrSignatureRequired        = errors.New("agreement letter of loan is not signed by borrower")
+	ErrInvalidSignatureToken    = errors.New("signature token is invalid or expired")
+	ErrAgreementMismatch        = errors.New("signed agreement letter does not match the agreement letter of loan")
+	ErrUnsupportedFileType      = errors.New("type of file is not supported")
+	ErrFileTooLarge             = errors.New("file is too large")
+	ErrInvalidFile              = errors.New("file is not uploaded to this service")
+	ErrInvalidDownloadSignature = errors.New("download signature is invalid or expired")
+	ErrVersionConflict          = errors.New("loan has been changed by another request")
+	ErrInvalidCursor            = errors.New("cursor is invalid")
+)
+
+// TransitionError is returned when a loan can not move from its current status to the requested one
+type TransitionError struct {
+	From LoanStatus
+	To   LoanStatus
+}
+
+func (e *TransitionError) Error() string {
+	return ErrInvalidTransition.Error() + ": cannot move from " + e.From.ToString() + " to " + e.To.ToString()
+}
+
+// Is makes errors.Is(err, ErrInvalidTransition) true for every TransitionError
+func (e *TransitionError) Is(target error) bool {
+	return target == ErrInvalidTransition
+}
+
+// VersionConflictError is returned when a loan is updated with a version that is not its current version
+type VersionConflictError struct {
+	LoanID   int
+	Expected int
+	Actual   int
+}
+
+func (e *VersionConflictError) Error() string {
+	return fmt.Sprintf("%s: loan %d is at version %d, not %d", ErrVersionConflict.Error(), e.LoanID, e.Actual, e.Expected)
+}
+
+// Is makes errors.Is(err, ErrVersionConflict) true for every VersionConflictError
+func (e *VersionConflictError) Is(target error) bool {
+	return target == ErrVersionConflict
+}
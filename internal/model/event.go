@@ -0,0 +1,41 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// LoanEvent is an immutable record of a change made to a loan
+type LoanEvent struct {
+	ID         int             `json:"id" db:"id"`
+	LoanID     int             `json:"loan_id" db:"loan_id"`
+	Event      string          `json:"event" db:"event"`
+	Actor      string          `json:"actor" db:"actor"`
+	FromStatus *LoanStatus     `json:"from_status,omitempty" db:"from_status"`
+	ToStatus   *LoanStatus     `json:"to_status,omitempty" db:"to_status"`
+	Payload    json.RawMessage `json:"payload" db:"payload"`
+	RequestID  string          `json:"request_id" db:"request_id"`
+	CreatedAt  *time.Time      `json:"created_at" db:"created_at"`
+}
+
+const (
+	EventCreated            = "created"
+	EventApproved           = "approved"
+	EventStatusChanged      = "status_changed"
+	EventInvested           = "invested"
+	EventWithdrawn          = "withdrawn"
+	EventReleased           = "released"
+	EventDisbursed          = "disbursed"
+	EventScheduleGenerated  = "schedule_generated"
+	EventRepaid             = "repaid"
+	EventSignatureRequested = "signature_requested"
+	EventSigned             = "signed"
+)
+
+// StatusChange is the request to move a loan to another status, e.g. reject or cancel
+type StatusChange struct {
+	ID      int    `json:"id"`
+	ActorID int    `json:"actor_id" validate:"required"`
+	Reason  string `json:"reason"`
+	Version int    `json:"-"`
+}
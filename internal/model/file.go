@@ -0,0 +1,28 @@
+package model
+
+// FileKind is the purpose of uploaded file, it decides the accepted types and size
+type FileKind string
+
+const (
+	FilePictureProof    FileKind = "picture-proof"
+	FileSignedAgreement FileKind = "signed-agreement"
+)
+
+// File is an uploaded file. URL is the internal URL that is given to other requests, e.g. approve,
+// DownloadURL is the same file with a signature that expires
+type File struct {
+	Kind        FileKind `json:"kind"`
+	Key         string   `json:"key"`
+	URL         string   `json:"url"`
+	DownloadURL string   `json:"download_url"`
+	ContentType string   `json:"content_type"`
+	Size        int      `json:"size"`
+	SHA256      string   `json:"sha256"`
+}
+
+// Download is the request to download a file by its key, Expires and Signature come from the download URL
+type Download struct {
+	Key       string `json:"key" validate:"required"`
+	Expires   int64  `json:"expires" validate:"required"`
+	Signature string `json:"signature" validate:"required"`
+}
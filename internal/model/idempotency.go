@@ -0,0 +1,25 @@
+package model
+
+import "time"
+
+// IdempotencyStatus is the state of the request of idempotency key
+type IdempotencyStatus string
+
+const (
+	IdempotencyInProgress IdempotencyStatus = "in_progress"
+	IdempotencyCompleted  IdempotencyStatus = "completed"
+)
+
+// IdempotencyKey is the first request sent with an Idempotency-Key header and its response,
+// a repeat with the same key gets the stored response back
+type IdempotencyKey struct {
+	Key          string            `json:"key" db:"idempotency_key"`
+	RequestHash  string            `json:"request_hash" db:"request_hash"`
+	Status       IdempotencyStatus `json:"status" db:"status"`
+	ResponseCode int               `json:"response_code" db:"response_code"`
+	ResponseBody []byte            `json:"response_body" db:"response_body"`
+	ContentType  string            `json:"content_type" db:"content_type"`
+	ExpiresAt    *time.Time        `json:"expires_at" db:"expires_at"`
+	ClaimedUntil *time.Time        `json:"claimed_until" db:"claimed_until"`
+	CreatedAt    *time.Time        `json:"created_at" db:"created_at"`
+}
@@ -0,0 +1,29 @@
+package model
+
+import (
+	"simple-app/internal/pkg/money"
+	"time"
+)
+
+type Installment struct {
+	ID                 int         `json:"id" db:"id"`
+	LoanID             int         `json:"loan_id" db:"loan_id"`
+	Number             int         `json:"number" db:"installment_number"`
+	DueDate            time.Time   `json:"due_date" db:"due_date"`
+	PrincipalAmount    money.Money `json:"principal_amount" db:"principal_amount"`
+	InterestAmount     money.Money `json:"interest_amount" db:"interest_amount"`
+	TotalAmount        money.Money `json:"total_amount" db:"total_amount"`
+	OutstandingBalance money.Money `json:"outstanding_balance" db:"outstanding_balance"`
+}
+
+// RepaymentMethod is the method used to build the installment schedule
+type RepaymentMethod string
+
+const (
+	// FLAT charges interest on the original principal every period
+	FLAT RepaymentMethod = "flat"
+	// ANNUITY keeps the installment amount equal every period
+	ANNUITY RepaymentMethod = "annuity"
+	// BULLET pays interest every period and the whole principal at the end
+	BULLET RepaymentMethod = "bullet"
+)
@@ -0,0 +1,79 @@
+package model
+
+import (
+	"simple-app/internal/pkg/money"
+	"time"
+)
+
+type Investor struct {
+	ID        int        `json:"id" db:"id"`
+	Name      string     `json:"name" db:"name" validate:"required"`
+	Email     string     `json:"email" db:"email" validate:"required,email"`
+	CreatedAt *time.Time `json:"created_at,omitempty" db:"created_at"`
+}
+
+// Wallet is the money of investor, Held is the part of Balance that is reserved for investments
+// which are not disbursed yet, so only Balance - Held can be invested or withdrawn
+type Wallet struct {
+	InvestorID int         `json:"investor_id" db:"investor_id"`
+	Balance    money.Money `json:"balance" db:"balance"`
+	Held       money.Money `json:"held" db:"held"`
+	UpdatedAt  *time.Time  `json:"updated_at,omitempty" db:"updated_at"`
+}
+
+// Available returns the balance that is not held
+func (w Wallet) Available() money.Money {
+	return w.Balance.Sub(w.Held)
+}
+
+// WalletTransactionType is the kind of movement in the wallet of investor
+type WalletTransactionType string
+
+const (
+	WalletDeposit    WalletTransactionType = "deposit"
+	WalletWithdrawal WalletTransactionType = "withdrawal"
+	WalletHold       WalletTransactionType = "hold"
+	WalletRelease    WalletTransactionType = "release"
+	WalletCapture    WalletTransactionType = "capture"
+	WalletPayout     WalletTransactionType = "payout"
+)
+
+// WalletTransaction is an entry of the wallet ledger
+type WalletTransaction struct {
+	ID         int                   `json:"id" db:"id"`
+	InvestorID int                   `json:"investor_id" db:"investor_id"`
+	Type       WalletTransactionType `json:"type" db:"type"`
+	Amount     money.Money           `json:"amount" db:"amount"`
+	LoanID     *int                  `json:"loan_id,omitempty" db:"loan_id"`
+	InvestID   *int                  `json:"invest_id,omitempty" db:"invest_id"`
+	CreatedAt  *time.Time            `json:"created_at,omitempty" db:"created_at"`
+}
+
+// WalletFund is the request to deposit to or withdraw from the wallet of investor
+type WalletFund struct {
+	InvestorID int         `json:"investor_id"`
+	Amount     money.Money `json:"amount" validate:"required,gt=0"`
+}
+
+// Position is an investment of investor in a loan
+type Position struct {
+	InvestID          int         `json:"invest_id" db:"invest_id"`
+	LoanID            int         `json:"loan_id" db:"loan_id"`
+	Status            LoanStatus  `json:"status" db:"status"`
+	StatusStr         string      `json:"status_str"`
+	Roi               float64     `json:"roi" db:"roi"`
+	Amount            money.Money `json:"amount" db:"amount"`
+	ExpectedReturn    money.Money `json:"expected_return"`
+	PrincipalReceived money.Money `json:"principal_received" db:"principal_received"`
+	ReturnReceived    money.Money `json:"return_received" db:"return_received"`
+}
+
+// Portfolio is every position of investor and their totals
+type Portfolio struct {
+	InvestorID             int         `json:"investor_id"`
+	TotalInvested          money.Money `json:"total_invested"`
+	TotalExpectedReturn    money.Money `json:"total_expected_return"`
+	TotalPrincipalReceived money.Money `json:"total_principal_received"`
+	TotalReturnReceived    money.Money `json:"total_return_received"`
+	Positions              []Position  `json:"positions"`
+}
@@ -0,0 +1,33 @@
+package model
+
+import (
+	"simple-app/internal/pkg/money"
+	"time"
+)
+
+const (
+	DefaultListLimit = 20
+	MaxListLimit     = 100
+	DefaultLoanSort  = "-id"
+)
+
+// LoanListParam is the filter, sort and page of list of loans. Sort is one of id, principal_amount or approval_date,
+// prefixed with "-" to sort descending. Cursor is the next_cursor of the previous page, it must be used with the same sort
+type LoanListParam struct {
+	Status       string       `form:"status" validate:"omitempty,oneof=proposed approved invested disbursed repaid rejected cancelled expired"`
+	BorrowerID   int          `form:"borrower_id" validate:"omitempty,min=1"`
+	MinPrincipal *money.Money `form:"min_principal"`
+	MaxPrincipal *money.Money `form:"max_principal"`
+	ApprovedFrom *time.Time   `form:"approved_from" time_format:"2006-01-02"`
+	ApprovedTo   *time.Time   `form:"approved_to" time_format:"2006-01-02"`
+	Sort         string       `form:"sort" validate:"omitempty,oneof=id -id principal_amount -principal_amount approval_date -approval_date"`
+	Limit        int          `form:"limit" validate:"omitempty,min=1,max=100"`
+	Cursor       string       `form:"cursor"`
+}
+
+// LoanPage is a page of list of loans, NextCursor is empty on the last page
+type LoanPage struct {
+	Data       []Loan `json:"data"`
+	NextCursor string `json:"next_cursor"`
+	Total      int    `json:"total"`
+}
@@ -1,49 +1,85 @@
 package model
 
-import "time"
+import (
+	"simple-app/internal/pkg/money"
+	"time"
+)
 
+// Loan, AgreementLetterKey is where the agreement letter is stored, AgreementLetterURL is a download URL
+// of it that expires and is only made when the loan is shown
 type Loan struct {
-	ID                 int        `json:"id" db:"id"`
-	BorrowerID         int        `json:"borrower_id" db:"borrower_id" validate:"required"`
-	PrincipalAmount    float64    `json:"principal_amount" db:"principal_amount" validate:"required"`
-	Rate               float64    `json:"rate" db:"rate" validate:"required"`
-	Roi                float64    `json:"roi" db:"roi" validate:"required"`
-	Status             LoanStatus `json:"status" db:"status"`
-	StatusStr          string     `json:"status_str,omitempty"`
-	AgreementLetterURL string     `json:"agreement_letter_url" db:"agreement_letter_url"`
-	PictureProofURL    *string    `json:"picture_proof_url,omitempty" db:"picture_proof_url"`
-	ApproverID         *int       `json:"approver_id,omitempty" db:"approver_id"`
-	ApprovalDate       *time.Time `json:"approval_date,omitempty" db:"approval_date"`
+	ID                     int             `json:"id" db:"id"`
+	BorrowerID             int             `json:"borrower_id" db:"borrower_id" validate:"required"`
+	PrincipalAmount        money.Money     `json:"principal_amount" db:"principal_amount" validate:"required,gt=0"`
+	Rate                   float64         `json:"rate" db:"rate" validate:"required"`
+	Roi                    float64         `json:"roi" db:"roi" validate:"required"`
+	Tenor                  int             `json:"tenor" db:"tenor" validate:"required,min=1"`
+	RepaymentMethod        RepaymentMethod `json:"repayment_method" db:"repayment_method" validate:"omitempty,oneof=flat annuity bullet"`
+	Status                 LoanStatus      `json:"status" db:"status"`
+	StatusStr              string          `json:"status_str,omitempty"`
+	AgreementLetterKey     string          `json:"-" db:"agreement_letter_key"`
+	AgreementLetterURL     string          `json:"agreement_letter_url" db:"-"`
+	AgreementLetterSHA256  string          `json:"agreement_letter_sha256,omitempty" db:"agreement_letter_sha256"`
+	AgreementLetterVersion string          `json:"agreement_letter_version,omitempty" db:"agreement_letter_version"`
+	PictureProofKey        *string         `json:"-" db:"picture_proof_key"`
+	PictureProofURL        string          `json:"picture_proof_url,omitempty" db:"-"`
+	ApproverID             *int            `json:"approver_id,omitempty" db:"approver_id"`
+	ApprovalDate           *time.Time      `json:"approval_date,omitempty" db:"approval_date"`
+	FundingDeadline        *time.Time      `json:"funding_deadline,omitempty" db:"funding_deadline"`
+	Version                int             `json:"version" db:"version"`
 }
 
+// Approve, PictureProofURL is the uploaded picture proof, it is kept by its key
 type Approve struct {
 	ID              int        `json:"id" db:"id" validate:"required"`
-	PictureProofURL *string    `json:"picture_proof_url" db:"picture_proof_url" validate:"required"`
+	PictureProofURL *string    `json:"picture_proof_url" db:"-" validate:"required"`
+	PictureProofKey *string    `json:"-" db:"picture_proof_key"`
 	ApproverID      int        `json:"approver_id" db:"approver_id"  validate:"required"`
 	ApprovalDate    *time.Time `json:"approval_date" db:"approval_date"`
+	FundingDeadline *time.Time `json:"funding_deadline" db:"funding_deadline"`
 	Status          LoanStatus `json:"status" db:"status"`
+	Version         int        `json:"-" db:"version"`
 }
 
 type Invest struct {
-	ID         int        `json:"id"`
-	LoanID     int        `json:"loan_id" db:"loan_id" validate:"required"`
-	InvestorID int        `json:"investor_id" db:"investor_id"  validate:"required"`
-	Amount     float32    `json:"amount" db:"amount"  validate:"required"`
-	Status     LoanStatus `json:"status,omitempty"`
+	ID         int         `json:"id"`
+	LoanID     int         `json:"loan_id" db:"loan_id" validate:"required"`
+	InvestorID int         `json:"investor_id" db:"investor_id"  validate:"required"`
+	Amount     money.Money `json:"amount" db:"amount"  validate:"required,gt=0"`
+	Status     LoanStatus  `json:"status,omitempty"`
+	Version    int         `json:"-" db:"-"`
+}
+
+// Withdraw is the request of investor to pull back an investment before the loan is fully funded
+type Withdraw struct {
+	ID          int         `json:"id" db:"id"`
+	LoanID      int         `json:"loan_id" db:"loan_id"`
+	InvestorID  int         `json:"investor_id" db:"investor_id" validate:"required"`
+	Amount      money.Money `json:"amount" db:"amount"`
+	Reason      string      `json:"reason" db:"withdrawal_reason" validate:"required"`
+	WithdrawnAt *time.Time  `json:"withdrawn_at" db:"withdrawn_at"`
+	Version     int         `json:"-" db:"-"`
 }
 
+// Disburse, SignedAgreementURL is an uploaded scan of the signed agreement letter,
+// the certificate of the e-signature of borrower is used when it is empty.
+// SignedAgreementKey is where the signed agreement is stored, it is shown as a download URL that expires
 type Disburse struct {
 	ID                 int        `json:"id"`
 	LoanID             int        `json:"loan_id" db:"loan_id" validate:"required"`
-	SignedAgreementURL string     `json:"signed_agreement_url"   db:"signed_agreement_url" validate:"required"`
+	SignedAgreementURL string     `json:"signed_agreement_url"   db:"-"`
+	SignedAgreementKey string     `json:"-" db:"signed_agreement_key"`
 	DisburseEmployeeID int        `json:"disburser_employee_id"  db:"disburser_employee_id" validate:"required"`
 	DisbursementDate   *time.Time `json:"disbursement_date"  db:"disbursement_date"`
+	Version            int        `json:"-" db:"-"`
 }
 
 type Detail struct {
 	Loan
-	Investors     []Invest   `json:"investors"`
-	Disbursements []Disburse `json:"disbursement"`
+	Investors     []Invest      `json:"investors"`
+	Disbursements []Disburse    `json:"disbursement"`
+	Installments  []Installment `json:"installments"`
+	Repayments    []Repayment   `json:"repayments"`
 }
 
 type LoanStatus int
@@ -53,6 +89,10 @@ const (
 	APPROVED
 	INVESTED
 	DISBURSED
+	REPAID
+	REJECTED
+	CANCELLED
+	EXPIRED
 )
 
 func (ls LoanStatus) ToString() string {
@@ -65,7 +105,26 @@ func (ls LoanStatus) ToString() string {
 		return "invested"
 	case ls == DISBURSED:
 		return "disbursed"
+	case ls == REPAID:
+		return "repaid"
+	case ls == REJECTED:
+		return "rejected"
+	case ls == CANCELLED:
+		return "cancelled"
+	case ls == EXPIRED:
+		return "expired"
 	}
 
 	return "invalid"
 }
+
+// ParseLoanStatus is the reverse of ToString
+func ParseLoanStatus(s string) (LoanStatus, bool) {
+	for ls := PROPOSED; ls <= EXPIRED; ls++ {
+		if ls.ToString() == s {
+			return ls, true
+		}
+	}
+
+	return 0, false
+}
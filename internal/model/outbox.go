@@ -0,0 +1,53 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// OutboxStatus is the delivery state of outbox message
+type OutboxStatus string
+
+const (
+	OutboxPending OutboxStatus = "pending"
+	OutboxSent    OutboxStatus = "sent"
+	// OutboxDead is a message that failed every attempt, it is kept for investigation and never retried
+	OutboxDead OutboxStatus = "dead"
+)
+
+const (
+	TopicAgreementLetter = "agreement_letter"
+	TopicLoanExpired     = "loan_expired"
+	// TopicSignatureRequest is sent to the borrower, unlike the other topics that are sent to investors
+	TopicSignatureRequest = "signature_request"
+)
+
+// OutboxMessage is a message written in the same transaction as the change it is about,
+// and delivered by the dispatcher once the transaction is committed
+type OutboxMessage struct {
+	ID            int             `json:"id" db:"id"`
+	Topic         string          `json:"topic" db:"topic"`
+	Channel       string          `json:"channel" db:"channel"`
+	RecipientID   int             `json:"recipient_id" db:"recipient_id"`
+	Payload       json.RawMessage `json:"payload" db:"payload"`
+	Status        OutboxStatus    `json:"status" db:"status"`
+	Attempts      int             `json:"attempts" db:"attempts"`
+	MaxAttempts   int             `json:"max_attempts" db:"max_attempts"`
+	NextAttemptAt *time.Time      `json:"next_attempt_at" db:"next_attempt_at"`
+	LastError     string          `json:"last_error" db:"last_error"`
+	CreatedAt     *time.Time      `json:"created_at" db:"created_at"`
+	SentAt        *time.Time      `json:"sent_at,omitempty" db:"sent_at"`
+}
+
+// Message is the content of outbox message that is delivered to the recipient.
+// AttachmentURL is a download URL of AttachmentKey that is made at every delivery, because it expires.
+// Secret is only for the recipient, e.g. a signature token, it is put at the end of the delivered body
+// and removed from the stored message once it is sent or dead
+type Message struct {
+	Subject       string `json:"subject"`
+	Body          string `json:"body"`
+	LoanID        int    `json:"loan_id,omitempty"`
+	AttachmentKey string `json:"attachment_key,omitempty"`
+	AttachmentURL string `json:"attachment_url,omitempty"`
+	Secret        string `json:"secret,omitempty"`
+}
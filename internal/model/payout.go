@@ -0,0 +1,18 @@
+package model
+
+import (
+	"simple-app/internal/pkg/money"
+	"time"
+)
+
+type Payout struct {
+	ID              int         `json:"id" db:"id"`
+	LoanID          int         `json:"loan_id" db:"loan_id"`
+	RepaymentID     int         `json:"repayment_id" db:"repayment_id"`
+	InvestID        int         `json:"invest_id" db:"invest_id"`
+	InvestorID      int         `json:"investor_id" db:"investor_id"`
+	PrincipalAmount money.Money `json:"principal_amount" db:"principal_amount"`
+	ReturnAmount    money.Money `json:"return_amount" db:"return_amount"`
+	Amount          money.Money `json:"amount" db:"amount"`
+	CreatedAt       *time.Time  `json:"created_at" db:"created_at"`
+}
@@ -0,0 +1,16 @@
+package model
+
+import (
+	"simple-app/internal/pkg/money"
+	"time"
+)
+
+type Repayment struct {
+	ID              int         `json:"id" db:"id"`
+	LoanID          int         `json:"loan_id" db:"loan_id" validate:"required"`
+	Amount          money.Money `json:"amount" db:"amount" validate:"required,gt=0"`
+	PrincipalAmount money.Money `json:"principal_amount" db:"principal_amount"`
+	InterestAmount  money.Money `json:"interest_amount" db:"interest_amount"`
+	PaymentDate     *time.Time  `json:"payment_date" db:"payment_date"`
+	Version         int         `json:"-" db:"-"`
+}
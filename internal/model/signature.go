@@ -0,0 +1,42 @@
+package model
+
+import "time"
+
+// Signature is the e-signature of borrower on the agreement letter of loan. It is requested with a token
+// that is handed to the borrower, and is signed once the borrower submits the token.
+// Only the SHA-256 of the token is kept. The certificate of signature is stored at SignedDocumentKey,
+// SignedDocumentURL is a download URL of it that expires
+type Signature struct {
+	ID                     int        `json:"id" db:"id"`
+	LoanID                 int        `json:"loan_id" db:"loan_id"`
+	BorrowerID             int        `json:"borrower_id" db:"borrower_id"`
+	TokenHash              string     `json:"-" db:"token_hash"`
+	AgreementLetterSHA256  string     `json:"agreement_letter_sha256" db:"agreement_letter_sha256"`
+	AgreementLetterVersion string     `json:"agreement_letter_version" db:"agreement_letter_version"`
+	SignerName             string     `json:"signer_name" db:"signer_name"`
+	SignerIP               string     `json:"signer_ip" db:"signer_ip"`
+	SignedDocumentSHA256   string     `json:"signed_document_sha256" db:"signed_document_sha256"`
+	SignedDocumentKey      string     `json:"-" db:"signed_document_key"`
+	SignedDocumentURL      string     `json:"signed_document_url,omitempty" db:"-"`
+	ExpiresAt              *time.Time `json:"expires_at" db:"expires_at"`
+	SignedAt               *time.Time `json:"signed_at,omitempty" db:"signed_at"`
+	CreatedAt              *time.Time `json:"created_at,omitempty" db:"created_at"`
+}
+
+// SignatureRequest acknowledges that a token to sign the agreement letter of loan is sent to the borrower.
+// The token itself is only in the message to the borrower
+type SignatureRequest struct {
+	LoanID    int        `json:"loan_id"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+// Sign is the request of borrower to sign the agreement letter, the SHA-256 is of the letter the borrower has read
+type Sign struct {
+	LoanID                int    `json:"loan_id"`
+	Token                 string `json:"-" validate:"required"`
+	SignerName            string `json:"signer_name" validate:"required"`
+	AgreementLetterSHA256 string `json:"agreement_letter_sha256" validate:"required,len=64,hexadecimal"`
+	SignerIP              string `json:"-"`
+	// Version is the version of loan the borrower has read
+	Version int `json:"-"`
+}
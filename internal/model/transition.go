@@ -0,0 +1,35 @@
+package model
+
+// transitions lists every status a loan can move to from its current status.
+// A status that is not a key here is final.
+var transitions = map[LoanStatus][]LoanStatus{
+	PROPOSED:  {APPROVED, REJECTED, CANCELLED},
+	APPROVED:  {INVESTED, CANCELLED, EXPIRED},
+	INVESTED:  {DISBURSED},
+	DISBURSED: {REPAID},
+}
+
+// CanTransitionTo reports whether a loan in this status can move to the given status
+func (ls LoanStatus) CanTransitionTo(to LoanStatus) bool {
+	for _, next := range transitions[ls] {
+		if next == to {
+			return true
+		}
+	}
+
+	return false
+}
+
+// TransitionTo returns *TransitionError when a loan in this status can not move to the given status
+func (ls LoanStatus) TransitionTo(to LoanStatus) error {
+	if !ls.CanTransitionTo(to) {
+		return &TransitionError{From: ls, To: to}
+	}
+
+	return nil
+}
+
+// IsFinal reports whether a loan in this status can not move anymore
+func (ls LoanStatus) IsFinal() bool {
+	return len(transitions[ls]) == 0
+}
@@ -0,0 +1,60 @@
+package model
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTransitionTo(t *testing.T) {
+	testCases := []struct {
+		from    LoanStatus
+		to      LoanStatus
+		allowed bool
+	}{
+		{from: PROPOSED, to: APPROVED, allowed: true},
+		{from: PROPOSED, to: REJECTED, allowed: true},
+		{from: PROPOSED, to: CANCELLED, allowed: true},
+		{from: PROPOSED, to: INVESTED, allowed: false},
+		{from: APPROVED, to: APPROVED, allowed: false},
+		{from: APPROVED, to: INVESTED, allowed: true},
+		{from: APPROVED, to: CANCELLED, allowed: true},
+		{from: APPROVED, to: EXPIRED, allowed: true},
+		{from: APPROVED, to: REJECTED, allowed: false},
+		{from: INVESTED, to: DISBURSED, allowed: true},
+		{from: INVESTED, to: CANCELLED, allowed: false},
+		{from: DISBURSED, to: REPAID, allowed: true},
+		{from: REPAID, to: DISBURSED, allowed: false},
+		{from: REJECTED, to: APPROVED, allowed: false},
+		{from: CANCELLED, to: APPROVED, allowed: false},
+		{from: EXPIRED, to: INVESTED, allowed: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.from.ToString()+" to "+tc.to.ToString(), func(t *testing.T) {
+			err := tc.from.TransitionTo(tc.to)
+			if tc.allowed {
+				require.NoError(t, err)
+				return
+			}
+
+			require.ErrorIs(t, err, ErrInvalidTransition)
+
+			var transitionErr *TransitionError
+			require.True(t, errors.As(err, &transitionErr))
+			require.Equal(t, tc.from, transitionErr.From)
+			require.Equal(t, tc.to, transitionErr.To)
+		})
+	}
+}
+
+func TestIsFinal(t *testing.T) {
+	for _, ls := range []LoanStatus{REPAID, REJECTED, CANCELLED, EXPIRED} {
+		require.True(t, ls.IsFinal(), ls.ToString())
+	}
+
+	for _, ls := range []LoanStatus{PROPOSED, APPROVED, INVESTED, DISBURSED} {
+		require.False(t, ls.IsFinal(), ls.ToString())
+	}
+}
@@ -1,24 +1,171 @@
 package agreementletter
 
 import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"embed"
+	"encoding/hex"
+	"fmt"
 	"simple-app/internal/model"
-
-	"log"
+	"simple-app/internal/pkg/amortization"
+	"simple-app/internal/pkg/blob"
+	"simple-app/internal/pkg/money"
+	"simple-app/internal/pkg/pdf"
+	"text/template"
+	"time"
 )
 
-type AgreementLetter struct{}
+// DefaultVersion is the template used when no version is configured
+const DefaultVersion = "v1"
+
+//go:embed templates/*.txt
+var templates embed.FS
+
+var funcs = template.FuncMap{
+	"percent": func(rate float64) string {
+		return fmt.Sprintf("%.2f%%", rate*100)
+	},
+}
+
+type AgreementLetter struct {
+	blob    blob.Store
+	version string
+	tmpl    *template.Template
+}
+
+type Param struct {
+	Store blob.Store
+	// Version is the name of the template under templates/, e.g. "v1"
+	Version string
+}
+
+func New(p Param) (*AgreementLetter, error) {
+	if p.Version == "" {
+		p.Version = DefaultVersion
+	}
+
+	tmpl, err := template.New(p.Version+".txt").Funcs(funcs).ParseFS(templates, "templates/"+p.Version+".txt")
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse agreement letter template %s: %w", p.Version, err)
+	}
+
+	return &AgreementLetter{
+		blob:    p.Store,
+		version: p.Version,
+		tmpl:    tmpl,
+	}, nil
+}
+
+type letterData struct {
+	Version       string
+	Date          time.Time
+	Loan          model.Loan
+	Borrower      model.Borrower
+	Installments  []model.Installment
+	TotalInterest money.Money
+	Share         *share
+}
+
+// share is the part of the loan that is funded by one investor, only the letter of investor has it
+type share struct {
+	InvestorID     int
+	Amount         money.Money
+	Ratio          float64
+	ExpectedReturn money.Money
+}
+
+// Generate renders the agreement letter of loan to PDF and stores it, the key of the document is its SHA-256
+// so the same letter is only stored once
+func (a *AgreementLetter) Generate(ctx context.Context, loan model.Loan, borrower model.Borrower) (letter model.AgreementLetter, err error) {
+	doc, err := a.Render(loan, borrower, time.Now())
+	if err != nil {
+		return letter, err
+	}
+
+	return a.store(ctx, doc)
+}
+
+// GenerateForInvestor renders the agreement letter of loan for one investor, showing their share of the loan, and stores it
+func (a *AgreementLetter) GenerateForInvestor(ctx context.Context, loan model.Loan, borrower model.Borrower, invest model.Invest) (letter model.AgreementLetter, err error) {
+	doc, err := a.RenderForInvestor(loan, borrower, invest, time.Now())
+	if err != nil {
+		return letter, err
+	}
+
+	return a.store(ctx, doc)
+}
+
+func (a *AgreementLetter) store(ctx context.Context, doc []byte) (letter model.AgreementLetter, err error) {
+
+	sum := sha256.Sum256(doc)
+	letter = model.AgreementLetter{
+		SHA256:  hex.EncodeToString(sum[:]),
+		Version: a.version,
+	}
 
-func New() *AgreementLetter {
-	return &AgreementLetter{}
+	key := fmt.Sprintf("agreement-letters/%s/%s.pdf", a.version, letter.SHA256)
+	err = a.blob.Put(ctx, key, doc)
+	if err != nil {
+		return letter, fmt.Errorf("failed to store agreement letter: %w", err)
+	}
+
+	letter.Key = key
+
+	return letter, nil
+}
+
+// Render renders the agreement letter of loan to PDF, the schedule is a preview starting from date
+func (a *AgreementLetter) Render(loan model.Loan, borrower model.Borrower, date time.Time) ([]byte, error) {
+	return a.render(loan, borrower, nil, date)
 }
 
-func (a *AgreementLetter) Generate(model.Loan) string {
-	/* THIS IS JUST PLACEHOLDER TO GENERATE URL OF AGREEMENT LETTER */
-	return "http://example-of-agreement-letter.com"
+// RenderForInvestor renders the agreement letter of loan to PDF with the share of investor in the loan
+func (a *AgreementLetter) RenderForInvestor(loan model.Loan, borrower model.Borrower, invest model.Invest, date time.Time) ([]byte, error) {
+	ratio := 0.0
+	if loan.PrincipalAmount.IsPositive() {
+		ratio = float64(invest.Amount.Minor()) / float64(loan.PrincipalAmount.Minor())
+	}
+
+	return a.render(loan, borrower, &share{
+		InvestorID:     invest.InvestorID,
+		Amount:         invest.Amount,
+		Ratio:          ratio,
+		ExpectedReturn: invest.Amount.MulRate(loan.Roi),
+	}, date)
 }
 
-func (a *AgreementLetter) Send(receiver int, agreementLetter string) error {
-	/* THIS IS JUST PLACEHOLDER TO SEND EMAIL OF AGREEMENT LETTER */
-	log.Printf("sending agreement letter to %v", receiver)
-	return nil
+func (a *AgreementLetter) render(loan model.Loan, borrower model.Borrower, share *share, date time.Time) ([]byte, error) {
+	installments, err := amortization.Generate(amortization.Param{
+		LoanID:    loan.ID,
+		Principal: loan.PrincipalAmount,
+		Rate:      loan.Rate,
+		Tenor:     loan.Tenor,
+		Method:    loan.RepaymentMethod,
+		StartDate: date,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	interest := money.FromMinor(0)
+	for _, installment := range installments {
+		interest = interest.Add(installment.InterestAmount)
+	}
+
+	var text bytes.Buffer
+	err = a.tmpl.Execute(&text, letterData{
+		Version:       a.version,
+		Date:          date,
+		Loan:          loan,
+		Borrower:      borrower,
+		Installments:  installments,
+		TotalInterest: interest,
+		Share:         share,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to render agreement letter: %w", err)
+	}
+
+	return pdf.FromText(text.String()), nil
 }
@@ -0,0 +1,143 @@
+package agreementletter
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"testing"
+	"time"
+
+	"simple-app/internal/model"
+	"simple-app/internal/pkg/blob"
+	"simple-app/internal/pkg/money"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerate(t *testing.T) {
+	ctx := context.Background()
+	store := blob.NewLocal(t.TempDir(), "http://localhost:4040/files")
+	letter, err := New(Param{Store: store})
+	require.NoError(t, err)
+
+	loan := model.Loan{
+		BorrowerID:      1,
+		PrincipalAmount: money.MustParse("1500"),
+		Rate:            0.2,
+		Roi:             0.1,
+		Tenor:           12,
+		RepaymentMethod: model.ANNUITY,
+	}
+	borrower := model.Borrower{ID: 1, Name: "Budi", Email: "budi@example.com"}
+
+	got, err := letter.Generate(ctx, loan, borrower)
+	require.NoError(t, err)
+	require.Equal(t, "v1", got.Version)
+	require.Equal(t, "agreement-letters/v1/"+got.SHA256+".pdf", got.Key)
+
+	doc, err := store.Get(ctx, "agreement-letters/v1/"+got.SHA256+".pdf")
+	require.NoError(t, err)
+
+	sum := sha256.Sum256(doc)
+	require.Equal(t, hex.EncodeToString(sum[:]), got.SHA256)
+	require.True(t, strings.HasPrefix(string(doc), "%PDF-"))
+}
+
+func TestRender(t *testing.T) {
+	letter, err := New(Param{})
+	require.NoError(t, err)
+
+	date := time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC)
+	doc, err := letter.Render(model.Loan{
+		PrincipalAmount: money.MustParse("1500"),
+		Rate:            0.2,
+		Roi:             0.1,
+		Tenor:           12,
+		RepaymentMethod: model.ANNUITY,
+	}, model.Borrower{ID: 7, Name: "Budi"}, date)
+	require.NoError(t, err)
+
+	for _, want := range []string{
+		"(Name         : Budi) Tj",
+		"(Principal amount : 1500.00 IDR) Tj",
+		"(Interest rate    : 20.00% per year) Tj",
+		"(Return to investors \\(ROI\\) : 10.00%) Tj",
+		"(Tenor            : 12 months) Tj",
+		"(1    2024-02-15   113.95",
+	} {
+		require.Contains(t, string(doc), want)
+	}
+}
+
+func TestRenderForInvestor(t *testing.T) {
+	letter, err := New(Param{})
+	require.NoError(t, err)
+
+	date := time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC)
+	loan := model.Loan{
+		PrincipalAmount: money.MustParse("1500"),
+		Rate:            0.2,
+		Roi:             0.1,
+		Tenor:           12,
+		RepaymentMethod: model.ANNUITY,
+	}
+	borrower := model.Borrower{ID: 7, Name: "Budi"}
+
+	doc, err := letter.RenderForInvestor(loan, borrower, model.Invest{InvestorID: 3, Amount: money.MustParse("500")}, date)
+	require.NoError(t, err)
+
+	for _, want := range []string{
+		"(YOUR INVESTMENT) Tj",
+		"(Investor ID      : 3) Tj",
+		"(Amount invested  : 500.00 IDR) Tj",
+		"(Share of loan    : 33.33%) Tj",
+		"(Expected return  : 50.00 IDR) Tj",
+	} {
+		require.Contains(t, string(doc), want)
+	}
+
+	// the letter of borrower has no share
+	doc, err = letter.Render(loan, borrower, date)
+	require.NoError(t, err)
+	require.NotContains(t, string(doc), "YOUR INVESTMENT")
+}
+
+func TestNewUnknownVersion(t *testing.T) {
+	_, err := New(Param{Version: "v0"})
+	require.Error(t, err)
+}
+
+func TestCertify(t *testing.T) {
+	ctx := context.Background()
+	store := blob.NewLocal(t.TempDir(), "http://localhost:4040/files")
+	letter, err := New(Param{Store: store})
+	require.NoError(t, err)
+
+	signedAt := time.Date(2024, 1, 15, 8, 30, 0, 0, time.UTC)
+	signature := model.Signature{
+		LoanID:                 3,
+		BorrowerID:             7,
+		AgreementLetterSHA256:  strings.Repeat("a", 64),
+		AgreementLetterVersion: "v1",
+		SignerName:             "Budi",
+		SignerIP:               "10.0.0.1",
+		SignedAt:               &signedAt,
+	}
+
+	got, err := letter.Certify(ctx, model.Loan{ID: 3}, signature)
+	require.NoError(t, err)
+	require.Equal(t, "agreement-signatures/"+got.SHA256+".pdf", got.Key)
+
+	doc, err := store.Get(ctx, "agreement-signatures/"+got.SHA256+".pdf")
+	require.NoError(t, err)
+	require.Contains(t, string(doc), "(Signer name              : Budi) Tj")
+	require.Contains(t, string(doc), "(Signed at                : 2024-01-15T08:30:00Z) Tj")
+	require.Contains(t, string(doc), "(SHA-256                  : "+strings.Repeat("a", 64)+") Tj")
+
+	// another signer is another document
+	signature.SignerName = "Andi"
+	other, err := letter.Certify(ctx, model.Loan{ID: 3}, signature)
+	require.NoError(t, err)
+	require.NotEqual(t, got.SHA256, other.SHA256)
+}
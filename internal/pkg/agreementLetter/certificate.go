@@ -0,0 +1,61 @@
+package agreementletter
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"simple-app/internal/model"
+	"simple-app/internal/pkg/pdf"
+	"strings"
+	"time"
+)
+
+// Certify renders the certificate of signature to PDF and stores it. The certificate names the agreement letter
+// by its SHA-256, so its own SHA-256 binds the signer to that exact letter
+func (a *AgreementLetter) Certify(ctx context.Context, loan model.Loan, signature model.Signature) (document model.AgreementLetter, err error) {
+	doc := RenderCertificate(loan, signature)
+
+	sum := sha256.Sum256(doc)
+	document = model.AgreementLetter{
+		SHA256:  hex.EncodeToString(sum[:]),
+		Version: signature.AgreementLetterVersion,
+	}
+
+	key := fmt.Sprintf("agreement-signatures/%s.pdf", document.SHA256)
+	err = a.blob.Put(ctx, key, doc)
+	if err != nil {
+		return document, fmt.Errorf("failed to store signature certificate: %w", err)
+	}
+
+	document.Key = key
+
+	return document, nil
+}
+
+// RenderCertificate renders the certificate of signature to PDF
+func RenderCertificate(loan model.Loan, signature model.Signature) []byte {
+	var signedAt string
+	if signature.SignedAt != nil {
+		signedAt = signature.SignedAt.UTC().Format(time.RFC3339)
+	}
+
+	lines := []string{
+		"SIGNATURE CERTIFICATE",
+		"",
+		"The borrower below has signed the agreement letter of the loan electronically.",
+		"",
+		fmt.Sprintf("Loan ID                  : %d", loan.ID),
+		fmt.Sprintf("Borrower ID              : %d", signature.BorrowerID),
+		fmt.Sprintf("Signer name              : %s", signature.SignerName),
+		fmt.Sprintf("Signer IP                : %s", signature.SignerIP),
+		fmt.Sprintf("Signed at                : %s", signedAt),
+		"",
+		"AGREEMENT LETTER",
+		fmt.Sprintf("Template version         : %s", signature.AgreementLetterVersion),
+		fmt.Sprintf("SHA-256                  : %s", signature.AgreementLetterSHA256),
+		fmt.Sprintf("Document                 : %s", loan.AgreementLetterKey),
+	}
+
+	return pdf.FromText(strings.Join(lines, "\n"))
+}
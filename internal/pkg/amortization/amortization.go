@@ -0,0 +1,147 @@
+package amortization
+
+import (
+	"errors"
+	"math"
+	"sort"
+	"time"
+
+	"simple-app/internal/model"
+	"simple-app/internal/pkg/money"
+)
+
+var (
+	ErrInvalidTenor  = errors.New("tenor must be greater than zero")
+	ErrInvalidMethod = errors.New("repayment method is invalid")
+)
+
+// Param is the input to build an installment schedule
+type Param struct {
+	LoanID    int
+	Principal money.Money
+	// Rate is the yearly interest rate charged to the borrower, e.g. 0.2 for 20%
+	Rate   float64
+	Tenor  int
+	Method model.RepaymentMethod
+	// StartDate is the disbursement date, first installment is due one month after it
+	StartDate time.Time
+}
+
+// Generate builds the monthly installment schedule of a loan.
+// Every amount is rounded to minor units and the rounding remainder is absorbed by the last installment,
+// so the principal of all installments always sums up to the loan principal.
+func Generate(p Param) ([]model.Installment, error) {
+	if p.Tenor <= 0 {
+		return nil, ErrInvalidTenor
+	}
+
+	monthlyRate := p.Rate / 12
+
+	var payment money.Money
+	switch p.Method {
+	case model.FLAT, model.BULLET:
+	case model.ANNUITY:
+		payment = annuityPayment(p.Principal, monthlyRate, p.Tenor)
+	default:
+		return nil, ErrInvalidMethod
+	}
+
+	installments := make([]model.Installment, 0, p.Tenor)
+	outstanding := p.Principal
+	for i := 1; i <= p.Tenor; i++ {
+		var principal, interest money.Money
+
+		switch p.Method {
+		case model.FLAT:
+			interest = p.Principal.MulRate(monthlyRate)
+			principal = p.Principal.DivRound(int64(p.Tenor))
+		case model.ANNUITY:
+			interest = outstanding.MulRate(monthlyRate)
+			principal = payment.Sub(interest)
+		case model.BULLET:
+			interest = p.Principal.MulRate(monthlyRate)
+			principal = money.FromMinor(0)
+		}
+
+		// last installment settles whatever is left
+		if i == p.Tenor || principal.Cmp(outstanding) > 0 {
+			principal = outstanding
+		}
+
+		outstanding = outstanding.Sub(principal)
+
+		installments = append(installments, model.Installment{
+			LoanID:             p.LoanID,
+			Number:             i,
+			DueDate:            p.StartDate.AddDate(0, i, 0),
+			PrincipalAmount:    principal,
+			InterestAmount:     interest,
+			TotalAmount:        principal.Add(interest),
+			OutstandingBalance: outstanding,
+		})
+	}
+
+	return installments, nil
+}
+
+// Outstanding returns the interest and principal of the schedule that are not yet covered by the repayments
+func Outstanding(installments []model.Installment, repayments []model.Repayment) (interest, principal money.Money) {
+	interest, principal = money.FromMinor(0), money.FromMinor(0)
+	for _, in := range installments {
+		interest = interest.Add(in.InterestAmount)
+		principal = principal.Add(in.PrincipalAmount)
+	}
+
+	for _, r := range repayments {
+		interest = interest.Sub(r.InterestAmount)
+		principal = principal.Sub(r.PrincipalAmount)
+	}
+
+	return interest, principal
+}
+
+// Allocate splits a payment over the installments in order, the interest of an installment is paid before
+// its principal and an installment is settled before the next one, so future interest is never paid ahead of principal.
+// What the earlier repayments paid is taken off the earliest installments first.
+// The amount must not exceed the sum of the outstanding interest and principal.
+func Allocate(amount money.Money, installments []model.Installment, repayments []model.Repayment) (interest, principal money.Money, err error) {
+	paidInterest, paidPrincipal := money.FromMinor(0), money.FromMinor(0)
+	for _, r := range repayments {
+		paidInterest = paidInterest.Add(r.InterestAmount)
+		paidPrincipal = paidPrincipal.Add(r.PrincipalAmount)
+	}
+
+	schedule := make([]model.Installment, len(installments))
+	copy(schedule, installments)
+	sort.Slice(schedule, func(i, j int) bool { return schedule[i].Number < schedule[j].Number })
+
+	interest, principal = money.FromMinor(0), money.FromMinor(0)
+	left := amount
+	for _, in := range schedule {
+		dueInterest, duePrincipal := in.InterestAmount, in.PrincipalAmount
+
+		covered := dueInterest.Min(paidInterest)
+		dueInterest, paidInterest = dueInterest.Sub(covered), paidInterest.Sub(covered)
+		covered = duePrincipal.Min(paidPrincipal)
+		duePrincipal, paidPrincipal = duePrincipal.Sub(covered), paidPrincipal.Sub(covered)
+
+		pay := left.Min(dueInterest)
+		interest, left = interest.Add(pay), left.Sub(pay)
+		pay = left.Min(duePrincipal)
+		principal, left = principal.Add(pay), left.Sub(pay)
+	}
+
+	if left.IsPositive() {
+		return money.FromMinor(0), money.FromMinor(0), model.ErrOverpayment
+	}
+
+	return interest, principal, nil
+}
+
+func annuityPayment(principal money.Money, monthlyRate float64, tenor int) money.Money {
+	if monthlyRate == 0 {
+		return principal.DivRound(int64(tenor))
+	}
+
+	return principal.MulRate(monthlyRate / (1 - math.Pow(1+monthlyRate, -float64(tenor))))
+}
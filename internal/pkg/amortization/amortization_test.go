@@ -0,0 +1,211 @@
+package amortization
+
+import (
+	"testing"
+	"time"
+
+	"simple-app/internal/model"
+	"simple-app/internal/pkg/money"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerate(t *testing.T) {
+	start := time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		name          string
+		param         Param
+		wantFirst     model.Installment
+		wantLastTotal money.Money
+	}{
+		{
+			name: "flat",
+			param: Param{
+				Principal: money.MustParse("1200"),
+				Rate:      0.12,
+				Tenor:     12,
+				Method:    model.FLAT,
+				StartDate: start,
+			},
+			wantFirst: model.Installment{
+				Number:             1,
+				PrincipalAmount:    money.MustParse("100"),
+				InterestAmount:     money.MustParse("12"),
+				TotalAmount:        money.MustParse("112"),
+				OutstandingBalance: money.MustParse("1100"),
+			},
+			wantLastTotal: money.MustParse("112"),
+		},
+		{
+			name: "annuity",
+			param: Param{
+				Principal: money.MustParse("1000"),
+				Rate:      0.12,
+				Tenor:     3,
+				Method:    model.ANNUITY,
+				StartDate: start,
+			},
+			wantFirst: model.Installment{
+				Number:             1,
+				PrincipalAmount:    money.MustParse("330.02"),
+				InterestAmount:     money.MustParse("10"),
+				TotalAmount:        money.MustParse("340.02"),
+				OutstandingBalance: money.MustParse("669.98"),
+			},
+			wantLastTotal: money.MustParse("340.03"),
+		},
+		{
+			name: "bullet",
+			param: Param{
+				Principal: money.MustParse("1500"),
+				Rate:      0.2,
+				Tenor:     6,
+				Method:    model.BULLET,
+				StartDate: start,
+			},
+			wantFirst: model.Installment{
+				Number:             1,
+				PrincipalAmount:    money.MustParse("0"),
+				InterestAmount:     money.MustParse("25"),
+				TotalAmount:        money.MustParse("25"),
+				OutstandingBalance: money.MustParse("1500"),
+			},
+			wantLastTotal: money.MustParse("1525"),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			installments, err := Generate(tc.param)
+			require.NoError(t, err)
+			require.Len(t, installments, tc.param.Tenor)
+
+			first := installments[0]
+			require.Equal(t, tc.wantFirst.Number, first.Number)
+			require.Equal(t, tc.wantFirst.PrincipalAmount, first.PrincipalAmount)
+			require.Equal(t, tc.wantFirst.InterestAmount, first.InterestAmount)
+			require.Equal(t, tc.wantFirst.TotalAmount, first.TotalAmount)
+			require.Equal(t, tc.wantFirst.OutstandingBalance, first.OutstandingBalance)
+			require.Equal(t, time.Date(2024, 2, 15, 0, 0, 0, 0, time.UTC), first.DueDate)
+
+			last := installments[len(installments)-1]
+			require.Equal(t, tc.wantLastTotal, last.TotalAmount)
+			require.True(t, last.OutstandingBalance.IsZero())
+
+			principal := money.FromMinor(0)
+			for _, in := range installments {
+				principal = principal.Add(in.PrincipalAmount)
+			}
+			require.Equal(t, tc.param.Principal, principal)
+		})
+	}
+}
+
+func TestGenerateInvalid(t *testing.T) {
+	_, err := Generate(Param{Principal: money.MustParse("1000"), Tenor: 0, Method: model.FLAT})
+	require.ErrorIs(t, err, ErrInvalidTenor)
+
+	_, err = Generate(Param{Principal: money.MustParse("1000"), Tenor: 12, Method: "weekly"})
+	require.ErrorIs(t, err, ErrInvalidMethod)
+}
+
+func TestAllocate(t *testing.T) {
+	// 12 installments of 12 interest and 100 principal
+	installments, err := Generate(Param{
+		Principal: money.MustParse("1200"),
+		Rate:      0.12,
+		Tenor:     12,
+		Method:    model.FLAT,
+	})
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name          string
+		amount        string
+		repayments    []model.Repayment
+		wantInterest  string
+		wantPrincipal string
+		wantErr       error
+	}{
+		{
+			name:          "interest of first installment",
+			amount:        "10",
+			wantInterest:  "10",
+			wantPrincipal: "0",
+		},
+		{
+			name:          "first installment",
+			amount:        "112",
+			wantInterest:  "12",
+			wantPrincipal: "100",
+		},
+		{
+			name:          "future interest is not paid before principal",
+			amount:        "224",
+			wantInterest:  "24",
+			wantPrincipal: "200",
+		},
+		{
+			name:          "after first installment",
+			amount:        "50",
+			repayments:    []model.Repayment{{InterestAmount: money.MustParse("12"), PrincipalAmount: money.MustParse("100")}},
+			wantInterest:  "12",
+			wantPrincipal: "38",
+		},
+		{
+			name:          "after partly paid interest",
+			amount:        "112",
+			repayments:    []model.Repayment{{InterestAmount: money.MustParse("5"), PrincipalAmount: money.MustParse("0")}},
+			wantInterest:  "12",
+			wantPrincipal: "100",
+		},
+		{
+			name:          "after all interest was paid ahead",
+			amount:        "112",
+			repayments:    []model.Repayment{{InterestAmount: money.MustParse("144"), PrincipalAmount: money.MustParse("0")}},
+			wantInterest:  "0",
+			wantPrincipal: "112",
+		},
+		{
+			name:          "settle",
+			amount:        "1344",
+			wantInterest:  "144",
+			wantPrincipal: "1200",
+		},
+		{
+			name:    "overpayment",
+			amount:  "1344.01",
+			wantErr: model.ErrOverpayment,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			interest, principal, err := Allocate(money.MustParse(tc.amount), installments, tc.repayments)
+			require.ErrorIs(t, err, tc.wantErr)
+			if tc.wantErr != nil {
+				return
+			}
+			require.Equal(t, money.MustParse(tc.wantInterest), interest)
+			require.Equal(t, money.MustParse(tc.wantPrincipal), principal)
+		})
+	}
+}
+
+func TestOutstanding(t *testing.T) {
+	installments, err := Generate(Param{
+		Principal: money.MustParse("1200"),
+		Rate:      0.12,
+		Tenor:     12,
+		Method:    model.FLAT,
+	})
+	require.NoError(t, err)
+
+	interest, principal := Outstanding(installments, []model.Repayment{
+		{InterestAmount: money.MustParse("144"), PrincipalAmount: money.MustParse("0")},
+		{InterestAmount: money.MustParse("0"), PrincipalAmount: money.MustParse("200")},
+	})
+	require.Equal(t, money.MustParse("0"), interest)
+	require.Equal(t, money.MustParse("1000"), principal)
+}
@@ -0,0 +1,98 @@
+package blob
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+var (
+	ErrNotFound   = errors.New("blob not found")
+	ErrInvalidKey = errors.New("blob key is invalid")
+)
+
+// Store keeps documents by key, e.g. "agreement-letters/v1/<sha256>.pdf"
+type Store interface {
+	Put(ctx context.Context, key string, data []byte) (err error)
+	Get(ctx context.Context, key string) (data []byte, err error)
+	URL(key string) string
+}
+
+// Local is a Store on the local filesystem, files are written under dir
+type Local struct {
+	dir     string
+	baseURL string
+}
+
+// NewLocal creates a Store that writes under dir, URL of a key is baseURL + "/" + key
+func NewLocal(dir, baseURL string) *Local {
+	return &Local{
+		dir:     dir,
+		baseURL: strings.TrimRight(baseURL, "/"),
+	}
+}
+
+// Put writes the data to a temporary file first and renames it, so readers never see a partial file
+func (l *Local) Put(ctx context.Context, key string, data []byte) (err error) {
+	name, err := l.path(key)
+	if err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(filepath.Dir(name), 0o755)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(name), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	_, err = tmp.Write(data)
+	if err != nil {
+		tmp.Close()
+		return err
+	}
+
+	err = tmp.Close()
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), name)
+}
+
+// Get reads the data of key
+func (l *Local) Get(ctx context.Context, key string) (data []byte, err error) {
+	name, err := l.path(key)
+	if err != nil {
+		return data, err
+	}
+
+	data, err = os.ReadFile(name)
+	if errors.Is(err, os.ErrNotExist) {
+		return data, ErrNotFound
+	}
+
+	return data, err
+}
+
+// URL returns where the document of key can be downloaded
+func (l *Local) URL(key string) string {
+	return l.baseURL + "/" + key
+}
+
+// path maps the key to a file under dir, keys can not leave dir
+func (l *Local) path(key string) (string, error) {
+	cleaned := path.Clean("/" + key)
+	if key == "" || cleaned == "/" || cleaned[1:] != key {
+		return "", ErrInvalidKey
+	}
+
+	return filepath.Join(l.dir, filepath.FromSlash(key)), nil
+}
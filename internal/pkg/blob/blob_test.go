@@ -0,0 +1,38 @@
+package blob
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLocal(t *testing.T) {
+	ctx := context.Background()
+	store := NewLocal(t.TempDir(), "http://localhost:4040/files/")
+
+	err := store.Put(ctx, "agreement-letters/v1/abc.pdf", []byte("%PDF"))
+	require.NoError(t, err)
+
+	data, err := store.Get(ctx, "agreement-letters/v1/abc.pdf")
+	require.NoError(t, err)
+	require.Equal(t, []byte("%PDF"), data)
+
+	require.Equal(t, "http://localhost:4040/files/agreement-letters/v1/abc.pdf", store.URL("agreement-letters/v1/abc.pdf"))
+
+	_, err = store.Get(ctx, "agreement-letters/v1/missing.pdf")
+	require.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestLocalInvalidKey(t *testing.T) {
+	ctx := context.Background()
+	store := NewLocal(t.TempDir(), "")
+
+	for _, key := range []string{"", "/", "../secret", "a/../../secret", "/etc/passwd", "a//b", "a/./b"} {
+		err := store.Put(ctx, key, []byte("x"))
+		require.ErrorIs(t, err, ErrInvalidKey, key)
+
+		_, err = store.Get(ctx, key)
+		require.ErrorIs(t, err, ErrInvalidKey, key)
+	}
+}
@@ -0,0 +1,30 @@
+// Package channel delivers outbox messages to their recipient, every channel is safe to retry
+// since the dispatcher may deliver the same message again after a failure
+package channel
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"simple-app/internal/model"
+)
+
+const (
+	Email   = "email"
+	Webhook = "webhook"
+	Log     = "log"
+)
+
+// ErrNoAddress is returned when the recipient of message has no address on the channel
+var ErrNoAddress = errors.New("recipient has no address")
+
+// decode read the content of outbox message
+func decode(message model.OutboxMessage) (content model.Message, err error) {
+	err = json.Unmarshal(message.Payload, &content)
+	if err != nil {
+		return content, fmt.Errorf("invalid payload of outbox message %d: %w", message.ID, err)
+	}
+
+	return content, nil
+}
@@ -0,0 +1,110 @@
+package channel
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/smtp"
+	"testing"
+	"time"
+
+	"simple-app/internal/model"
+
+	"github.com/stretchr/testify/require"
+)
+
+func message(t *testing.T) model.OutboxMessage {
+	payload, err := json.Marshal(model.Message{
+		Subject:       "Agreement letter of loan 3",
+		Body:          "Loan 3 is fully funded.",
+		LoanID:        3,
+		AttachmentURL: "http://localhost:4040/files/letter.pdf",
+	})
+	require.NoError(t, err)
+
+	return model.OutboxMessage{ID: 9, Topic: model.TopicAgreementLetter, RecipientID: 7, Payload: payload}
+}
+
+func TestSMTP(t *testing.T) {
+	testCases := []struct {
+		name    string
+		address string
+		sendErr error
+		wantErr bool
+	}{
+		{name: "sent", address: "investor@example.com"},
+		{name: "no address", wantErr: true},
+		{name: "server down", address: "investor@example.com", sendErr: errors.New("connection refused"), wantErr: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var (
+				gotTo  []string
+				gotMsg string
+			)
+			ch := NewSMTP("localhost:1025", "noreply@example.com", func(ctx context.Context, topic string, recipientID int) (string, error) {
+				require.Equal(t, 7, recipientID)
+				return tc.address, nil
+			})
+			ch.send = func(addr string, a smtp.Auth, from string, to []string, msg []byte) error {
+				gotTo, gotMsg = to, string(msg)
+				return tc.sendErr
+			}
+
+			err := ch.Deliver(context.Background(), message(t))
+			if tc.wantErr {
+				require.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, []string{tc.address}, gotTo)
+			require.Contains(t, gotMsg, "Subject: Agreement letter of loan 3\r\n")
+			require.Contains(t, gotMsg, "http://localhost:4040/files/letter.pdf")
+		})
+	}
+}
+
+func TestWebhook(t *testing.T) {
+	testCases := []struct {
+		name    string
+		status  int
+		wantErr bool
+	}{
+		{name: "accepted", status: http.StatusAccepted},
+		{name: "server error", status: http.StatusInternalServerError, wantErr: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var body webhookBody
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				require.Equal(t, "outbox-9", r.Header.Get("Idempotency-Key"))
+				b, _ := io.ReadAll(r.Body)
+				require.NoError(t, json.Unmarshal(b, &body))
+				w.WriteHeader(tc.status)
+			}))
+			defer srv.Close()
+
+			err := NewWebhook(srv.URL, time.Second).Deliver(context.Background(), message(t))
+			if tc.wantErr {
+				require.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, 9, body.ID)
+			require.Equal(t, 7, body.RecipientID)
+			require.Equal(t, 3, body.Message.LoanID)
+		})
+	}
+}
+
+func TestInvalidPayload(t *testing.T) {
+	err := NewLog().Deliver(context.Background(), model.OutboxMessage{ID: 1, Payload: []byte("{")})
+	require.Error(t, err)
+}
@@ -0,0 +1,25 @@
+package channel
+
+import (
+	"context"
+	"log"
+
+	"simple-app/internal/model"
+)
+
+// LogChannel only writes the message to the log, used in development
+type LogChannel struct{}
+
+func NewLog() *LogChannel {
+	return &LogChannel{}
+}
+
+func (l *LogChannel) Deliver(ctx context.Context, message model.OutboxMessage) error {
+	content, err := decode(message)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("sending %s to %v: %s", message.Topic, message.RecipientID, content.Subject)
+	return nil
+}
@@ -0,0 +1,74 @@
+package channel
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/smtp"
+	"strings"
+
+	"simple-app/internal/model"
+)
+
+// AddressLookup get the address of recipient, e.g. the email of investor. The topic tells
+// who the recipient is, e.g. the borrower for model.TopicSignatureRequest
+type AddressLookup func(ctx context.Context, topic string, recipientID int) (address string, err error)
+
+// SMTPChannel sends the message as plain text email, without authentication
+// since it is meant for a local relay like mailhog that forwards the mail
+type SMTPChannel struct {
+	addr   string
+	from   string
+	lookup AddressLookup
+	send   func(addr string, a smtp.Auth, from string, to []string, msg []byte) error
+}
+
+// NewSMTP will instantiate email channel that sends through the SMTP server at addr, e.g. "localhost:1025"
+func NewSMTP(addr, from string, lookup AddressLookup) *SMTPChannel {
+	return &SMTPChannel{
+		addr:   addr,
+		from:   from,
+		lookup: lookup,
+		send:   smtp.SendMail,
+	}
+}
+
+func (s *SMTPChannel) Deliver(ctx context.Context, message model.OutboxMessage) error {
+	content, err := decode(message)
+	if err != nil {
+		return err
+	}
+
+	to, err := s.lookup(ctx, message.Topic, message.RecipientID)
+	if err != nil {
+		return err
+	}
+	if to == "" {
+		return ErrNoAddress
+	}
+
+	err = s.send(s.addr, nil, s.from, []string{to}, mail(s.from, to, content))
+	if err != nil {
+		return fmt.Errorf("failed to send email: %w", err)
+	}
+
+	return nil
+}
+
+// mail build the RFC 5322 message
+func mail(from, to string, content model.Message) []byte {
+	var b bytes.Buffer
+
+	fmt.Fprintf(&b, "From: %s\r\n", from)
+	fmt.Fprintf(&b, "To: %s\r\n", to)
+	fmt.Fprintf(&b, "Subject: %s\r\n", strings.ReplaceAll(content.Subject, "\n", " "))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(strings.ReplaceAll(content.Body, "\n", "\r\n"))
+	if content.AttachmentURL != "" {
+		fmt.Fprintf(&b, "\r\n\r\n%s\r\n", content.AttachmentURL)
+	}
+
+	return b.Bytes()
+}
@@ -0,0 +1,71 @@
+package channel
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"simple-app/internal/model"
+)
+
+// WebhookChannel posts the message as JSON to an URL,
+// the receiver should use the Idempotency-Key header to drop duplicates
+type WebhookChannel struct {
+	url    string
+	client *http.Client
+}
+
+// NewWebhook will instantiate webhook channel, requests that take longer than timeout fail
+func NewWebhook(url string, timeout time.Duration) *WebhookChannel {
+	return &WebhookChannel{
+		url:    url,
+		client: &http.Client{Timeout: timeout},
+	}
+}
+
+type webhookBody struct {
+	ID          int           `json:"id"`
+	Topic       string        `json:"topic"`
+	RecipientID int           `json:"recipient_id"`
+	Message     model.Message `json:"message"`
+}
+
+func (w *WebhookChannel) Deliver(ctx context.Context, message model.OutboxMessage) error {
+	content, err := decode(message)
+	if err != nil {
+		return err
+	}
+
+	body, err := json.Marshal(webhookBody{
+		ID:          message.ID,
+		Topic:       message.Topic,
+		RecipientID: message.RecipientID,
+		Message:     content,
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Idempotency-Key", "outbox-"+strconv.Itoa(message.ID))
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to call webhook: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook responded with status %d", resp.StatusCode)
+	}
+
+	return nil
+}
@@ -0,0 +1,261 @@
+package money
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Currency is the currency of every amount
+const Currency = "IDR"
+
+// scale is the number of minor units in one major unit, every amount is kept with 2 decimals
+const scale = 100
+
+var (
+	ErrInvalidAmount   = errors.New("amount is invalid")
+	ErrTooManyDecimals = errors.New("amount has more than 2 decimal places")
+)
+
+// Money is an exact amount of money kept as minor units (e.g. cents). Every amount is in Currency, the only
+// currency of the service, so the currency is neither kept nor stored.
+// It is stored as NUMERIC in the database and serialized as a decimal string in JSON
+type Money struct {
+	minor int64
+}
+
+// FromMinor creates Money from minor units
+func FromMinor(minor int64) Money {
+	return Money{minor: minor}
+}
+
+// FromFloat creates Money from a float, rounded to 2 decimals.
+// Only use it on values that are not money yet, like the result of multiplying by a rate
+func FromFloat(f float64) Money {
+	return FromMinor(int64(math.Round(f * scale)))
+}
+
+// Parse creates Money from a decimal string like "1500" or "1500.25"
+func Parse(s string) (Money, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return Money{}, ErrInvalidAmount
+	}
+
+	negative := false
+	switch s[0] {
+	case '-':
+		negative = true
+		s = s[1:]
+	case '+':
+		s = s[1:]
+	}
+
+	whole, frac, _ := strings.Cut(s, ".")
+	if (whole == "" && frac == "") || !isDigits(whole) || !isDigits(frac) {
+		return Money{}, ErrInvalidAmount
+	}
+
+	// NUMERIC columns may come with trailing zeros beyond the scale
+	frac = strings.TrimRight(frac, "0")
+	if len(frac) > 2 {
+		return Money{}, ErrTooManyDecimals
+	}
+	frac += strings.Repeat("0", 2-len(frac))
+
+	if whole == "" {
+		whole = "0"
+	}
+
+	w, err := strconv.ParseUint(whole, 10, 63)
+	if err != nil {
+		return Money{}, ErrInvalidAmount
+	}
+
+	f, err := strconv.ParseUint(frac, 10, 63)
+	if err != nil {
+		return Money{}, ErrInvalidAmount
+	}
+
+	if w > (math.MaxInt64-f)/scale {
+		return Money{}, ErrInvalidAmount
+	}
+
+	minor := int64(w*scale + f)
+	if negative {
+		minor = -minor
+	}
+
+	return FromMinor(minor), nil
+}
+
+func isDigits(s string) bool {
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// MustParse is like Parse but panics on error, for constants and tests
+func MustParse(s string) Money {
+	m, err := Parse(s)
+	if err != nil {
+		panic(err)
+	}
+	return m
+}
+
+// Minor returns the amount in minor units
+func (m Money) Minor() int64 {
+	return m.minor
+}
+
+// Currency returns the currency of the amount, it is always Currency
+func (m Money) Currency() string {
+	return Currency
+}
+
+// Float returns the amount as float, only for display and ratios
+func (m Money) Float() float64 {
+	return float64(m.minor) / scale
+}
+
+// String returns the amount as decimal string with 2 decimals, e.g. "1500.00"
+func (m Money) String() string {
+	minor := m.minor
+	sign := ""
+	if minor < 0 {
+		sign = "-"
+		minor = -minor
+	}
+
+	return fmt.Sprintf("%s%d.%02d", sign, minor/scale, minor%scale)
+}
+
+// IsZero reports whether the amount is zero
+func (m Money) IsZero() bool {
+	return m.minor == 0
+}
+
+// IsPositive reports whether the amount is greater than zero
+func (m Money) IsPositive() bool {
+	return m.minor > 0
+}
+
+// IsNegative reports whether the amount is less than zero
+func (m Money) IsNegative() bool {
+	return m.minor < 0
+}
+
+// Add returns m + o
+func (m Money) Add(o Money) Money {
+	return FromMinor(m.minor + o.minor)
+}
+
+// Sub returns m - o
+func (m Money) Sub(o Money) Money {
+	return FromMinor(m.minor - o.minor)
+}
+
+// Cmp compares m and o and returns -1, 0 or +1
+func (m Money) Cmp(o Money) int {
+	switch {
+	case m.minor < o.minor:
+		return -1
+	case m.minor > o.minor:
+		return 1
+	}
+	return 0
+}
+
+// Min returns the smaller of m and o
+func (m Money) Min(o Money) Money {
+	if m.Cmp(o) <= 0 {
+		return m
+	}
+	return o
+}
+
+// MulRate returns m multiplied by rate, rounded half away from zero to minor units
+func (m Money) MulRate(rate float64) Money {
+	return FromMinor(int64(math.Round(float64(m.minor) * rate)))
+}
+
+// DivRound returns m divided by n, rounded half away from zero to minor units
+func (m Money) DivRound(n int64) Money {
+	return FromMinor(int64(math.Round(float64(m.minor) / float64(n))))
+}
+
+// MarshalJSON implements json.Marshaler, the amount is written as string to keep it exact
+func (m Money) MarshalJSON() ([]byte, error) {
+	return []byte(`"` + m.String() + `"`), nil
+}
+
+// UnmarshalJSON implements json.Unmarshaler, accepts both string and number
+func (m *Money) UnmarshalJSON(b []byte) error {
+	s := string(b)
+	if s == "null" {
+		return nil
+	}
+
+	s = strings.Trim(s, `"`)
+	parsed, err := Parse(s)
+	if err != nil {
+		return err
+	}
+
+	*m = parsed
+	return nil
+}
+
+// Value implements driver.Valuer
+func (m Money) Value() (driver.Value, error) {
+	return m.String(), nil
+}
+
+// Scan implements sql.Scanner
+func (m *Money) Scan(src any) error {
+	switch v := src.(type) {
+	case nil:
+		*m = Money{}
+		return nil
+	case []byte:
+		parsed, err := Parse(string(v))
+		if err != nil {
+			return err
+		}
+		*m = parsed
+	case string:
+		parsed, err := Parse(v)
+		if err != nil {
+			return err
+		}
+		*m = parsed
+	case int64:
+		*m = FromMinor(v * scale)
+	case float64:
+		*m = FromFloat(v)
+	default:
+		return fmt.Errorf("cannot scan %T into money", src)
+	}
+
+	return nil
+}
+
+// Sum returns the total of amounts, zero when there is none
+func Sum(amounts ...Money) Money {
+	if len(amounts) == 0 {
+		return FromMinor(0)
+	}
+
+	total := amounts[0]
+	for _, a := range amounts[1:] {
+		total = total.Add(a)
+	}
+	return total
+}
@@ -0,0 +1,104 @@
+package money
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	testCases := []struct {
+		input   string
+		want    int64
+		wantErr error
+	}{
+		{input: "1500", want: 150000},
+		{input: "1500.5", want: 150050},
+		{input: "1500.25", want: 150025},
+		{input: "1500.250000", want: 150025},
+		{input: "0.01", want: 1},
+		{input: ".5", want: 50},
+		{input: "-12.30", want: -1230},
+		{input: "1500.255", wantErr: ErrTooManyDecimals},
+		{input: "", wantErr: ErrInvalidAmount},
+		{input: "abc", wantErr: ErrInvalidAmount},
+		{input: "1.2.3", wantErr: ErrInvalidAmount},
+		{input: "99999999999999999999", wantErr: ErrInvalidAmount},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.input, func(t *testing.T) {
+			got, err := Parse(tc.input)
+			require.ErrorIs(t, err, tc.wantErr)
+			require.Equal(t, tc.want, got.Minor())
+		})
+	}
+}
+
+func TestString(t *testing.T) {
+	require.Equal(t, "1500.00", FromMinor(150000).String())
+	require.Equal(t, "0.05", FromMinor(5).String())
+	require.Equal(t, "-12.30", FromMinor(-1230).String())
+}
+
+func TestArithmetic(t *testing.T) {
+	a := MustParse("0.10")
+	b := MustParse("0.20")
+
+	require.Equal(t, MustParse("0.30"), a.Add(b))
+	require.Equal(t, MustParse("-0.10"), a.Sub(b))
+	require.Equal(t, -1, a.Cmp(b))
+	require.Equal(t, a, a.Min(b))
+	require.Equal(t, MustParse("0.60"), Sum(a, b, a, b))
+	require.Equal(t, MustParse("25.00"), MustParse("1500").MulRate(0.2/12))
+	require.Equal(t, MustParse("333.33"), MustParse("1000").DivRound(3))
+}
+
+func TestJSON(t *testing.T) {
+	var data struct {
+		Amount Money `json:"amount"`
+	}
+
+	err := json.Unmarshal([]byte(`{"amount": 1500.5}`), &data)
+	require.NoError(t, err)
+	require.Equal(t, int64(150050), data.Amount.Minor())
+
+	err = json.Unmarshal([]byte(`{"amount": "1500.25"}`), &data)
+	require.NoError(t, err)
+	require.Equal(t, int64(150025), data.Amount.Minor())
+
+	err = json.Unmarshal([]byte(`{"amount": "1500.255"}`), &data)
+	require.Error(t, err)
+
+	b, err := json.Marshal(data)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"amount": "1500.25"}`, string(b))
+}
+
+func TestScan(t *testing.T) {
+	testCases := []struct {
+		name string
+		src  any
+		want int64
+	}{
+		{name: "numeric", src: []byte("1500.25"), want: 150025},
+		{name: "string", src: "10", want: 1000},
+		{name: "int", src: int64(7), want: 700},
+		{name: "float", src: float64(0.1 + 0.2), want: 30},
+		{name: "null", src: nil, want: 0},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var m Money
+			err := m.Scan(tc.src)
+			require.NoError(t, err)
+			require.Equal(t, tc.want, m.Minor())
+		})
+	}
+
+	v, err := MustParse("1500.25").Value()
+	require.NoError(t, err)
+	require.Equal(t, "1500.25", v)
+}
@@ -0,0 +1,78 @@
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFromText(t *testing.T) {
+	testCases := []struct {
+		name      string
+		text      string
+		wantPages int
+		contains  []string
+	}{
+		{
+			name:      "empty",
+			text:      "",
+			wantPages: 1,
+		},
+		{
+			name:      "escaped",
+			text:      "Loan (ID 1) \\ Rp 1.500,00",
+			wantPages: 1,
+			contains:  []string{`(Loan \(ID 1\) \\ Rp 1.500,00) Tj`},
+		},
+		{
+			name:      "wrapped",
+			text:      strings.Repeat("word ", 30),
+			wantPages: 1,
+			contains:  []string{"(" + strings.TrimSpace(strings.Repeat("word ", 19)) + ") Tj", "(" + strings.TrimSpace(strings.Repeat("word ", 11)) + ") Tj"},
+		},
+		{
+			name:      "many pages",
+			text:      strings.Repeat("line\n", linesPerPage*2+1),
+			wantPages: 3,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			doc := FromText(tc.text)
+
+			require.True(t, bytes.HasPrefix(doc, []byte("%PDF-1.4\n")))
+			require.True(t, bytes.HasSuffix(doc, []byte("%%EOF\n")))
+			require.Contains(t, string(doc), fmt.Sprintf("/Count %d", tc.wantPages))
+			for _, c := range tc.contains {
+				require.Contains(t, string(doc), c)
+			}
+
+			requireValidXref(t, doc)
+			require.Equal(t, doc, FromText(tc.text))
+		})
+	}
+}
+
+// requireValidXref checks that every entry of the cross reference table points to its object
+func requireValidXref(t *testing.T, doc []byte) {
+	m := regexp.MustCompile(`startxref\n(\d+)\n`).FindSubmatch(doc)
+	require.NotNil(t, m)
+
+	start, err := strconv.Atoi(string(m[1]))
+	require.NoError(t, err)
+	require.True(t, bytes.HasPrefix(doc[start:], []byte("xref\n")))
+
+	entries := regexp.MustCompile(`(\d{10}) 00000 n `).FindAllSubmatch(doc[start:], -1)
+	require.NotEmpty(t, entries)
+	for i, entry := range entries {
+		offset, err := strconv.Atoi(string(entry[1]))
+		require.NoError(t, err)
+		require.True(t, bytes.HasPrefix(doc[offset:], []byte(fmt.Sprintf("%d 0 obj\n", i+1))), "object %d", i+1)
+	}
+}
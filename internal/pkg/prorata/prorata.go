@@ -0,0 +1,68 @@
+package prorata
+
+import (
+	"math/big"
+	"sort"
+
+	"simple-app/internal/pkg/money"
+)
+
+// Split divides amount into shares proportional to weights, in minor units.
+// Minor units left over by rounding are given one by one to the shares with the largest
+// fractional remainder, ties go to the lower index, so the shares always sum up to amount
+// and the same input always gives the same output.
+func Split(amount money.Money, weights []money.Money) []money.Money {
+	shares := make([]money.Money, len(weights))
+	for i := range shares {
+		shares[i] = money.FromMinor(0)
+	}
+
+	var totalWeight int64
+	for _, w := range weights {
+		totalWeight += w.Minor()
+	}
+
+	if totalWeight <= 0 {
+		return shares
+	}
+
+	total := amount.Minor()
+
+	minors := make([]int64, len(weights))
+	remainders := make([]int64, len(weights))
+	var allocated int64
+	for i, w := range weights {
+		// integer division keeps it exact, the remainder decides who gets the leftover
+		minors[i], remainders[i] = mulDiv(total, w.Minor(), totalWeight)
+		allocated += minors[i]
+	}
+
+	order := make([]int, len(weights))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return remainders[order[a]] > remainders[order[b]]
+	})
+
+	for i := 0; allocated < total; i++ {
+		minors[order[i%len(order)]]++
+		allocated++
+	}
+
+	for i := range minors {
+		shares[i] = money.FromMinor(minors[i])
+	}
+
+	return shares
+}
+
+// mulDiv returns a*b/c and its remainder without overflowing on large amounts
+func mulDiv(a, b, c int64) (quo, rem int64) {
+	q, r := new(big.Int).QuoRem(
+		new(big.Int).Mul(big.NewInt(a), big.NewInt(b)),
+		big.NewInt(c),
+		new(big.Int),
+	)
+	return q.Int64(), r.Int64()
+}
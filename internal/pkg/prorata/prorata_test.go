@@ -0,0 +1,72 @@
+package prorata
+
+import (
+	"testing"
+
+	"simple-app/internal/pkg/money"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplit(t *testing.T) {
+	testCases := []struct {
+		name    string
+		amount  string
+		weights []string
+		want    []string
+	}{
+		{
+			name:    "even",
+			amount:  "100",
+			weights: []string{"500", "500"},
+			want:    []string{"50", "50"},
+		},
+		{
+			name:    "remainder goes to largest fraction",
+			amount:  "100",
+			weights: []string{"1", "1", "1"},
+			want:    []string{"33.34", "33.33", "33.33"},
+		},
+		{
+			name:    "proportional",
+			amount:  "113.95",
+			weights: []string{"1000", "500"},
+			want:    []string{"75.97", "37.98"},
+		},
+		{
+			name:    "large amount",
+			amount:  "90000000000000",
+			weights: []string{"60000000000000", "30000000000000"},
+			want:    []string{"60000000000000", "30000000000000"},
+		},
+		{
+			name:    "no weight",
+			amount:  "100",
+			weights: []string{"0", "0"},
+			want:    []string{"0", "0"},
+		},
+		{
+			name:    "empty",
+			amount:  "100",
+			weights: nil,
+			want:    []string{},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			weights := make([]money.Money, len(tc.weights))
+			for i, w := range tc.weights {
+				weights[i] = money.MustParse(w)
+			}
+
+			want := make([]money.Money, len(tc.want))
+			for i, w := range tc.want {
+				want[i] = money.MustParse(w)
+			}
+
+			got := Split(money.MustParse(tc.amount), weights)
+			require.Equal(t, want, got)
+		})
+	}
+}
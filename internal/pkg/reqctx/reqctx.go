@@ -0,0 +1,32 @@
+package reqctx
+
+import (
+	"context"
+	"fmt"
+)
+
+type requestIDKey struct{}
+
+type actorKey struct{}
+
+// WithRequestID returns a copy of ctx carrying the request ID
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, requestID)
+}
+
+// RequestID returns the request ID carried by ctx, empty if none
+func RequestID(ctx context.Context) string {
+	v, _ := ctx.Value(requestIDKey{}).(string)
+	return v
+}
+
+// WithActor returns a copy of ctx carrying who is doing the request, written as "role:id" e.g. "investor:3"
+func WithActor(ctx context.Context, role string, id int) context.Context {
+	return context.WithValue(ctx, actorKey{}, fmt.Sprintf("%s:%d", role, id))
+}
+
+// Actor returns the actor carried by ctx, empty if none
+func Actor(ctx context.Context) string {
+	v, _ := ctx.Value(actorKey{}).(string)
+	return v
+}
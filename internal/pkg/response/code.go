@@ -11,9 +11,12 @@ var (
 	paymentRequiredMsg     = "You need to upgrade your plan to access this feature."
 	forbiddenMsg           = "You do not have the necessary permissions to view this item."
 	notFoundMsg            = "Oops! We couldn't find what you were looking for. Please send a report to support@your-app.com if you believe this was an error."
+	conflictMsg            = "The data has been changed by another request. Please refresh and try again."
+	stateConflictMsg       = "This action is not allowed in the current state of the data. Please refresh to see its latest state."
 	unprocessableEntityMsg = "The request could not be processed correctly due to a mistake in the information provided. Please review and try again."
 	tooManyRequestMsg      = "You've made too many requests. Please take a break and try again later."
 	unauthorizedMsg        = "Sorry, you need to be logged in to access this page. Please log in and try again."
+	preconditionMsg        = "Please refresh the data and try again with its latest version."
 )
 
 // Code type
@@ -80,8 +83,14 @@ var (
 	ForbiddenAccessCode Code
 	// NotFoundCode data not found
 	NotFoundCode Code
+	// ConflictCode the data has been changed by another request since the client read it
+	ConflictCode Code
+	// StateConflictCode the current state of the data does not allow the request, e.g. a status it can not move from
+	StateConflictCode Code
 	// UnprocessableCode unprocessable entity
 	UnprocessableCode Code
+	// PreconditionRequiredCode the request must be conditional, e.g. with If-Match header
+	PreconditionRequiredCode Code
 	// TooManyRequestCode rate limit request
 	TooManyRequestCode Code
 	// InternalErrCode success code
@@ -133,6 +142,20 @@ func setDefaults() {
 		devMsg:   "Not Found",
 		userMsg:  notFoundMsg,
 	}
+	// ConflictCode the data has been changed by another request since the client read it
+	ConflictCode = Code{
+		code:     "CONFLICT",
+		httpCode: http.StatusConflict,
+		devMsg:   "Conflict",
+		userMsg:  conflictMsg,
+	}
+	// StateConflictCode the current state of the data does not allow the request, e.g. a status it can not move from
+	StateConflictCode = Code{
+		code:     "STATE_CONFLICT",
+		httpCode: http.StatusConflict,
+		devMsg:   "State Conflict",
+		userMsg:  stateConflictMsg,
+	}
 	// UnprocessableCode unprocessable entity
 	UnprocessableCode = Code{
 		code:     "UNPROCESSABLE",
@@ -140,6 +163,13 @@ func setDefaults() {
 		devMsg:   "Unprocessable Entity",
 		userMsg:  unprocessableEntityMsg,
 	}
+	// PreconditionRequiredCode the request must be conditional, e.g. with If-Match header
+	PreconditionRequiredCode = Code{
+		code:     "PRECONDITION_REQUIRED",
+		httpCode: http.StatusPreconditionRequired,
+		devMsg:   "Precondition Required",
+		userMsg:  preconditionMsg,
+	}
 	// TooManyRequestCode rate limit request
 	TooManyRequestCode = Code{
 		code:     "TOO_MANY_REQUESTS",
@@ -1 +1,39 @@
 package response
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConflictCodes(t *testing.T) {
+	tests := []struct {
+		name        string
+		code        Code
+		wantCode    string
+		wantUserMsg string
+	}{
+		{
+			name:        "version conflict",
+			code:        ConflictCode,
+			wantCode:    "CONFLICT",
+			wantUserMsg: conflictMsg,
+		}, {
+			name:        "state conflict",
+			code:        StateConflictCode,
+			wantCode:    "STATE_CONFLICT",
+			wantUserMsg: stateConflictMsg,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.wantCode, tt.code.Code())
+			assert.Equal(t, http.StatusConflict, tt.code.HTTPCode())
+			assert.Equal(t, tt.wantUserMsg, tt.code.UserMsg())
+		})
+	}
+
+	// a client tells them apart by code, they must not share the message either
+	assert.NotEqual(t, ConflictCode.UserMsg(), StateConflictCode.UserMsg())
+}
@@ -3,6 +3,8 @@ package response
 import (
 	"time"
 
+	"simple-app/internal/pkg/reqctx"
+
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 )
@@ -14,10 +16,14 @@ const (
 	RequestIDKey = "trace-request_id"
 )
 
-// Middleware start processing time and request id inside gin.Context
+// Middleware start processing time and request id inside gin.Context,
+// the request id is also put into the request context so lower layers can record it
 func Middleware(c *gin.Context) {
+	requestID := uuid.New().String()
+
 	c.Set(ProcessingTimeKey, time.Now())
-	c.Set(RequestIDKey, uuid.New().String())
+	c.Set(RequestIDKey, requestID)
+	c.Request = c.Request.WithContext(reqctx.WithRequestID(c.Request.Context(), requestID))
 
 	c.Next()
 }
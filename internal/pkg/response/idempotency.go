@@ -0,0 +1,170 @@
+package response
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"time"
+
+	"simple-app/internal/model"
+	"simple-app/internal/pkg/log"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// IdempotencyKeyHeader header the client sends to make a request safe to retry
+	IdempotencyKeyHeader = "Idempotency-Key"
+	// IdempotentReplayedHeader is set when the response is replayed from an earlier request
+	IdempotentReplayedHeader = "Idempotent-Replayed"
+	// DefaultIdempotencyTTL how long a key is remembered
+	DefaultIdempotencyTTL = 24 * time.Hour
+	// DefaultIdempotencyLease how long a request in progress holds its key, it must be longer than any request
+	DefaultIdempotencyLease = time.Minute
+
+	maxIdempotencyKeyLength = 255
+)
+
+// IdempotencyStore persist the idempotency keys and their response
+type IdempotencyStore interface {
+	Start(ctx context.Context, key, requestHash string, ttl, lease time.Duration) (record model.IdempotencyKey, started bool, err error)
+	Complete(ctx context.Context, record model.IdempotencyKey) error
+	Delete(ctx context.Context, key string) error
+}
+
+// bodyWriter keep a copy of the response body so it can be stored
+type bodyWriter struct {
+	gin.ResponseWriter
+	body *bytes.Buffer
+}
+
+func (w bodyWriter) Write(b []byte) (int, error) {
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+func (w bodyWriter) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
+
+// Idempotency make a request with Idempotency-Key header run only once. A key belongs to the caller, method and route
+// it is sent to, so clients and endpoints can not see each other's responses. A repeat with the same key and request
+// get the stored response back, a repeat with the same key but different request is rejected with 422,
+// and a repeat while the first one is still running is rejected with 409. A request holds its key for lease,
+// so a repeat takes over the key of a request whose process is gone before it is done.
+// Server errors and panics are not stored, so the client can retry them with the same key
+func Idempotency(store IdempotencyStore, ttl, lease time.Duration) gin.HandlerFunc {
+	if ttl <= 0 {
+		ttl = DefaultIdempotencyTTL
+	}
+	if lease <= 0 {
+		lease = DefaultIdempotencyLease
+	}
+
+	return func(c *gin.Context) {
+		key := c.GetHeader(IdempotencyKeyHeader)
+		if key == "" {
+			c.Next()
+			return
+		}
+		if len(key) > maxIdempotencyKeyLength {
+			ErrCode(c, BadRequestErrCode, "Idempotency-Key is too long")
+			return
+		}
+
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			ErrCode(c, BadRequestErrCode)
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		ctx := c.Request.Context()
+		key = scopedKey(c, key)
+		hash := requestHash(c.Request.Method, c.Request.URL.Path, c.GetHeader("If-Match"), body)
+
+		record, started, err := store.Start(ctx, key, hash, ttl, lease)
+		if err != nil {
+			Err(c, WrapErrCode(err, InternalErrCode), err.Error())
+			return
+		}
+
+		if !started {
+			switch {
+			case record.RequestHash != hash:
+				ErrCode(c, UnprocessableCode, "Idempotency-Key is already used for a different request")
+			case record.Status != model.IdempotencyCompleted:
+				ErrCode(c, StateConflictCode, "request with the same Idempotency-Key is still in progress")
+			default:
+				c.Header(IdempotentReplayedHeader, "true")
+				c.Data(record.ResponseCode, record.ContentType, record.ResponseBody)
+				c.Abort()
+			}
+			return
+		}
+
+		// the outcome is kept even when the client goes away before the request is done
+		done := context.WithoutCancel(ctx)
+		release := func() {
+			if err := store.Delete(done, key); err != nil {
+				log.Errorf("failed to release idempotency key %s: %v", key, err)
+			}
+		}
+
+		// a panic is recovered outside of this middleware, the key is released before so it is not stuck in progress
+		defer func() {
+			if p := recover(); p != nil {
+				release()
+				panic(p)
+			}
+		}()
+
+		w := bodyWriter{ResponseWriter: c.Writer, body: &bytes.Buffer{}}
+		c.Writer = w
+
+		c.Next()
+
+		if w.Status() >= http.StatusInternalServerError {
+			release()
+			return
+		}
+
+		record.ResponseCode = w.Status()
+		record.ResponseBody = w.body.Bytes()
+		record.ContentType = w.Header().Get("Content-Type")
+		if err := store.Complete(done, record); err != nil {
+			log.Errorf("failed to store idempotency key %s: %v", key, err)
+		}
+	}
+}
+
+// scopedKey is the stored key of the Idempotency-Key of caller on the route of the request.
+// There is no login, the caller is the Authorization header when it is sent and the client IP otherwise
+func scopedKey(c *gin.Context, key string) string {
+	caller := c.GetHeader("Authorization")
+	if caller == "" {
+		caller = c.ClientIP()
+	}
+
+	return hashOf(caller, c.Request.Method, c.FullPath(), key)
+}
+
+// requestHash tell whether a repeat is the same request, If-Match is part of it because it picks the version of loan
+func requestHash(method, path, ifMatch string, body []byte) string {
+	return hashOf(method, path, ifMatch, string(body))
+}
+
+func hashOf(parts ...string) string {
+	h := sha256.New()
+	for i, part := range parts {
+		if i > 0 {
+			h.Write([]byte{'\n'})
+		}
+		h.Write([]byte(part))
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,223 @@
+package response
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"simple-app/internal/model"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+type memoryIdempotencyStore struct {
+	records map[string]model.IdempotencyKey
+}
+
+func (s *memoryIdempotencyStore) Start(ctx context.Context, key, requestHash string, ttl, lease time.Duration) (model.IdempotencyKey, bool, error) {
+	now := time.Now()
+	record, ok := s.records[key]
+	if ok && (record.Status == model.IdempotencyCompleted || record.ClaimedUntil.After(now)) {
+		return record, false, nil
+	}
+	claimedUntil := now.Add(lease)
+	record = model.IdempotencyKey{Key: key, RequestHash: requestHash, Status: model.IdempotencyInProgress, ClaimedUntil: &claimedUntil}
+	s.records[key] = record
+	return record, true, nil
+}
+
+func (s *memoryIdempotencyStore) Complete(ctx context.Context, record model.IdempotencyKey) error {
+	record.Status = model.IdempotencyCompleted
+	s.records[record.Key] = record
+	return nil
+}
+
+func (s *memoryIdempotencyStore) Delete(ctx context.Context, key string) error {
+	delete(s.records, key)
+	return nil
+}
+
+func TestIdempotency(t *testing.T) {
+	type request struct {
+		key string
+		// path is /test when it is empty
+		path       string
+		auth       string
+		ifMatch    string
+		body       string
+		wantStatus int
+		wantBody   string
+		wantReplay bool
+	}
+	tests := []struct {
+		name   string
+		status int
+		// panics is how many runs panic before the handler answers with status
+		panics  int
+		pending []string
+		// abandoned are keys in progress whose lease is over, like the key of a crashed process
+		abandoned []string
+		requests  []request
+		wantRuns  int
+	}{
+		{
+			name:   "without key every request runs",
+			status: http.StatusOK,
+			requests: []request{
+				{body: `{"amount":"10"}`, wantStatus: http.StatusOK, wantBody: `{"run":1}`},
+				{body: `{"amount":"10"}`, wantStatus: http.StatusOK, wantBody: `{"run":2}`},
+			},
+			wantRuns: 2,
+		}, {
+			name:   "repeat replays the first response",
+			status: http.StatusOK,
+			requests: []request{
+				{key: "k1", body: `{"amount":"10"}`, wantStatus: http.StatusOK, wantBody: `{"run":1}`},
+				{key: "k1", body: `{"amount":"10"}`, wantStatus: http.StatusOK, wantBody: `{"run":1}`, wantReplay: true},
+				{key: "k2", body: `{"amount":"10"}`, wantStatus: http.StatusOK, wantBody: `{"run":2}`},
+			},
+			wantRuns: 2,
+		}, {
+			name:   "client error is replayed too",
+			status: http.StatusConflict,
+			requests: []request{
+				{key: "k1", body: `{}`, wantStatus: http.StatusConflict, wantBody: `{"run":1}`},
+				{key: "k1", body: `{}`, wantStatus: http.StatusConflict, wantBody: `{"run":1}`, wantReplay: true},
+			},
+			wantRuns: 1,
+		}, {
+			name:   "reuse with different request is rejected",
+			status: http.StatusOK,
+			requests: []request{
+				{key: "k1", body: `{"amount":"10"}`, wantStatus: http.StatusOK, wantBody: `{"run":1}`},
+				{key: "k1", body: `{"amount":"20"}`, wantStatus: http.StatusUnprocessableEntity},
+			},
+			wantRuns: 1,
+		}, {
+			name:    "request still in progress is rejected",
+			status:  http.StatusOK,
+			pending: []string{"k1"},
+			requests: []request{
+				{key: "k1", body: `{"amount":"10"}`, wantStatus: http.StatusConflict},
+			},
+			wantRuns: 0,
+		}, {
+			name:      "abandoned request is taken over after its lease",
+			status:    http.StatusOK,
+			abandoned: []string{"k1"},
+			requests: []request{
+				{key: "k1", body: `{"amount":"10"}`, wantStatus: http.StatusOK, wantBody: `{"run":1}`},
+				{key: "k1", body: `{"amount":"10"}`, wantStatus: http.StatusOK, wantBody: `{"run":1}`, wantReplay: true},
+			},
+			wantRuns: 1,
+		}, {
+			name:   "server error is not stored",
+			status: http.StatusInternalServerError,
+			requests: []request{
+				{key: "k1", body: `{}`, wantStatus: http.StatusInternalServerError, wantBody: `{"run":1}`},
+				{key: "k1", body: `{}`, wantStatus: http.StatusInternalServerError, wantBody: `{"run":2}`},
+			},
+			wantRuns: 2,
+		}, {
+			name:   "panic releases the key",
+			status: http.StatusOK,
+			panics: 1,
+			requests: []request{
+				{key: "k1", body: `{}`, wantStatus: http.StatusInternalServerError},
+				{key: "k1", body: `{}`, wantStatus: http.StatusOK, wantBody: `{"run":2}`},
+				{key: "k1", body: `{}`, wantStatus: http.StatusOK, wantBody: `{"run":2}`, wantReplay: true},
+			},
+			wantRuns: 2,
+		}, {
+			name:   "key of another caller is not replayed",
+			status: http.StatusOK,
+			requests: []request{
+				{key: "k1", auth: "Bearer a", body: `{}`, wantStatus: http.StatusOK, wantBody: `{"run":1}`},
+				{key: "k1", auth: "Bearer b", body: `{}`, wantStatus: http.StatusOK, wantBody: `{"run":2}`},
+				{key: "k1", auth: "Bearer a", body: `{}`, wantStatus: http.StatusOK, wantBody: `{"run":1}`, wantReplay: true},
+			},
+			wantRuns: 2,
+		}, {
+			name:   "key of another route is not replayed",
+			status: http.StatusOK,
+			requests: []request{
+				{key: "k1", body: `{}`, wantStatus: http.StatusOK, wantBody: `{"run":1}`},
+				{key: "k1", path: "/test/other", body: `{}`, wantStatus: http.StatusOK, wantBody: `{"run":2}`},
+			},
+			wantRuns: 2,
+		}, {
+			name:   "reuse with another If-Match is rejected",
+			status: http.StatusOK,
+			requests: []request{
+				{key: "k1", ifMatch: `"1"`, body: `{}`, wantStatus: http.StatusOK, wantBody: `{"run":1}`},
+				{key: "k1", ifMatch: `"2"`, body: `{}`, wantStatus: http.StatusUnprocessableEntity},
+			},
+			wantRuns: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &memoryIdempotencyStore{records: map[string]model.IdempotencyKey{}}
+			claim := func(key string, claimedUntil time.Time) {
+				// the key of a caller without Authorization and client IP
+				key = hashOf("", http.MethodPost, "/test", key)
+				store.records[key] = model.IdempotencyKey{
+					Key:          key,
+					RequestHash:  requestHash(http.MethodPost, "/test", "", []byte(`{"amount":"10"}`)),
+					Status:       model.IdempotencyInProgress,
+					ClaimedUntil: &claimedUntil,
+				}
+			}
+			for _, key := range tt.pending {
+				claim(key, time.Now().Add(time.Minute))
+			}
+			for _, key := range tt.abandoned {
+				claim(key, time.Now().Add(-time.Second))
+			}
+
+			runs := 0
+			handle := func(c *gin.Context) {
+				runs++
+				if runs <= tt.panics {
+					panic("handler failed")
+				}
+				c.JSON(tt.status, gin.H{"run": runs})
+			}
+			r, _ := setupRouter([]gin.HandlerFunc{Middleware, Idempotency(store, time.Hour, time.Minute)}, func(r gin.IRoutes) gin.IRoutes {
+				return r.POST("", handle).POST("/other", handle)
+			})
+
+			for _, rq := range tt.requests {
+				path := rq.path
+				if path == "" {
+					path = "/test"
+				}
+
+				w := httptest.NewRecorder()
+				req, _ := http.NewRequest(http.MethodPost, path, strings.NewReader(rq.body))
+				if rq.key != "" {
+					req.Header.Set(IdempotencyKeyHeader, rq.key)
+				}
+				if rq.auth != "" {
+					req.Header.Set("Authorization", rq.auth)
+				}
+				if rq.ifMatch != "" {
+					req.Header.Set("If-Match", rq.ifMatch)
+				}
+
+				r.ServeHTTP(w, req)
+
+				assert.Equal(t, rq.wantStatus, w.Code)
+				if rq.wantBody != "" {
+					assert.Equal(t, rq.wantBody, w.Body.String())
+				}
+				assert.Equal(t, rq.wantReplay, w.Header().Get(IdempotentReplayedHeader) == "true")
+			}
+			assert.Equal(t, tt.wantRuns, runs)
+		})
+	}
+}
@@ -0,0 +1,50 @@
+package sqldb
+
+import (
+	"context"
+	"errors"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/lib/pq"
+)
+
+// Driver returns the base driver of DB, postgres or mysql
+func (db *DB) Driver() string {
+	return db.driver
+}
+
+// Insert runs an INSERT of a single row written with ? placeholders on querier and returns the ID of the row.
+// The ID is read with RETURNING on postgres and with LAST_INSERT_ID() on mysql, the table must have an id column
+func (db *DB) Insert(ctx context.Context, querier Querier, query string, args ...interface{}) (id int, err error) {
+	if db.driver != "mysql" {
+		err = querier.GetContext(ctx, &id, db.Rebind(query+" RETURNING id"), args...)
+		return id, err
+	}
+
+	result, err := querier.ExecContext(ctx, query, args...)
+	if err != nil {
+		return id, err
+	}
+
+	lastID, err := result.LastInsertId()
+	if err != nil {
+		return id, err
+	}
+
+	return int(lastID), nil
+}
+
+// IsUniqueViolation tells whether err is a violation of a unique constraint, 23505 on postgres and 1062 on mysql
+func IsUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		return pqErr.Code == "23505"
+	}
+
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) {
+		return mysqlErr.Number == 1062
+	}
+
+	return false
+}
@@ -0,0 +1,72 @@
+package sqldb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/go-sql-driver/mysql"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInsert(t *testing.T) {
+	testCases := []struct {
+		driver string
+		mock   func(m sqlmock.Sqlmock)
+	}{
+		{
+			driver: "postgres",
+			mock: func(m sqlmock.Sqlmock) {
+				m.ExpectQuery(regexp.QuoteMeta("INSERT INTO loan (status) VALUES ($1) RETURNING id")).WithArgs(1).
+					WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(7))
+			},
+		},
+		{
+			driver: "nrmysql",
+			mock: func(m sqlmock.Sqlmock) {
+				m.ExpectExec(regexp.QuoteMeta("INSERT INTO loan (status) VALUES (?)")).WithArgs(1).
+					WillReturnResult(sqlmock.NewResult(7, 1))
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.driver, func(t *testing.T) {
+			conn, m, err := sqlmock.New()
+			require.NoError(t, err)
+			defer conn.Close()
+			tc.mock(m)
+
+			db := NewFromDB(conn, conn, tc.driver)
+			id, err := db.Insert(context.Background(), db.Writer(context.Background()), "INSERT INTO loan (status) VALUES (?)", 1)
+			require.NoError(t, err)
+			require.Equal(t, 7, id)
+			require.NoError(t, m.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestIsUniqueViolation(t *testing.T) {
+	testCases := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "postgres unique violation", err: &pq.Error{Code: "23505"}, want: true},
+		{name: "mysql duplicate entry", err: &mysql.MySQLError{Number: 1062}, want: true},
+		{name: "wrapped", err: fmt.Errorf("failed to insert: %w", &mysql.MySQLError{Number: 1062}), want: true},
+		{name: "postgres foreign key violation", err: &pq.Error{Code: "23503"}},
+		{name: "mysql deadlock", err: &mysql.MySQLError{Number: 1213}},
+		{name: "other error", err: errors.New("failed")},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.want, IsUniqueViolation(tc.err))
+		})
+	}
+}
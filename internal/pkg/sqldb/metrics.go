@@ -0,0 +1,18 @@
+package sqldb
+
+import "expvar"
+
+// txRetries counts transactions of TxManager that are run again, by the error they failed on,
+// and "exhausted" for the ones that still fail after the last retry. It is published as sqldb_tx_retries on /debug/vars
+var txRetries = expvar.NewMap("sqldb_tx_retries")
+
+// TxRetries returns how many transactions are retried after the given reason, "serialization_failure" or "deadlock",
+// or given up on with "exhausted"
+func TxRetries(reason string) int64 {
+	v, ok := txRetries.Get(reason).(*expvar.Int)
+	if !ok {
+		return 0
+	}
+
+	return v.Value()
+}
@@ -0,0 +1,262 @@
+package sqldb
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// RoutingConfig defines how reads are routed between master and follower DB.
+// Reads go to follower unless the request has written within PinWindow, or follower is more than MaxReplicationLag behind
+type RoutingConfig struct {
+	// PinWindow is how long reads of a request stay on master after it writes, 0 disables pinning
+	PinWindow time.Duration `yaml:"pin_window"`
+
+	// MaxReplicationLag is the lag of follower above which every read goes to master, 0 disables the lag check
+	MaxReplicationLag time.Duration `yaml:"max_replication_lag"`
+
+	// LagCheckInterval is how often the lag of follower is checked
+	LagCheckInterval time.Duration `yaml:"lag_check_interval"`
+}
+
+// router holds the routing state of DB
+type router struct {
+	pinWindow time.Duration
+	lagging   atomic.Bool
+}
+
+// errReplicationStopped is returned by the lag check when follower does not replicate from master
+var errReplicationStopped = errors.New("replication of follower is stopped")
+
+// lagChecks get the replication lag of follower, 0 when follower is up to date
+var lagChecks = map[string]func(ctx context.Context, follower *sqlx.DB) (time.Duration, error){
+	"postgres": postgresLag,
+	"mysql":    mysqlLag,
+}
+
+// Route starts routing reads with cfg, the lag of follower is checked until ctx is done
+func (db *DB) Route(ctx context.Context, cfg RoutingConfig) {
+	db.router.pinWindow = cfg.PinWindow
+
+	if cfg.MaxReplicationLag <= 0 || db.follower == db.master {
+		return
+	}
+
+	lagCheck, ok := lagChecks[db.driver]
+	if !ok {
+		// a lagging follower can not be told apart, so it is never read from
+		log.Printf("sqldb: replication lag check is not supported for %s, reads go to master", db.driver)
+		db.router.lagging.Store(true)
+		return
+	}
+
+	interval := cfg.LagCheckInterval
+	if interval <= 0 {
+		interval = time.Second
+	}
+
+	check := func() {
+		lag, err := lagCheck(ctx, db.follower)
+		if err != nil {
+			// the follower can not be trusted when its lag is unknown
+			log.Printf("sqldb: failed to check replication lag: %s", err.Error())
+			db.router.lagging.Store(true)
+			return
+		}
+		db.router.lagging.Store(lag > cfg.MaxReplicationLag)
+	}
+
+	check()
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				check()
+			}
+		}
+	}()
+}
+
+func postgresLag(ctx context.Context, follower *sqlx.DB) (time.Duration, error) {
+	var seconds float64
+	err := follower.GetContext(ctx, &seconds, `SELECT CASE
+			WHEN NOT pg_is_in_recovery() OR pg_last_wal_receive_lsn() = pg_last_wal_replay_lsn() THEN 0
+			ELSE COALESCE(EXTRACT(EPOCH FROM now() - pg_last_xact_replay_timestamp()), 0)
+		END`)
+	if err != nil {
+		return 0, err
+	}
+
+	return time.Duration(seconds * float64(time.Second)), nil
+}
+
+// mysqlLag reads Seconds_Behind_Source of SHOW REPLICA STATUS (MySQL 8.0.22 or later),
+// MariaDB names it Seconds_Behind_Master. It is NULL when replication is stopped
+func mysqlLag(ctx context.Context, follower *sqlx.DB) (time.Duration, error) {
+	rows, err := follower.QueryxContext(ctx, `SHOW REPLICA STATUS`)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	// follower is not a replica, e.g. master itself
+	if !rows.Next() {
+		return 0, rows.Err()
+	}
+
+	status := map[string]interface{}{}
+	err = rows.MapScan(status)
+	if err != nil {
+		return 0, err
+	}
+
+	value, ok := status["Seconds_Behind_Source"]
+	if !ok {
+		value, ok = status["Seconds_Behind_Master"]
+	}
+	if !ok {
+		return 0, errors.New("SHOW REPLICA STATUS has no Seconds_Behind_Source")
+	}
+
+	var seconds int64
+	switch v := value.(type) {
+	case nil:
+		return 0, errReplicationStopped
+	case int64:
+		seconds = v
+	case []byte:
+		seconds, err = strconv.ParseInt(string(v), 10, 64)
+	case string:
+		seconds, err = strconv.ParseInt(v, 10, 64)
+	default:
+		err = fmt.Errorf("unexpected Seconds_Behind_Source %T", value)
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	return time.Duration(seconds) * time.Second, nil
+}
+
+// pin is the time until which reads of a request go to master
+type pin struct {
+	mu    sync.Mutex
+	until time.Time
+}
+
+type pinKey struct{}
+
+// WithPin returns a context whose reads go to master until the given time, and longer once it writes.
+// It is usually set once per request, a zero time starts unpinned
+func WithPin(ctx context.Context, until time.Time) context.Context {
+	return context.WithValue(ctx, pinKey{}, &pin{until: until})
+}
+
+// PinnedUntil returns the time until which reads of ctx go to master, zero when ctx has no pin
+func PinnedUntil(ctx context.Context) time.Time {
+	p, ok := ctx.Value(pinKey{}).(*pin)
+	if !ok {
+		return time.Time{}
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.until
+}
+
+// markWrite pins the reads of ctx to master for the pin window
+func (db *DB) markWrite(ctx context.Context) {
+	p, ok := ctx.Value(pinKey{}).(*pin)
+	if !ok || db.router.pinWindow <= 0 {
+		return
+	}
+
+	until := time.Now().Add(db.router.pinWindow)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if until.After(p.until) {
+		p.until = until
+	}
+}
+
+// reader returns the transaction of ctx, otherwise the DB that reads of ctx go to
+func (db *DB) reader(ctx context.Context) Querier {
+	if tx := txFromContext(ctx); tx != nil {
+		return tx.tx
+	}
+
+	if db.router.lagging.Load() {
+		return db.master
+	}
+
+	if time.Now().Before(PinnedUntil(ctx)) {
+		return db.master
+	}
+
+	return db.follower
+}
+
+// GetContext from the transaction of ctx, otherwise from follower DB, or master DB when ctx is pinned or follower is lagging
+func (db *DB) GetContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+	return db.reader(ctx).GetContext(ctx, dest, query, args...)
+}
+
+// SelectContext from follower DB, or master DB when ctx is pinned or follower is lagging
+func (db *DB) SelectContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+	return db.reader(ctx).SelectContext(ctx, dest, query, args...)
+}
+
+// QueryContext from follower DB, or master DB when ctx is pinned or follower is lagging
+func (db *DB) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	return db.reader(ctx).QueryContext(ctx, query, args...)
+}
+
+// QueryRowContext from follower DB, or master DB when ctx is pinned or follower is lagging
+func (db *DB) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	return db.reader(ctx).QueryRowContext(ctx, query, args...)
+}
+
+// QueryxContext from follower DB, or master DB when ctx is pinned or follower is lagging
+func (db *DB) QueryxContext(ctx context.Context, query string, args ...interface{}) (*sqlx.Rows, error) {
+	return db.reader(ctx).QueryxContext(ctx, query, args...)
+}
+
+// QueryRowxContext from follower DB, or master DB when ctx is pinned or follower is lagging
+func (db *DB) QueryRowxContext(ctx context.Context, query string, args ...interface{}) *sqlx.Row {
+	return db.reader(ctx).QueryRowxContext(ctx, query, args...)
+}
+
+// NamedQueryContext from follower DB, or master DB when ctx is pinned or follower is lagging
+func (db *DB) NamedQueryContext(ctx context.Context, query string, arg interface{}) (*sqlx.Rows, error) {
+	return sqlx.NamedQueryContext(ctx, db.reader(ctx), query, arg)
+}
+
+// ExecContext on the transaction of ctx or master DB, reads of ctx are pinned to master afterwards
+func (db *DB) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return db.Writer(ctx).ExecContext(ctx, query, args...)
+}
+
+// NamedExecContext on the transaction of ctx or master DB, reads of ctx are pinned to master afterwards
+func (db *DB) NamedExecContext(ctx context.Context, query string, arg interface{}) (sql.Result, error) {
+	return db.Writer(ctx).NamedExecContext(ctx, query, arg)
+}
+
+// BeginTxx begins a transaction on master DB, reads of ctx are pinned to master afterwards
+func (db *DB) BeginTxx(ctx context.Context, opts *sql.TxOptions) (*sqlx.Tx, error) {
+	db.markWrite(ctx)
+	return db.master.BeginTxx(ctx, opts)
+}
@@ -0,0 +1,159 @@
+package sqldb
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRoute(t *testing.T) {
+	ctx := context.Background()
+
+	db, err := Connect(ctx, DBConfig{
+		Driver:      "ramsql",
+		MasterDSN:   "route-master",
+		FollowerDSN: "route-follower",
+	})
+	require.NoError(t, err)
+
+	// the same table with a different row on master and follower, so the read tells where it went
+	_, err = db.master.Exec(`CREATE TABLE source (name TEXT);`)
+	require.NoError(t, err)
+	_, err = db.master.Exec(`INSERT INTO source (name) VALUES ('master');`)
+	require.NoError(t, err)
+	_, err = db.master.Exec(`CREATE TABLE written (name TEXT);`)
+	require.NoError(t, err)
+	_, err = db.follower.Exec(`CREATE TABLE source (name TEXT);`)
+	require.NoError(t, err)
+	_, err = db.follower.Exec(`INSERT INTO source (name) VALUES ('follower');`)
+	require.NoError(t, err)
+
+	read := func(ctx context.Context) string {
+		var name string
+		err := db.GetContext(ctx, &name, `SELECT name FROM source`)
+		require.NoError(t, err)
+		return name
+	}
+
+	db.Route(ctx, RoutingConfig{PinWindow: time.Minute})
+
+	testCases := []struct {
+		name    string
+		ctx     func() context.Context
+		write   bool
+		lagging bool
+		want    string
+	}{
+		{
+			name: "read goes to follower",
+			ctx:  context.Background,
+			want: "follower",
+		},
+		{
+			name:  "read after write goes to master",
+			ctx:   func() context.Context { return WithPin(context.Background(), time.Time{}) },
+			write: true,
+			want:  "master",
+		},
+		{
+			name:  "write without pin does not pin",
+			ctx:   context.Background,
+			write: true,
+			want:  "follower",
+		},
+		{
+			name: "pin of earlier request",
+			ctx:  func() context.Context { return WithPin(context.Background(), time.Now().Add(time.Second)) },
+			want: "master",
+		},
+		{
+			name: "expired pin",
+			ctx:  func() context.Context { return WithPin(context.Background(), time.Now().Add(-time.Second)) },
+			want: "follower",
+		},
+		{
+			name:    "follower is lagging",
+			ctx:     context.Background,
+			lagging: true,
+			want:    "master",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			db.router.lagging.Store(tc.lagging)
+			ctx := tc.ctx()
+
+			if tc.write {
+				_, err := db.ExecContext(ctx, `INSERT INTO written (name) VALUES ('write');`)
+				require.NoError(t, err)
+			}
+
+			require.Equal(t, tc.want, read(ctx))
+		})
+	}
+}
+
+func TestMySQLLag(t *testing.T) {
+	testCases := []struct {
+		name    string
+		rows    *sqlmock.Rows
+		want    time.Duration
+		wantErr error
+	}{
+		{
+			name: "replica",
+			rows: sqlmock.NewRows([]string{"Replica_IO_State", "Seconds_Behind_Source"}).AddRow("Waiting for source to send event", "5"),
+			want: 5 * time.Second,
+		},
+		{
+			name: "mariadb replica",
+			rows: sqlmock.NewRows([]string{"Slave_IO_State", "Seconds_Behind_Master"}).AddRow("Waiting for master to send event", "2"),
+			want: 2 * time.Second,
+		},
+		{
+			name:    "replication stopped",
+			rows:    sqlmock.NewRows([]string{"Replica_IO_State", "Seconds_Behind_Source"}).AddRow("", nil),
+			wantErr: errReplicationStopped,
+		},
+		{
+			name: "not a replica",
+			rows: sqlmock.NewRows([]string{"Replica_IO_State", "Seconds_Behind_Source"}),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			follower, mock, err := sqlmock.New()
+			require.NoError(t, err)
+			defer follower.Close()
+
+			mock.ExpectQuery(`SHOW REPLICA STATUS`).WillReturnRows(tc.rows)
+
+			lag, err := mysqlLag(context.Background(), sqlx.NewDb(follower, "mysql"))
+			require.ErrorIs(t, err, tc.wantErr)
+			require.Equal(t, tc.want, lag)
+			require.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestRouteWithoutLagCheck(t *testing.T) {
+	master, _, err := sqlmock.New()
+	require.NoError(t, err)
+	defer master.Close()
+
+	follower, _, err := sqlmock.New()
+	require.NoError(t, err)
+	defer follower.Close()
+
+	db := newFromSqlxDB(sqlx.NewDb(master, "sqlmock"), sqlx.NewDb(follower, "sqlmock"))
+	db.Route(context.Background(), RoutingConfig{MaxReplicationLag: time.Second})
+
+	// the lag of follower is unknown, so reads go to master
+	require.Equal(t, db.master, db.reader(context.Background()))
+}
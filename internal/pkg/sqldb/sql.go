@@ -31,6 +31,9 @@ type DB struct {
 	// driver define the base driver used. like postgres or mysql. nrpostgres will be converted as postgres
 	driver string
 
+	// router decides whether reads go to master or follower DB
+	router router
+
 	defaultTimeout time.Duration // TODO: do we really need it? It currently only used by ping
 
 	// TODO: add tracer
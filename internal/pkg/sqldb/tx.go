@@ -0,0 +1,245 @@
+package sqldb
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"math/rand"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+const (
+	// DefaultMaxRetries is how many times a transaction is retried after a serialization failure or deadlock
+	DefaultMaxRetries = 3
+	// DefaultBaseBackoff is the wait before the first retry
+	DefaultBaseBackoff = 10 * time.Millisecond
+	// DefaultMaxBackoff caps the wait between retries
+	DefaultMaxBackoff = 500 * time.Millisecond
+)
+
+// TxConfig defines how TxManager retries a transaction failing on serialization or deadlock
+type TxConfig struct {
+	// MaxRetries is how many times the transaction is run again, 0 uses DefaultMaxRetries and below 0 disables the retry
+	MaxRetries int
+
+	// BaseBackoff is the wait before the first retry, doubled every retry. The actual wait is a random duration up to it,
+	// so transactions that failed on each other do not retry at the same time again
+	BaseBackoff time.Duration
+
+	// MaxBackoff caps the wait between retries
+	MaxBackoff time.Duration
+}
+
+// Querier runs queries on the transaction of the context, or on master DB when there is none
+type Querier interface {
+	sqlx.ExtContext
+
+	GetContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error
+	SelectContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+	NamedExecContext(ctx context.Context, query string, arg interface{}) (sql.Result, error)
+}
+
+// ambientTx is the transaction stored in the context by TxManager
+type ambientTx struct {
+	tx *sqlx.Tx
+
+	// savepoints is the number of nested WithTx currently running
+	savepoints int
+}
+
+type txKey struct{}
+
+func txFromContext(ctx context.Context) *ambientTx {
+	tx, _ := ctx.Value(txKey{}).(*ambientTx)
+	return tx
+}
+
+// InTx tells whether ctx has a transaction started by TxManager
+func InTx(ctx context.Context) bool {
+	return txFromContext(ctx) != nil
+}
+
+// Writer returns the transaction of ctx, or master DB when there is none. Reads of ctx are pinned to master afterwards
+func (db *DB) Writer(ctx context.Context) Querier {
+	if tx := txFromContext(ctx); tx != nil {
+		return tx.tx
+	}
+
+	db.markWrite(ctx)
+	return db.master
+}
+
+// TxManager runs functions inside a transaction of master DB. The transaction is stored in the context given to
+// the function, so every repository using DB joins it without passing it around
+type TxManager struct {
+	db  *DB
+	cfg TxConfig
+}
+
+// NewTxManager creates TxManager, zero fields of cfg use their default
+func NewTxManager(db *DB, cfg TxConfig) *TxManager {
+	if cfg.MaxRetries == 0 {
+		cfg.MaxRetries = DefaultMaxRetries
+	}
+	if cfg.BaseBackoff <= 0 {
+		cfg.BaseBackoff = DefaultBaseBackoff
+	}
+	if cfg.MaxBackoff <= 0 {
+		cfg.MaxBackoff = DefaultMaxBackoff
+	}
+
+	return &TxManager{
+		db:  db,
+		cfg: cfg,
+	}
+}
+
+// WithTx runs fn inside a transaction at the default isolation of the database, READ COMMITTED on PostgreSQL and
+// REPEATABLE READ on MySQL, committed when fn returns nil and rolled back otherwise.
+// When ctx already has a transaction, fn runs inside a savepoint of it, so only the work of fn is rolled back on error.
+// A transaction failing on serialization or deadlock is run again from the start, fn must be safe to run more than once
+func (m *TxManager) WithTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	return m.withTx(ctx, sql.LevelDefault, fn)
+}
+
+// WithSerializableTx is WithTx with SERIALIZABLE isolation, for work whose reads must not change until it commits.
+// When ctx already has a transaction, fn runs inside a savepoint of it with the isolation of that transaction
+func (m *TxManager) WithSerializableTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	return m.withTx(ctx, sql.LevelSerializable, fn)
+}
+
+func (m *TxManager) withTx(ctx context.Context, isolation sql.IsolationLevel, fn func(ctx context.Context) error) error {
+	if tx := txFromContext(ctx); tx != nil {
+		return m.savepoint(ctx, tx, fn)
+	}
+
+	for attempt := 0; ; attempt++ {
+		err := m.run(ctx, isolation, fn)
+		reason := retryReason(err)
+		if reason == "" {
+			return err
+		}
+
+		if attempt >= m.cfg.MaxRetries {
+			txRetries.Add("exhausted", 1)
+			return err
+		}
+		txRetries.Add(reason, 1)
+
+		wait := m.backoff(attempt)
+		log.Printf("sqldb: retrying transaction after %s in %s. Retry: %d", reason, wait, attempt+1)
+
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(wait):
+		}
+	}
+}
+
+// backoff is a random wait up to BaseBackoff doubled by the number of attempts, capped at MaxBackoff
+func (m *TxManager) backoff(attempt int) time.Duration {
+	ceiling := m.cfg.MaxBackoff
+	if attempt < 32 && m.cfg.BaseBackoff<<attempt < ceiling {
+		ceiling = m.cfg.BaseBackoff << attempt
+	}
+
+	return time.Duration(rand.Int63n(int64(ceiling))) + 1
+}
+
+func (m *TxManager) run(ctx context.Context, isolation sql.IsolationLevel, fn func(ctx context.Context) error) (err error) {
+	tx, err := m.db.BeginTxx(ctx, &sql.TxOptions{Isolation: isolation})
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			_ = tx.Rollback()
+			panic(p)
+		}
+	}()
+
+	err = fn(context.WithValue(ctx, txKey{}, &ambientTx{tx: tx}))
+	if err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (m *TxManager) savepoint(ctx context.Context, tx *ambientTx, fn func(ctx context.Context) error) (err error) {
+	tx.savepoints++
+	defer func() { tx.savepoints-- }()
+
+	name := fmt.Sprintf("sp_%d", tx.savepoints)
+
+	_, err = tx.tx.ExecContext(ctx, "SAVEPOINT "+name)
+	if err != nil {
+		return fmt.Errorf("failed to create savepoint: %w", err)
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			_, _ = tx.tx.ExecContext(ctx, "ROLLBACK TO SAVEPOINT "+name)
+			panic(p)
+		}
+	}()
+
+	err = fn(ctx)
+	if err != nil {
+		_, rbErr := tx.tx.ExecContext(ctx, "ROLLBACK TO SAVEPOINT "+name)
+		if rbErr != nil {
+			return errors.Join(err, fmt.Errorf("failed to rollback savepoint: %w", rbErr))
+		}
+		return err
+	}
+
+	_, err = tx.tx.ExecContext(ctx, "RELEASE SAVEPOINT "+name)
+	if err != nil {
+		return fmt.Errorf("failed to release savepoint: %w", err)
+	}
+
+	return nil
+}
+
+// retryableCodes are the errors of PostgreSQL after which a transaction succeeds when it is run again
+var retryableCodes = map[pq.ErrorCode]string{
+	"40001": "serialization_failure",
+	"40P01": "deadlock",
+}
+
+// retryableNumbers are the errors of MySQL after which a transaction succeeds when it is run again,
+// InnoDB reports conflicts of SERIALIZABLE transactions as deadlocks too
+var retryableNumbers = map[uint16]string{
+	1213: "deadlock",
+}
+
+// retryReason is the name of the retryable error err is, empty when err can not be retried
+func retryReason(err error) string {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		return retryableCodes[pqErr.Code]
+	}
+
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) {
+		return retryableNumbers[mysqlErr.Number]
+	}
+
+	return ""
+}
+
+// IsRetryable tells whether err is a serialization failure (40001) or deadlock (40P01) of PostgreSQL,
+// or a deadlock (1213) of MySQL
+func IsRetryable(err error) bool {
+	return retryReason(err) != ""
+}
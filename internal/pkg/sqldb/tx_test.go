@@ -0,0 +1,255 @@
+package sqldb
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/require"
+)
+
+// recorder is a driver connection that records the statements it is given instead of running them
+type recorder struct {
+	mu  sync.Mutex
+	log []string
+}
+
+func (r *recorder) record(stmt string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.log = append(r.log, stmt)
+}
+
+func (r *recorder) Connect(context.Context) (driver.Conn, error) { return r, nil }
+func (r *recorder) Driver() driver.Driver                        { return nil }
+
+func (r *recorder) Prepare(string) (driver.Stmt, error) { return nil, errors.New("not supported") }
+func (r *recorder) Close() error                        { return nil }
+func (r *recorder) Begin() (driver.Tx, error)           { r.record("BEGIN"); return r, nil }
+func (r *recorder) Commit() error                       { r.record("COMMIT"); return nil }
+func (r *recorder) Rollback() error                     { r.record("ROLLBACK"); return nil }
+
+func (r *recorder) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if sql.IsolationLevel(opts.Isolation) == sql.LevelSerializable {
+		r.record("BEGIN SERIALIZABLE")
+		return r, nil
+	}
+	return r.Begin()
+}
+
+func (r *recorder) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	r.record(query)
+	return driver.RowsAffected(1), nil
+}
+
+func TestWithTx(t *testing.T) {
+	errFailed := errors.New("failed")
+	serialization := &pq.Error{Code: "40001"}
+	deadlock := &pq.Error{Code: "40P01"}
+
+	insert := func(db *DB) func(ctx context.Context) error {
+		return func(ctx context.Context) error {
+			_, err := db.ExecContext(ctx, "INSERT")
+			return err
+		}
+	}
+
+	testCases := []struct {
+		name         string
+		maxRetries   int
+		serializable bool
+		fn           func(db *DB, tm *TxManager) func(ctx context.Context) error
+		wantErr      error
+		wantLog      []string
+		wantRetries  map[string]int64
+	}{
+		{
+			name: "commit when fn succeeds",
+			fn: func(db *DB, tm *TxManager) func(ctx context.Context) error {
+				return insert(db)
+			},
+			wantLog: []string{"BEGIN", "INSERT", "COMMIT"},
+		},
+		{
+			name: "rollback when fn fails",
+			fn: func(db *DB, tm *TxManager) func(ctx context.Context) error {
+				return func(ctx context.Context) error {
+					_ = insert(db)(ctx)
+					return errFailed
+				}
+			},
+			wantErr: errFailed,
+			wantLog: []string{"BEGIN", "INSERT", "ROLLBACK"},
+		},
+		{
+			name: "nested call joins the transaction with a savepoint",
+			fn: func(db *DB, tm *TxManager) func(ctx context.Context) error {
+				return func(ctx context.Context) error {
+					return tm.WithTx(ctx, insert(db))
+				}
+			},
+			wantLog: []string{"BEGIN", "SAVEPOINT sp_1", "INSERT", "RELEASE SAVEPOINT sp_1", "COMMIT"},
+		},
+		{
+			name: "failed nested call only rolls back its savepoint",
+			fn: func(db *DB, tm *TxManager) func(ctx context.Context) error {
+				return func(ctx context.Context) error {
+					err := tm.WithTx(ctx, func(ctx context.Context) error {
+						_ = insert(db)(ctx)
+						return errFailed
+					})
+					if !errors.Is(err, errFailed) {
+						return errors.New("nested error is lost")
+					}
+					return nil
+				}
+			},
+			wantLog: []string{"BEGIN", "SAVEPOINT sp_1", "INSERT", "ROLLBACK TO SAVEPOINT sp_1", "COMMIT"},
+		},
+		{
+			name: "retry after serialization failure",
+			fn: func(db *DB, tm *TxManager) func(ctx context.Context) error {
+				attempts := 0
+				return func(ctx context.Context) error {
+					attempts++
+					if attempts == 1 {
+						return serialization
+					}
+					return insert(db)(ctx)
+				}
+			},
+			wantLog:     []string{"BEGIN", "ROLLBACK", "BEGIN", "INSERT", "COMMIT"},
+			wantRetries: map[string]int64{"serialization_failure": 1},
+		},
+		{
+			name:         "serializable transaction retries after deadlock",
+			serializable: true,
+			fn: func(db *DB, tm *TxManager) func(ctx context.Context) error {
+				attempts := 0
+				return func(ctx context.Context) error {
+					attempts++
+					if attempts <= 2 {
+						return deadlock
+					}
+					return insert(db)(ctx)
+				}
+			},
+			wantLog:     []string{"BEGIN SERIALIZABLE", "ROLLBACK", "BEGIN SERIALIZABLE", "ROLLBACK", "BEGIN SERIALIZABLE", "INSERT", "COMMIT"},
+			wantRetries: map[string]int64{"deadlock": 2},
+		},
+		{
+			name: "retry after mysql deadlock",
+			fn: func(db *DB, tm *TxManager) func(ctx context.Context) error {
+				attempts := 0
+				return func(ctx context.Context) error {
+					attempts++
+					if attempts == 1 {
+						return &mysql.MySQLError{Number: 1213}
+					}
+					return insert(db)(ctx)
+				}
+			},
+			wantLog:     []string{"BEGIN", "ROLLBACK", "BEGIN", "INSERT", "COMMIT"},
+			wantRetries: map[string]int64{"deadlock": 1},
+		},
+		{
+			name:         "serializable call inside a transaction keeps its isolation",
+			serializable: true,
+			fn: func(db *DB, tm *TxManager) func(ctx context.Context) error {
+				return func(ctx context.Context) error {
+					return tm.WithTx(ctx, insert(db))
+				}
+			},
+			wantLog: []string{"BEGIN SERIALIZABLE", "SAVEPOINT sp_1", "INSERT", "RELEASE SAVEPOINT sp_1", "COMMIT"},
+		},
+		{
+			name:       "give up after max retries",
+			maxRetries: 1,
+			fn: func(db *DB, tm *TxManager) func(ctx context.Context) error {
+				return func(ctx context.Context) error {
+					return serialization
+				}
+			},
+			wantErr:     serialization,
+			wantLog:     []string{"BEGIN", "ROLLBACK", "BEGIN", "ROLLBACK"},
+			wantRetries: map[string]int64{"serialization_failure": 1, "exhausted": 1},
+		},
+		{
+			name:       "no retry when disabled",
+			maxRetries: -1,
+			fn: func(db *DB, tm *TxManager) func(ctx context.Context) error {
+				return func(ctx context.Context) error {
+					return serialization
+				}
+			},
+			wantErr:     serialization,
+			wantLog:     []string{"BEGIN", "ROLLBACK"},
+			wantRetries: map[string]int64{"exhausted": 1},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := &recorder{}
+			conn := sqlx.NewDb(sql.OpenDB(rec), "postgres")
+			db := &DB{master: conn, follower: conn, driver: "postgres"}
+			tm := NewTxManager(db, TxConfig{MaxRetries: tc.maxRetries, BaseBackoff: time.Millisecond})
+
+			reasons := []string{"serialization_failure", "deadlock", "exhausted"}
+			before := map[string]int64{}
+			for _, reason := range reasons {
+				before[reason] = TxRetries(reason)
+			}
+
+			withTx := tm.WithTx
+			if tc.serializable {
+				withTx = tm.WithSerializableTx
+			}
+
+			err := withTx(context.Background(), tc.fn(db, tm))
+			if tc.wantErr != nil {
+				require.ErrorIs(t, err, tc.wantErr)
+			} else {
+				require.NoError(t, err)
+			}
+			require.Equal(t, tc.wantLog, rec.log)
+
+			for _, reason := range reasons {
+				require.Equal(t, tc.wantRetries[reason], TxRetries(reason)-before[reason], reason)
+			}
+		})
+	}
+}
+
+func TestWriterOutsideTx(t *testing.T) {
+	rec := &recorder{}
+	conn := sqlx.NewDb(sql.OpenDB(rec), "postgres")
+	db := &DB{master: conn, follower: conn, driver: "postgres"}
+
+	require.False(t, InTx(context.Background()))
+
+	_, err := db.Writer(context.Background()).ExecContext(context.Background(), "INSERT")
+	require.NoError(t, err)
+	require.Equal(t, []string{"INSERT"}, rec.log)
+}
+
+func TestBackoff(t *testing.T) {
+	tm := NewTxManager(nil, TxConfig{BaseBackoff: 10 * time.Millisecond, MaxBackoff: 50 * time.Millisecond})
+
+	for attempt, ceiling := range []time.Duration{10, 20, 40, 50, 50} {
+		for i := 0; i < 100; i++ {
+			wait := tm.backoff(attempt)
+			require.Positive(t, wait)
+			require.LessOrEqual(t, wait, ceiling*time.Millisecond)
+		}
+	}
+
+	require.LessOrEqual(t, tm.backoff(100), 50*time.Millisecond)
+}
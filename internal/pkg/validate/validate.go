@@ -6,6 +6,8 @@ import (
 	"strings"
 	"time"
 
+	"simple-app/internal/pkg/money"
+
 	"github.com/go-playground/locales/en"
 	ut "github.com/go-playground/universal-translator"
 	"github.com/go-playground/validator/v10"
@@ -52,6 +54,9 @@ func New(cfg *Options) *Validate {
 
 	_ = v.RegisterValidation("datetime", validateIsDatetime)
 
+	// validate money by its minor units, so tags like required and gt=0 work on it
+	v.RegisterCustomTypeFunc(moneyValue, money.Money{})
+
 	// use json tag for message
 	v.RegisterTagNameFunc(func(f reflect.StructField) string {
 		name := strings.SplitN(f.Tag.Get("json"), ",", 2)[0]
@@ -116,6 +121,14 @@ func (v *Validate) translateErrMessage(err error) []ErrorValidation {
 	return errs
 }
 
+// moneyValue returns the minor units of money.Money for validation
+func moneyValue(field reflect.Value) interface{} {
+	if m, ok := field.Interface().(money.Money); ok {
+		return m.Minor()
+	}
+	return nil
+}
+
 // validateIsDatetime is the validation function for validating if the current field's value is a valid datetime string.
 func validateIsDatetime(fl validator.FieldLevel) bool {
 	field := fl.Field()
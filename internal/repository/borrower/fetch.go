@@ -0,0 +1,42 @@
+package borrower
+
+import (
+	"context"
+	"database/sql"
+	"simple-app/internal/model"
+)
+
+// GetByID get borrower by ID
+func (b *Borrower) GetByID(ctx context.Context, ID int) (borrower model.Borrower, err error) {
+	var getQuery = `SELECT id ,name ,email ,phone_number ,kyc_status ,credit_limit ,created_at FROM borrower WHERE id=?`
+
+	err = b.db.GetContext(ctx, &borrower, b.db.Rebind(getQuery), ID)
+	if err != nil && err != sql.ErrNoRows {
+		return borrower, err
+	}
+
+	if err == sql.ErrNoRows {
+		return borrower, model.ErrNotFound
+	}
+
+	return borrower, nil
+}
+
+// GetByIDForUpdate get borrower by ID and lock the row until the transaction ends,
+// so loans of the same borrower are checked against the credit limit one by one
+func (b *Borrower) GetByIDForUpdate(ctx context.Context, ID int) (borrower model.Borrower, err error) {
+	querier := b.db.Writer(ctx)
+
+	var getQuery = `SELECT id ,name ,email ,phone_number ,kyc_status ,credit_limit ,created_at FROM borrower WHERE id=? FOR UPDATE`
+
+	err = querier.GetContext(ctx, &borrower, b.db.Rebind(getQuery), ID)
+	if err != nil && err != sql.ErrNoRows {
+		return borrower, err
+	}
+
+	if err == sql.ErrNoRows {
+		return borrower, model.ErrNotFound
+	}
+
+	return borrower, nil
+}
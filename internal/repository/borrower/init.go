@@ -0,0 +1,19 @@
+package borrower
+
+import (
+	"simple-app/internal/pkg/sqldb"
+)
+
+type Borrower struct {
+	db *sqldb.DB
+}
+
+type Param struct {
+	DB *sqldb.DB
+}
+
+func New(p Param) Borrower {
+	return Borrower{
+		db: p.DB,
+	}
+}
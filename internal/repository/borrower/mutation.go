@@ -0,0 +1,71 @@
+package borrower
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"simple-app/internal/model"
+)
+
+// Create create borrower, KYC status default is "pending"
+func (b *Borrower) Create(ctx context.Context, param model.Borrower) (data model.Borrower, err error) {
+	query := `
+		INSERT INTO borrower (
+			name,
+			email,
+			phone_number,
+			credit_limit
+		) VALUES (
+			?,
+			?,
+			?,
+			?
+		)
+	`
+
+	id, err := b.db.Insert(ctx, b.db.GetMaster(), query,
+		param.Name,
+		param.Email,
+		param.PhoneNumber,
+		param.CreditLimit,
+	)
+	if err != nil {
+		return data, fmt.Errorf("failed to insert borrower: %w", err)
+	}
+
+	var getQuery = `SELECT id ,name ,email ,phone_number ,kyc_status ,credit_limit ,created_at FROM borrower WHERE id=?`
+
+	err = b.db.GetMaster().GetContext(ctx, &data, b.db.Rebind(getQuery), id)
+	if err != nil {
+		return data, fmt.Errorf("failed to get inserted borrower: %w", err)
+	}
+
+	return data, nil
+}
+
+// UpdateKYC update KYC status of borrower
+func (b *Borrower) UpdateKYC(ctx context.Context, param model.KYC) (data model.Borrower, err error) {
+	query := `
+		UPDATE borrower SET
+			kyc_status = ?
+		WHERE id = ?
+	`
+
+	_, err = b.db.GetMaster().ExecContext(ctx, b.db.Rebind(query), param.KYCStatus, param.ID)
+	if err != nil {
+		return data, fmt.Errorf("failed to update kyc of borrower: %w", err)
+	}
+
+	// the borrower is read back rather than trusting the affected rows, mysql does not count a row set to the same value
+	var getQuery = `SELECT id ,name ,email ,phone_number ,kyc_status ,credit_limit ,created_at FROM borrower WHERE id=?`
+
+	err = b.db.GetMaster().GetContext(ctx, &data, b.db.Rebind(getQuery), param.ID)
+	if err == sql.ErrNoRows {
+		return data, model.ErrNotFound
+	}
+	if err != nil {
+		return data, fmt.Errorf("failed to get borrower: %w", err)
+	}
+
+	return data, nil
+}
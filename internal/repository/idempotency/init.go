@@ -0,0 +1,19 @@
+package idempotency
+
+import (
+	"simple-app/internal/pkg/sqldb"
+)
+
+type Idempotency struct {
+	db *sqldb.DB
+}
+
+type Param struct {
+	DB *sqldb.DB
+}
+
+func New(p Param) Idempotency {
+	return Idempotency{
+		db: p.DB,
+	}
+}
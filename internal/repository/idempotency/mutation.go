@@ -0,0 +1,115 @@
+package idempotency
+
+import (
+	"context"
+	"fmt"
+	"simple-app/internal/model"
+	"simple-app/internal/pkg/sqldb"
+	"time"
+)
+
+// Start claim the key for a request until the lease ends. When the key is new, the old one is expired, or its request
+// is still in progress after its lease, it is claimed and started is true, otherwise the existing key is returned
+// so the caller can replay or reject the request
+func (i *Idempotency) Start(ctx context.Context, key, requestHash string, ttl, lease time.Duration) (record model.IdempotencyKey, started bool, err error) {
+	now := time.Now()
+	expiresAt := now.Add(ttl)
+	claimedUntil := now.Add(lease)
+
+	query := `
+		INSERT INTO idempotency_key (
+			idempotency_key,
+			request_hash,
+			expires_at,
+			claimed_until
+		) VALUES (
+			?,
+			?,
+			?,
+			?
+		)
+	`
+
+	_, err = i.db.GetMaster().ExecContext(ctx, i.db.Rebind(query), key, requestHash, expiresAt, claimedUntil)
+	if err == nil {
+		started = true
+	} else if !sqldb.IsUniqueViolation(err) {
+		return record, false, fmt.Errorf("failed to start idempotency key: %w", err)
+	}
+
+	// the key exists already, it is only claimed again when it is expired,
+	// or when the process that claimed it is gone without completing or releasing it
+	if !started {
+		query = `
+			UPDATE idempotency_key SET
+				request_hash = ?,
+				status = 'in_progress',
+				response_code = 0,
+				response_body = '',
+				content_type = '',
+				expires_at = ?,
+				claimed_until = ?,
+				created_at = NOW()
+			WHERE idempotency_key = ?
+				AND (
+					expires_at < NOW()
+					OR (status = 'in_progress' AND (claimed_until IS NULL OR claimed_until < NOW()))
+				)
+		`
+
+		result, err := i.db.GetMaster().ExecContext(ctx, i.db.Rebind(query), requestHash, expiresAt, claimedUntil, key)
+		if err != nil {
+			return record, false, fmt.Errorf("failed to start idempotency key: %w", err)
+		}
+
+		claimed, err := result.RowsAffected()
+		if err != nil {
+			return record, false, fmt.Errorf("failed to start idempotency key: %w", err)
+		}
+		started = claimed > 0
+	}
+
+	var getQuery = `SELECT idempotency_key ,request_hash ,status ,response_code ,response_body ,content_type ,expires_at ,claimed_until ,created_at FROM idempotency_key WHERE idempotency_key=?`
+
+	err = i.db.GetMaster().GetContext(ctx, &record, i.db.Rebind(getQuery), key)
+	if err != nil {
+		return record, false, fmt.Errorf("failed to get idempotency key: %w", err)
+	}
+
+	return record, started, nil
+}
+
+// Complete store the response of the request of key
+func (i *Idempotency) Complete(ctx context.Context, record model.IdempotencyKey) (err error) {
+	query := `
+		UPDATE idempotency_key SET
+			status = ?,
+			response_code = ?,
+			response_body = ?,
+			content_type = ?
+		WHERE idempotency_key = ?
+	`
+
+	_, err = i.db.GetMaster().ExecContext(ctx, i.db.Rebind(query),
+		model.IdempotencyCompleted,
+		record.ResponseCode,
+		record.ResponseBody,
+		record.ContentType,
+		record.Key,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to complete idempotency key: %w", err)
+	}
+
+	return nil
+}
+
+// Delete release the key, so the request can be tried again
+func (i *Idempotency) Delete(ctx context.Context, key string) (err error) {
+	_, err = i.db.GetMaster().ExecContext(ctx, i.db.Rebind(`DELETE FROM idempotency_key WHERE idempotency_key = ?`), key)
+	if err != nil {
+		return fmt.Errorf("failed to delete idempotency key: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,100 @@
+package investor
+
+import (
+	"context"
+	"database/sql"
+	"simple-app/internal/model"
+)
+
+// GetByID get investor by ID
+func (i *Investor) GetByID(ctx context.Context, ID int) (investor model.Investor, err error) {
+	var getQuery = `SELECT id ,name ,email ,created_at FROM investor WHERE id=?`
+
+	err = i.db.GetContext(ctx, &investor, i.db.Rebind(getQuery), ID)
+	if err != nil && err != sql.ErrNoRows {
+		return investor, err
+	}
+
+	if err == sql.ErrNoRows {
+		return investor, model.ErrNotFound
+	}
+
+	return investor, nil
+}
+
+// GetWalletByInvestorID get wallet of investor
+func (i *Investor) GetWalletByInvestorID(ctx context.Context, ID int) (wallet model.Wallet, err error) {
+	var getQuery = `SELECT investor_id ,balance ,held ,updated_at FROM investor_wallet WHERE investor_id=?`
+
+	err = i.db.GetContext(ctx, &wallet, i.db.Rebind(getQuery), ID)
+	if err != nil && err != sql.ErrNoRows {
+		return wallet, err
+	}
+
+	if err == sql.ErrNoRows {
+		return wallet, model.ErrNotFound
+	}
+
+	return wallet, nil
+}
+
+// GetWalletForUpdate get wallet of investor and lock the row until the transaction ends
+func (i *Investor) GetWalletForUpdate(ctx context.Context, ID int) (wallet model.Wallet, err error) {
+	querier := i.db.Writer(ctx)
+
+	var getQuery = `SELECT investor_id ,balance ,held ,updated_at FROM investor_wallet WHERE investor_id=? FOR UPDATE`
+
+	err = querier.GetContext(ctx, &wallet, i.db.Rebind(getQuery), ID)
+	if err != nil && err != sql.ErrNoRows {
+		return wallet, err
+	}
+
+	if err == sql.ErrNoRows {
+		return wallet, model.ErrNotFound
+	}
+
+	return wallet, nil
+}
+
+// GetWalletTransactionByInvestorID get the wallet ledger of investor, newest first
+func (i *Investor) GetWalletTransactionByInvestorID(ctx context.Context, ID int) (transactions []model.WalletTransaction, err error) {
+	var getQuery = `SELECT id ,investor_id ,type ,amount ,loan_id ,invest_id ,created_at FROM investor_wallet_transaction WHERE investor_id=? ORDER BY id DESC`
+
+	err = i.db.SelectContext(ctx, &transactions, i.db.Rebind(getQuery), ID)
+	if err != nil && err != sql.ErrNoRows {
+		return transactions, err
+	}
+
+	if err == sql.ErrNoRows {
+		return transactions, model.ErrNotFound
+	}
+
+	return transactions, nil
+}
+
+// GetPositionByInvestorID get the investments of investor that are not withdrawn with the payouts received so far
+func (i *Investor) GetPositionByInvestorID(ctx context.Context, ID int) (positions []model.Position, err error) {
+	var getQuery = `
+		SELECT
+			li.id AS invest_id,
+			li.loan_id,
+			l.status,
+			l.roi,
+			li.amount,
+			COALESCE(SUM(p.principal_amount), 0) AS principal_received,
+			COALESCE(SUM(p.return_amount), 0) AS return_received
+		FROM loan_investment li
+		JOIN loan l ON l.id = li.loan_id
+		LEFT JOIN loan_payout p ON p.invest_id = li.id
+		WHERE li.investor_id=? AND li.withdrawn_at IS NULL
+		GROUP BY li.id, li.loan_id, l.status, l.roi, li.amount
+		ORDER BY li.id
+	`
+
+	err = i.db.SelectContext(ctx, &positions, i.db.Rebind(getQuery), ID)
+	if err != nil && err != sql.ErrNoRows {
+		return positions, err
+	}
+
+	return positions, nil
+}
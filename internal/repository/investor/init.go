@@ -0,0 +1,19 @@
+package investor
+
+import (
+	"simple-app/internal/pkg/sqldb"
+)
+
+type Investor struct {
+	db *sqldb.DB
+}
+
+type Param struct {
+	DB *sqldb.DB
+}
+
+func New(p Param) Investor {
+	return Investor{
+		db: p.DB,
+	}
+}
@@ -0,0 +1,116 @@
+package investor
+
+import (
+	"context"
+	"fmt"
+	"simple-app/internal/model"
+)
+
+// Create create investor with an empty wallet
+func (i *Investor) Create(ctx context.Context, param model.Investor) (data model.Investor, err error) {
+	querier := i.db.Writer(ctx)
+
+	query := `
+		INSERT INTO investor (
+			name,
+			email
+		) VALUES (
+			?,
+			?
+		)
+	`
+
+	id, err := i.db.Insert(ctx, querier, query,
+		param.Name,
+		param.Email,
+	)
+	if err != nil {
+		return data, fmt.Errorf("failed to insert investor: %w", err)
+	}
+
+	var getQuery = `SELECT id ,name ,email ,created_at FROM investor WHERE id=?`
+
+	err = querier.GetContext(ctx, &data, i.db.Rebind(getQuery), id)
+	if err != nil {
+		return data, fmt.Errorf("failed to get inserted investor: %w", err)
+	}
+
+	_, err = querier.ExecContext(ctx, i.db.Rebind(`INSERT INTO investor_wallet (investor_id) VALUES (?)`), data.ID)
+	if err != nil {
+		return data, fmt.Errorf("failed to insert wallet: %w", err)
+	}
+
+	return data, nil
+}
+
+// UpdateWallet update balance and held amount of wallet
+func (i *Investor) UpdateWallet(ctx context.Context, wallet model.Wallet) (data model.Wallet, err error) {
+	querier := i.db.Writer(ctx)
+
+	query := `
+		UPDATE investor_wallet SET
+			balance = ?,
+			held = ?,
+			updated_at = NOW()
+		WHERE investor_id = ?
+	`
+
+	_, err = querier.ExecContext(ctx, i.db.Rebind(query),
+		wallet.Balance,
+		wallet.Held,
+		wallet.InvestorID,
+	)
+	if err != nil {
+		return data, fmt.Errorf("failed to update wallet: %w", err)
+	}
+
+	var getQuery = `SELECT investor_id ,balance ,held ,updated_at FROM investor_wallet WHERE investor_id=?`
+
+	err = querier.GetContext(ctx, &data, i.db.Rebind(getQuery), wallet.InvestorID)
+	if err != nil {
+		return data, fmt.Errorf("failed to get wallet: %w", err)
+	}
+
+	return data, nil
+}
+
+// CreateWalletTransaction insert entry of the wallet ledger
+func (i *Investor) CreateWalletTransaction(ctx context.Context, param model.WalletTransaction) (data model.WalletTransaction, err error) {
+	querier := i.db.Writer(ctx)
+
+	query := `
+		INSERT INTO investor_wallet_transaction (
+			investor_id,
+			type,
+			amount,
+			loan_id,
+			invest_id
+		) VALUES (
+			?,
+			?,
+			?,
+			?,
+			?
+		)
+	`
+
+	id, err := i.db.Insert(ctx, querier, query,
+		param.InvestorID,
+		param.Type,
+		param.Amount,
+		param.LoanID,
+		param.InvestID,
+	)
+	if err != nil {
+		return data, fmt.Errorf("failed to insert wallet transaction: %w", err)
+	}
+
+	var getQuery = `SELECT id ,investor_id ,type ,amount ,loan_id ,invest_id ,created_at FROM investor_wallet_transaction WHERE id=?`
+
+	err = querier.GetContext(ctx, &data, i.db.Rebind(getQuery), id)
+	if err != nil {
+		return data, fmt.Errorf("failed to get wallet transaction: %w", err)
+	}
+
+	return data, nil
+}
@@ -0,0 +1,1154 @@
+package loan_test
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"simple-app/internal/model"
+	"simple-app/internal/pkg/money"
+	"simple-app/internal/pkg/sqldb"
+	"simple-app/internal/repository/loan"
+	"simple-app/internal/repository/loan/memory"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/require"
+)
+
+// repository is the loan repository the contract is written for, the methods used by the loan usecase
+type repository interface {
+	GetByID(ctx context.Context, ID int) (loan model.Loan, err error)
+	GetByIDForUpdate(ctx context.Context, ID int) (loan model.Loan, err error)
+	GetInvestByID(ctx context.Context, ID int) (invests []model.Invest, err error)
+	GetInvestByIDTx(ctx context.Context, ID int) (invests []model.Invest, err error)
+	GetDisburseByID(ctx context.Context, ID int) (disburses []model.Disburse, err error)
+	GetInstallmentByID(ctx context.Context, ID int) (installments []model.Installment, err error)
+	GetRepaymentByID(ctx context.Context, ID int) (repayments []model.Repayment, err error)
+	GetList(ctx context.Context, param model.LoanListParam) (page model.LoanPage, err error)
+	GetOverdueFunding(ctx context.Context, before time.Time) (loans []model.Loan, err error)
+	GetOutstandingPrincipalByBorrower(ctx context.Context, borrowerID int) (outstanding money.Money, err error)
+	GetEventByID(ctx context.Context, ID int) (events []model.LoanEvent, err error)
+
+	Create(ctx context.Context, param model.Loan) (data model.Loan, err error)
+	Approve(ctx context.Context, param model.Approve) (id int, err error)
+	Invest(ctx context.Context, param model.Invest) (data model.Invest, err error)
+	Withdraw(ctx context.Context, param model.Withdraw) (data model.Withdraw, err error)
+	ReleaseInvestments(ctx context.Context, loanID int, reason string) (released []model.Withdraw, err error)
+	Disburse(ctx context.Context, param model.Disburse) (data model.Disburse, err error)
+	CreateInstallments(ctx context.Context, installments []model.Installment) (err error)
+	Repay(ctx context.Context, param model.Repayment) (data model.Repayment, err error)
+
+	UpdateStatus(ctx context.Context, status model.Loan) (id int, err error)
+	UpdateStatusWithReason(ctx context.Context, status model.Loan, reason string) (id int, err error)
+	BumpVersion(ctx context.Context, id, version int) (err error)
+
+	CreateSignature(ctx context.Context, param model.Signature) (data model.Signature, err error)
+	GetSignatureByTokenForUpdate(ctx context.Context, tokenHash string) (signature model.Signature, err error)
+	GetSignedSignature(ctx context.Context, loanID int, sha256, version string) (signature model.Signature, err error)
+	Sign(ctx context.Context, param model.Signature) (data model.Signature, err error)
+}
+
+type txManager interface {
+	WithTx(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+var loanColumns = []string{
+	"id", "borrower_id", "principal_amount", "rate", "roi", "tenor", "repayment_method", "status",
+	"agreement_letter_key", "picture_proof_key", "approver_id", "approval_date", "funding_deadline",
+	"agreement_letter_sha256", "agreement_letter_version", "version",
+}
+
+// loanRows is the result of a loan query returning the given loans
+func loanRows(loans ...model.Loan) *sqlmock.Rows {
+	rows := sqlmock.NewRows(loanColumns)
+	for _, l := range loans {
+		rows.AddRow(
+			l.ID, l.BorrowerID, l.PrincipalAmount.String(), l.Rate, l.Roi, l.Tenor, string(l.RepaymentMethod), int64(l.Status),
+			l.AgreementLetterKey, nullString(l.PictureProofKey), nil, nil, nil,
+			l.AgreementLetterSHA256, l.AgreementLetterVersion, l.Version,
+		)
+	}
+	return rows
+}
+
+func investRows(invests ...model.Invest) *sqlmock.Rows {
+	rows := sqlmock.NewRows([]string{"id", "loan_id", "investor_id", "amount"})
+	for _, i := range invests {
+		rows.AddRow(i.ID, i.LoanID, i.InvestorID, i.Amount.String())
+	}
+	return rows
+}
+
+func withdrawRows(withdraws ...model.Withdraw) *sqlmock.Rows {
+	rows := sqlmock.NewRows([]string{"id", "loan_id", "investor_id", "amount", "withdrawal_reason", "withdrawn_at"})
+	for _, w := range withdraws {
+		rows.AddRow(w.ID, w.LoanID, w.InvestorID, w.Amount.String(), w.Reason, *w.WithdrawnAt)
+	}
+	return rows
+}
+
+func disburseRows(disburses ...model.Disburse) *sqlmock.Rows {
+	rows := sqlmock.NewRows([]string{"id", "loan_id", "signed_agreement_key", "disburser_employee_id", "disbursement_date"})
+	for _, d := range disburses {
+		rows.AddRow(d.ID, d.LoanID, d.SignedAgreementKey, d.DisburseEmployeeID, *d.DisbursementDate)
+	}
+	return rows
+}
+
+func installmentRows(installments ...model.Installment) *sqlmock.Rows {
+	rows := sqlmock.NewRows([]string{
+		"id", "loan_id", "installment_number", "due_date", "principal_amount", "interest_amount", "total_amount", "outstanding_balance",
+	})
+	for _, i := range installments {
+		rows.AddRow(i.ID, i.LoanID, i.Number, i.DueDate,
+			i.PrincipalAmount.String(), i.InterestAmount.String(), i.TotalAmount.String(), i.OutstandingBalance.String())
+	}
+	return rows
+}
+
+func repaymentRows(repayments ...model.Repayment) *sqlmock.Rows {
+	rows := sqlmock.NewRows([]string{"id", "loan_id", "amount", "principal_amount", "interest_amount", "payment_date"})
+	for _, r := range repayments {
+		rows.AddRow(r.ID, r.LoanID, r.Amount.String(), r.PrincipalAmount.String(), r.InterestAmount.String(), *r.PaymentDate)
+	}
+	return rows
+}
+
+func signatureRows(signatures ...model.Signature) *sqlmock.Rows {
+	rows := sqlmock.NewRows([]string{
+		"id", "loan_id", "borrower_id", "token_hash", "agreement_letter_sha256", "agreement_letter_version",
+		"signer_name", "signer_ip", "signed_document_sha256", "signed_document_key", "expires_at", "signed_at", "created_at",
+	})
+	for _, s := range signatures {
+		var signedAt interface{}
+		if s.SignedAt != nil {
+			signedAt = *s.SignedAt
+		}
+		rows.AddRow(s.ID, s.LoanID, s.BorrowerID, s.TokenHash, s.AgreementLetterSHA256, s.AgreementLetterVersion,
+			s.SignerName, s.SignerIP, s.SignedDocumentSHA256, s.SignedDocumentKey, *s.ExpiresAt, signedAt, time.Now())
+	}
+	return rows
+}
+
+func eventRows(events ...model.LoanEvent) *sqlmock.Rows {
+	rows := sqlmock.NewRows([]string{"id", "loan_id", "event", "actor", "from_status", "to_status", "payload", "request_id", "created_at"})
+	status := func(s *model.LoanStatus) interface{} {
+		if s == nil {
+			return nil
+		}
+		return int64(*s)
+	}
+	for _, e := range events {
+		rows.AddRow(e.ID, e.LoanID, e.Event, "", status(e.FromStatus), status(e.ToStatus), []byte("{}"), "", time.Now())
+	}
+	return rows
+}
+
+func countRows(total int) *sqlmock.Rows {
+	return sqlmock.NewRows([]string{"count"}).AddRow(total)
+}
+
+// record is the part of a row the contract compares, the times set by the repository itself are only compared by presence
+type record map[string]interface{}
+
+// nullString is the column value of a nullable string
+func nullString(s *string) interface{} {
+	if s == nil {
+		return nil
+	}
+	return *s
+}
+
+func loanRecords(loans ...model.Loan) (records []record) {
+	for _, l := range loans {
+		records = append(records, record{
+			"id":               l.ID,
+			"borrower_id":      l.BorrowerID,
+			"principal_amount": l.PrincipalAmount.String(),
+			"status":           l.Status,
+			"version":          l.Version,
+			"picture_proof":    l.PictureProofKey,
+		})
+	}
+	return records
+}
+
+func investRecords(invests ...model.Invest) (records []record) {
+	for _, i := range invests {
+		records = append(records, record{
+			"id":          i.ID,
+			"loan_id":     i.LoanID,
+			"investor_id": i.InvestorID,
+			"amount":      i.Amount.String(),
+		})
+	}
+	return records
+}
+
+func withdrawRecords(withdraws ...model.Withdraw) (records []record) {
+	for _, w := range withdraws {
+		records = append(records, record{
+			"id":          w.ID,
+			"loan_id":     w.LoanID,
+			"investor_id": w.InvestorID,
+			"amount":      w.Amount.String(),
+			"reason":      w.Reason,
+			"withdrawn":   w.WithdrawnAt != nil,
+		})
+	}
+	return records
+}
+
+func disburseRecords(disburses ...model.Disburse) (records []record) {
+	for _, d := range disburses {
+		records = append(records, record{
+			"id":                    d.ID,
+			"loan_id":               d.LoanID,
+			"signed_agreement_key":  d.SignedAgreementKey,
+			"disburser_employee_id": d.DisburseEmployeeID,
+			"disbursement_date":     d.DisbursementDate.Format(time.DateOnly),
+		})
+	}
+	return records
+}
+
+func installmentRecords(installments ...model.Installment) (records []record) {
+	for _, i := range installments {
+		records = append(records, record{
+			"id":                  i.ID,
+			"loan_id":             i.LoanID,
+			"number":              i.Number,
+			"due_date":            i.DueDate.Format(time.DateOnly),
+			"principal_amount":    i.PrincipalAmount.String(),
+			"interest_amount":     i.InterestAmount.String(),
+			"total_amount":        i.TotalAmount.String(),
+			"outstanding_balance": i.OutstandingBalance.String(),
+		})
+	}
+	return records
+}
+
+func repaymentRecords(repayments ...model.Repayment) (records []record) {
+	for _, r := range repayments {
+		records = append(records, record{
+			"id":               r.ID,
+			"loan_id":          r.LoanID,
+			"amount":           r.Amount.String(),
+			"principal_amount": r.PrincipalAmount.String(),
+			"interest_amount":  r.InterestAmount.String(),
+		})
+	}
+	return records
+}
+
+func signatureRecords(signatures ...model.Signature) (records []record) {
+	for _, s := range signatures {
+		records = append(records, record{
+			"id":                       s.ID,
+			"loan_id":                  s.LoanID,
+			"borrower_id":              s.BorrowerID,
+			"token_hash":               s.TokenHash,
+			"agreement_letter_sha256":  s.AgreementLetterSHA256,
+			"agreement_letter_version": s.AgreementLetterVersion,
+			"signer_name":              s.SignerName,
+			"signer_ip":                s.SignerIP,
+			"signed_document_sha256":   s.SignedDocumentSHA256,
+			"signed_document_key":      s.SignedDocumentKey,
+			"signed":                   s.SignedAt != nil,
+		})
+	}
+	return records
+}
+
+func eventRecords(events ...model.LoanEvent) (records []record) {
+	for _, e := range events {
+		records = append(records, record{
+			"id":      e.ID,
+			"loan_id": e.LoanID,
+			"event":   e.Event,
+		})
+	}
+	return records
+}
+
+func pageRecord(page model.LoanPage) record {
+	return record{
+		"total": page.Total,
+		"data":  loanRecords(page.Data...),
+		"more":  page.NextCursor != "",
+	}
+}
+
+// script expects the queries of a case in the dialect of driver, queries are written with ? placeholders
+type script struct {
+	sqlmock.Sqlmock
+	driver string
+}
+
+func (s script) query(q string) string {
+	return regexp.QuoteMeta(sqlx.Rebind(sqlx.BindType(s.driver), q))
+}
+
+// ExpectInsert expects an insert of a row getting id, read with RETURNING on postgres and LAST_INSERT_ID() on mysql
+func (s script) ExpectInsert(q string, id int) {
+	if s.driver == "mysql" {
+		s.ExpectExec(s.query(q)).WillReturnResult(sqlmock.NewResult(int64(id), 1))
+		return
+	}
+	s.ExpectQuery(s.query(q)).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(id))
+}
+
+// ExpectLock expects the loan to be locked at version 3 before a versioned update, no status means the loan is missing
+func (s script) ExpectLock(id int, status ...model.LoanStatus) {
+	rows := sqlmock.NewRows([]string{"status", "version"})
+	for _, st := range status {
+		rows.AddRow(int64(st), 3)
+	}
+	s.ExpectQuery(s.query("SELECT status ,version FROM loan WHERE id=? FOR UPDATE")).WithArgs(id).WillReturnRows(rows)
+}
+
+// ExpectEvent expects the loan event recorded by a mutation
+func (s script) ExpectEvent(loanID int, event string) {
+	s.ExpectExec(s.query("INSERT INTO loan_event")).
+		WithArgs(loanID, event, sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+}
+
+// ExpectInvests expects the investments of invests to be inserted with IDs from 1
+func (s script) ExpectInvests(invests ...model.Invest) {
+	for _, i := range invests {
+		s.ExpectInsert("INSERT INTO loan_investment (", i.ID)
+		s.ExpectEvent(i.LoanID, model.EventInvested)
+	}
+}
+
+// ExpectSignature expects the signature to be created with ID 1
+func (s script) ExpectSignature(signature model.Signature) {
+	s.ExpectInsert("INSERT INTO loan_agreement_signature (", signature.ID)
+	s.ExpectQuery(s.query("FROM loan_agreement_signature WHERE id=?")).WithArgs(signature.ID).WillReturnRows(signatureRows(signature))
+	s.ExpectEvent(signature.LoanID, model.EventSignatureRequested)
+}
+
+// ExpectSign expects the signature to be signed, signed false means it is already signed
+func (s script) ExpectSign(signature model.Signature, signed bool) {
+	exec := s.ExpectExec(s.query("UPDATE loan_agreement_signature SET")).
+		WithArgs(signature.SignerName, signature.SignerIP, signature.SignedDocumentSHA256, signature.SignedDocumentKey, sqlmock.AnyArg(), signature.ID)
+	if !signed {
+		exec.WillReturnResult(sqlmock.NewResult(0, 0))
+		return
+	}
+
+	exec.WillReturnResult(sqlmock.NewResult(0, 1))
+	s.ExpectQuery(s.query("FROM loan_agreement_signature WHERE id=?")).WithArgs(signature.ID).WillReturnRows(signatureRows(signature))
+	s.ExpectEvent(signature.LoanID, model.EventSigned)
+}
+
+// invest invests invests in order, the repository gives them IDs from 1
+func invest(ctx context.Context, r repository, invests ...model.Invest) error {
+	for _, i := range invests {
+		i.ID = 0
+		_, err := r.Invest(ctx, i)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+var (
+	created = model.Loan{
+		BorrowerID:      1,
+		PrincipalAmount: money.MustParse("1000"),
+		Rate:            0.1,
+		Tenor:           12,
+		RepaymentMethod: model.ANNUITY,
+		// status and version are chosen by the repository
+		Status:  model.APPROVED,
+		Version: 5,
+	}
+	stored   = model.Loan{ID: 1, BorrowerID: 1, PrincipalAmount: money.MustParse("1000"), Rate: 0.1, Tenor: 12, RepaymentMethod: model.ANNUITY, Status: model.PROPOSED, Version: 1}
+	proposed = model.Loan{ID: 1, BorrowerID: 1, PrincipalAmount: money.MustParse("1000"), Status: model.PROPOSED, Version: 3}
+	approved = model.Loan{ID: 1, BorrowerID: 1, PrincipalAmount: money.MustParse("1000"), Status: model.APPROVED, Version: 4}
+	rejected = model.Loan{ID: 1, BorrowerID: 1, PrincipalAmount: money.MustParse("1000"), Status: model.REJECTED, Version: 4}
+
+	now = time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+
+	invest1 = model.Invest{ID: 1, LoanID: 1, InvestorID: 7, Amount: money.MustParse("100")}
+	invest2 = model.Invest{ID: 2, LoanID: 1, InvestorID: 8, Amount: money.MustParse("250")}
+
+	disbursed = model.Disburse{ID: 1, LoanID: 1, SignedAgreementKey: "signed-1", DisburseEmployeeID: 3, DisbursementDate: &now}
+
+	repayment = model.Repayment{
+		ID:              1,
+		LoanID:          1,
+		Amount:          money.MustParse("110"),
+		PrincipalAmount: money.MustParse("100"),
+		InterestAmount:  money.MustParse("10"),
+		PaymentDate:     &now,
+	}
+
+	signature = model.Signature{
+		ID:                     1,
+		LoanID:                 1,
+		BorrowerID:             1,
+		TokenHash:              "token-hash",
+		AgreementLetterSHA256:  "letter-sha",
+		AgreementLetterVersion: "v1",
+		ExpiresAt:              &now,
+	}
+	signed = model.Signature{
+		ID:                     1,
+		LoanID:                 1,
+		BorrowerID:             1,
+		TokenHash:              "token-hash",
+		AgreementLetterSHA256:  "letter-sha",
+		AgreementLetterVersion: "v1",
+		SignerName:             "Budi",
+		SignerIP:               "10.0.0.1",
+		SignedDocumentSHA256:   "document-sha",
+		SignedDocumentKey:      "signed-1",
+		ExpiresAt:              &now,
+		SignedAt:               &now,
+	}
+
+	errFailed = errors.New("failed")
+)
+
+// TestContract runs the same cases against every implementation of the loan repository,
+// the SQL repository is run on every driver with the queries it must send scripted with sqlmock
+func TestContract(t *testing.T) {
+	// an approved loan keeps the key of its picture proof
+	proofKey := "uploads/picture-proofs/proof.jpg"
+	proved := approved
+	proved.PictureProofKey = &proofKey
+
+	withdrawn := model.Withdraw{ID: 1, LoanID: 1, InvestorID: 7, Amount: invest1.Amount, Reason: "changed mind", WithdrawnAt: &now}
+	released := []model.Withdraw{
+		{ID: 1, LoanID: 1, InvestorID: 7, Amount: invest1.Amount, Reason: "expired", WithdrawnAt: &now},
+		{ID: 2, LoanID: 1, InvestorID: 8, Amount: invest2.Amount, Reason: "expired", WithdrawnAt: &now},
+	}
+
+	// the schedule is inserted last installment first, it is still listed by number
+	schedule := []model.Installment{
+		{ID: 1, LoanID: 1, Number: 2, DueDate: now.AddDate(0, 2, 0), PrincipalAmount: money.MustParse("500"), InterestAmount: money.MustParse("10"), TotalAmount: money.MustParse("510"), OutstandingBalance: money.MustParse("0")},
+		{ID: 2, LoanID: 1, Number: 1, DueDate: now.AddDate(0, 1, 0), PrincipalAmount: money.MustParse("500"), InterestAmount: money.MustParse("20"), TotalAmount: money.MustParse("520"), OutstandingBalance: money.MustParse("500")},
+	}
+
+	deadline := func(d time.Duration) *time.Time {
+		at := now.Add(d)
+		return &at
+	}
+	overdue := []model.Loan{
+		{ID: 1, BorrowerID: 1, PrincipalAmount: money.MustParse("1000"), Status: model.APPROVED, FundingDeadline: deadline(-time.Hour), Version: 1},
+		{ID: 2, BorrowerID: 1, PrincipalAmount: money.MustParse("2000"), Status: model.APPROVED, FundingDeadline: deadline(-2 * time.Hour), Version: 1},
+		{ID: 3, BorrowerID: 1, PrincipalAmount: money.MustParse("3000"), Status: model.APPROVED, FundingDeadline: deadline(time.Hour), Version: 1},
+		{ID: 4, BorrowerID: 1, PrincipalAmount: money.MustParse("4000"), Status: model.PROPOSED, FundingDeadline: deadline(-3 * time.Hour), Version: 1},
+	}
+
+	borrowed := []model.Loan{
+		{ID: 1, BorrowerID: 1, PrincipalAmount: money.MustParse("1000"), Status: model.DISBURSED, Version: 1},
+		{ID: 2, BorrowerID: 1, PrincipalAmount: money.MustParse("500"), Status: model.REPAID, Version: 1},
+		{ID: 3, BorrowerID: 2, PrincipalAmount: money.MustParse("700"), Status: model.DISBURSED, Version: 1},
+	}
+
+	listed := []model.Loan{
+		{ID: 1, BorrowerID: 1, PrincipalAmount: money.MustParse("1000"), Status: model.PROPOSED, Version: 1},
+		{ID: 2, BorrowerID: 1, PrincipalAmount: money.MustParse("2000"), Status: model.APPROVED, Version: 1},
+		{ID: 3, BorrowerID: 2, PrincipalAmount: money.MustParse("3000"), Status: model.APPROVED, Version: 1},
+	}
+
+	testCases := []struct {
+		name string
+		// seed is the content of the in-memory repository before run
+		seed []model.Loan
+		// mock scripts the database of the SQL repository
+		mock    func(s script)
+		run     func(ctx context.Context, r repository, tx txManager) (interface{}, error)
+		wantErr error
+		want    interface{}
+	}{
+		{
+			name: "create defaults to proposed and version 1",
+			mock: func(s script) {
+				s.ExpectInsert("INSERT INTO loan (", 1)
+				s.ExpectQuery(s.query("FROM loan WHERE id=?")).WithArgs(1).WillReturnRows(loanRows(stored))
+				s.ExpectEvent(1, model.EventCreated)
+				s.ExpectQuery(s.query("FROM loan WHERE id=?")).WithArgs(1).WillReturnRows(loanRows(stored))
+			},
+			run: func(ctx context.Context, r repository, tx txManager) (interface{}, error) {
+				data, err := r.Create(ctx, created)
+				if err != nil {
+					return nil, err
+				}
+				loan, err := r.GetByID(ctx, data.ID)
+				return loanRecords(loan), err
+			},
+			want: loanRecords(stored),
+		},
+		{
+			name: "get missing loan is not found",
+			mock: func(s script) {
+				s.ExpectQuery(s.query("FROM loan WHERE id=?")).WithArgs(9).WillReturnRows(loanRows())
+			},
+			run: func(ctx context.Context, r repository, tx txManager) (interface{}, error) {
+				return r.GetByID(ctx, 9)
+			},
+			wantErr: model.ErrNotFound,
+		},
+		{
+			name: "get for update returns the loan",
+			seed: []model.Loan{proposed},
+			mock: func(s script) {
+				s.ExpectQuery(s.query("FROM loan WHERE id=? FOR UPDATE")).WithArgs(1).WillReturnRows(loanRows(proposed))
+			},
+			run: func(ctx context.Context, r repository, tx txManager) (interface{}, error) {
+				loan, err := r.GetByIDForUpdate(ctx, 1)
+				return loanRecords(loan), err
+			},
+			want: loanRecords(proposed),
+		},
+		{
+			name: "get for update missing loan is not found",
+			mock: func(s script) {
+				s.ExpectQuery(s.query("FROM loan WHERE id=? FOR UPDATE")).WithArgs(9).WillReturnRows(loanRows())
+			},
+			run: func(ctx context.Context, r repository, tx txManager) (interface{}, error) {
+				return r.GetByIDForUpdate(ctx, 9)
+			},
+			wantErr: model.ErrNotFound,
+		},
+		{
+			name: "approve bumps the version",
+			seed: []model.Loan{proposed},
+			mock: func(s script) {
+				s.ExpectLock(1, model.PROPOSED)
+				s.ExpectExec(s.query("UPDATE loan SET")).WillReturnResult(sqlmock.NewResult(0, 1))
+				s.ExpectEvent(1, model.EventApproved)
+				s.ExpectQuery(s.query("FROM loan WHERE id=?")).WithArgs(1).WillReturnRows(loanRows(proved))
+			},
+			run: func(ctx context.Context, r repository, tx txManager) (interface{}, error) {
+				_, err := r.Approve(ctx, model.Approve{ID: 1, PictureProofKey: &proofKey, ApproverID: 2, ApprovalDate: &now, Status: model.APPROVED, Version: 3})
+				if err != nil {
+					return nil, err
+				}
+				loan, err := r.GetByID(ctx, 1)
+				return loanRecords(loan), err
+			},
+			want: loanRecords(proved),
+		},
+		{
+			name: "approve with stale version conflicts",
+			seed: []model.Loan{proposed},
+			mock: func(s script) {
+				s.ExpectLock(1, model.PROPOSED)
+			},
+			run: func(ctx context.Context, r repository, tx txManager) (interface{}, error) {
+				return r.Approve(ctx, model.Approve{ID: 1, PictureProofKey: &proofKey, ApproverID: 2, Status: model.APPROVED, Version: 2})
+			},
+			wantErr: model.ErrVersionConflict,
+		},
+		{
+			name: "update status bumps the version",
+			seed: []model.Loan{proposed},
+			mock: func(s script) {
+				s.ExpectLock(1, model.PROPOSED)
+				s.ExpectExec(s.query("UPDATE loan SET")).WillReturnResult(sqlmock.NewResult(0, 1))
+				s.ExpectEvent(1, model.EventStatusChanged)
+				s.ExpectQuery(s.query("FROM loan WHERE id=?")).WithArgs(1).WillReturnRows(loanRows(rejected))
+			},
+			run: func(ctx context.Context, r repository, tx txManager) (interface{}, error) {
+				status := proposed
+				status.Status = model.REJECTED
+				_, err := r.UpdateStatusWithReason(ctx, status, "duplicate")
+				if err != nil {
+					return nil, err
+				}
+				loan, err := r.GetByID(ctx, 1)
+				return loanRecords(loan), err
+			},
+			want: loanRecords(rejected),
+		},
+		{
+			name: "update status without reason bumps the version",
+			seed: []model.Loan{proposed},
+			mock: func(s script) {
+				s.ExpectLock(1, model.PROPOSED)
+				s.ExpectExec(s.query("UPDATE loan SET")).WillReturnResult(sqlmock.NewResult(0, 1))
+				s.ExpectEvent(1, model.EventStatusChanged)
+				s.ExpectQuery(s.query("FROM loan WHERE id=?")).WithArgs(1).WillReturnRows(loanRows(rejected))
+			},
+			run: func(ctx context.Context, r repository, tx txManager) (interface{}, error) {
+				status := proposed
+				status.Status = model.REJECTED
+				_, err := r.UpdateStatus(ctx, status)
+				if err != nil {
+					return nil, err
+				}
+				loan, err := r.GetByID(ctx, 1)
+				return loanRecords(loan), err
+			},
+			want: loanRecords(rejected),
+		},
+		{
+			name: "update status with stale version conflicts",
+			seed: []model.Loan{proposed},
+			mock: func(s script) {
+				s.ExpectLock(1, model.PROPOSED)
+			},
+			run: func(ctx context.Context, r repository, tx txManager) (interface{}, error) {
+				status := proposed
+				status.Status = model.REJECTED
+				status.Version = 2
+				_, err := r.UpdateStatusWithReason(ctx, status, "duplicate")
+
+				var conflict *model.VersionConflictError
+				if errors.As(err, &conflict) && conflict.Actual != 3 {
+					return nil, errors.New("wrong actual version")
+				}
+				return nil, err
+			},
+			wantErr: model.ErrVersionConflict,
+		},
+		{
+			name: "update status of missing loan is not found",
+			mock: func(s script) {
+				s.ExpectLock(9)
+			},
+			run: func(ctx context.Context, r repository, tx txManager) (interface{}, error) {
+				return r.UpdateStatusWithReason(ctx, model.Loan{ID: 9, Status: model.REJECTED, Version: 1}, "")
+			},
+			wantErr: model.ErrNotFound,
+		},
+		{
+			name: "bump version",
+			seed: []model.Loan{proposed},
+			mock: func(s script) {
+				s.ExpectLock(1, model.PROPOSED)
+				s.ExpectExec(s.query("UPDATE loan SET")).WithArgs(1, 3).WillReturnResult(sqlmock.NewResult(0, 1))
+				s.ExpectQuery(s.query("FROM loan WHERE id=?")).WithArgs(1).WillReturnRows(loanRows(model.Loan{ID: 1, BorrowerID: 1, PrincipalAmount: proposed.PrincipalAmount, Status: model.PROPOSED, Version: 4}))
+			},
+			run: func(ctx context.Context, r repository, tx txManager) (interface{}, error) {
+				err := r.BumpVersion(ctx, 1, 3)
+				if err != nil {
+					return nil, err
+				}
+				loan, err := r.GetByID(ctx, 1)
+				return loanRecords(loan), err
+			},
+			want: loanRecords(model.Loan{ID: 1, BorrowerID: 1, PrincipalAmount: proposed.PrincipalAmount, Status: model.PROPOSED, Version: 4}),
+		},
+		{
+			name: "bump version with stale version conflicts",
+			seed: []model.Loan{proposed},
+			mock: func(s script) {
+				s.ExpectLock(1, model.PROPOSED)
+			},
+			run: func(ctx context.Context, r repository, tx txManager) (interface{}, error) {
+				return nil, r.BumpVersion(ctx, 1, 2)
+			},
+			wantErr: model.ErrVersionConflict,
+		},
+		{
+			name: "bump version of missing loan is not found",
+			mock: func(s script) {
+				s.ExpectLock(9)
+			},
+			run: func(ctx context.Context, r repository, tx txManager) (interface{}, error) {
+				return nil, r.BumpVersion(ctx, 9, 1)
+			},
+			wantErr: model.ErrNotFound,
+		},
+		{
+			name: "investments are listed in order",
+			seed: []model.Loan{approved},
+			mock: func(s script) {
+				s.ExpectInvests(invest1, invest2)
+				s.ExpectQuery(s.query("FROM loan_investment WHERE loan_id=? AND withdrawn_at IS NULL ORDER BY id")).WithArgs(1).
+					WillReturnRows(investRows(invest1, invest2))
+			},
+			run: func(ctx context.Context, r repository, tx txManager) (interface{}, error) {
+				err := invest(ctx, r, invest1, invest2)
+				if err != nil {
+					return nil, err
+				}
+				invests, err := r.GetInvestByID(ctx, 1)
+				return investRecords(invests...), err
+			},
+			want: investRecords(invest1, invest2),
+		},
+		{
+			name: "withdraw takes the investment out of the loan",
+			seed: []model.Loan{approved},
+			mock: func(s script) {
+				s.ExpectInvests(invest1, invest2)
+				s.ExpectExec(s.query("UPDATE loan_investment SET")).WithArgs("changed mind", 1, 1, 7).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				s.ExpectQuery(s.query("FROM loan_investment WHERE id=?")).WithArgs(1).WillReturnRows(withdrawRows(withdrawn))
+				s.ExpectEvent(1, model.EventWithdrawn)
+				s.ExpectQuery(s.query("FROM loan_investment WHERE loan_id=? AND withdrawn_at IS NULL ORDER BY id")).WithArgs(1).
+					WillReturnRows(investRows(invest2))
+			},
+			run: func(ctx context.Context, r repository, tx txManager) (interface{}, error) {
+				err := invest(ctx, r, invest1, invest2)
+				if err != nil {
+					return nil, err
+				}
+				data, err := r.Withdraw(ctx, model.Withdraw{ID: 1, LoanID: 1, InvestorID: 7, Reason: "changed mind"})
+				if err != nil {
+					return nil, err
+				}
+				invests, err := r.GetInvestByIDTx(ctx, 1)
+				return []interface{}{withdrawRecords(data), investRecords(invests...)}, err
+			},
+			want: []interface{}{withdrawRecords(withdrawn), investRecords(invest2)},
+		},
+		{
+			name: "withdraw unknown investment is not found",
+			seed: []model.Loan{proposed},
+			mock: func(s script) {
+				s.ExpectExec(s.query("UPDATE loan_investment SET")).WithArgs("changed mind", 7, 1, 2).
+					WillReturnResult(sqlmock.NewResult(0, 0))
+			},
+			run: func(ctx context.Context, r repository, tx txManager) (interface{}, error) {
+				return r.Withdraw(ctx, model.Withdraw{ID: 7, LoanID: 1, InvestorID: 2, Reason: "changed mind"})
+			},
+			wantErr: model.ErrNotFound,
+		},
+		{
+			name: "release withdraws every open investment",
+			seed: []model.Loan{approved},
+			mock: func(s script) {
+				s.ExpectInvests(invest1, invest2)
+				s.ExpectQuery(s.query("FROM loan_investment WHERE loan_id=? AND withdrawn_at IS NULL ORDER BY id FOR UPDATE")).WithArgs(1).
+					WillReturnRows(investRows(invest1, invest2))
+				s.ExpectExec(s.query("UPDATE loan_investment SET")).WithArgs(sqlmock.AnyArg(), "expired", 1, 2).
+					WillReturnResult(sqlmock.NewResult(0, 2))
+				s.ExpectEvent(1, model.EventReleased)
+				s.ExpectQuery(s.query("FROM loan_investment WHERE loan_id=? AND withdrawn_at IS NULL ORDER BY id")).WithArgs(1).
+					WillReturnRows(investRows())
+			},
+			run: func(ctx context.Context, r repository, tx txManager) (interface{}, error) {
+				err := invest(ctx, r, invest1, invest2)
+				if err != nil {
+					return nil, err
+				}
+				data, err := r.ReleaseInvestments(ctx, 1, "expired")
+				if err != nil {
+					return nil, err
+				}
+				invests, err := r.GetInvestByID(ctx, 1)
+				return []interface{}{withdrawRecords(data...), investRecords(invests...)}, err
+			},
+			want: []interface{}{withdrawRecords(released...), investRecords()},
+		},
+		{
+			name: "release without investments records no event",
+			seed: []model.Loan{approved},
+			mock: func(s script) {
+				s.ExpectQuery(s.query("FROM loan_investment WHERE loan_id=? AND withdrawn_at IS NULL ORDER BY id FOR UPDATE")).WithArgs(1).
+					WillReturnRows(investRows())
+				s.ExpectQuery(s.query("FROM loan_event WHERE loan_id=?")).WithArgs(1).WillReturnRows(eventRows())
+			},
+			run: func(ctx context.Context, r repository, tx txManager) (interface{}, error) {
+				data, err := r.ReleaseInvestments(ctx, 1, "expired")
+				if err != nil {
+					return nil, err
+				}
+				events, err := r.GetEventByID(ctx, 1)
+				return []interface{}{withdrawRecords(data...), eventRecords(events...)}, err
+			},
+			want: []interface{}{withdrawRecords(), eventRecords()},
+		},
+		{
+			name: "disbursement is listed with the loan",
+			seed: []model.Loan{approved},
+			mock: func(s script) {
+				s.ExpectInsert("INSERT INTO loan_disbursement (", 1)
+				s.ExpectQuery(s.query("FROM loan_disbursement WHERE id=?")).WithArgs(1).WillReturnRows(disburseRows(disbursed))
+				s.ExpectEvent(1, model.EventDisbursed)
+				s.ExpectQuery(s.query("FROM loan_disbursement WHERE loan_id=?")).WithArgs(1).WillReturnRows(disburseRows(disbursed))
+			},
+			run: func(ctx context.Context, r repository, tx txManager) (interface{}, error) {
+				param := disbursed
+				param.ID = 0
+				data, err := r.Disburse(ctx, param)
+				if err != nil {
+					return nil, err
+				}
+				disburses, err := r.GetDisburseByID(ctx, 1)
+				return []interface{}{disburseRecords(data), disburseRecords(disburses...)}, err
+			},
+			want: []interface{}{disburseRecords(disbursed), disburseRecords(disbursed)},
+		},
+		{
+			name: "installments are listed by number",
+			seed: []model.Loan{approved},
+			mock: func(s script) {
+				s.ExpectExec(s.query("INSERT INTO loan_installment (")).WillReturnResult(sqlmock.NewResult(2, 2))
+				s.ExpectEvent(1, model.EventScheduleGenerated)
+				s.ExpectQuery(s.query("FROM loan_installment WHERE loan_id=? ORDER BY installment_number")).WithArgs(1).
+					WillReturnRows(installmentRows(schedule[1], schedule[0]))
+			},
+			run: func(ctx context.Context, r repository, tx txManager) (interface{}, error) {
+				err := r.CreateInstallments(ctx, schedule)
+				if err != nil {
+					return nil, err
+				}
+				installments, err := r.GetInstallmentByID(ctx, 1)
+				return installmentRecords(installments...), err
+			},
+			want: installmentRecords(schedule[1], schedule[0]),
+		},
+		{
+			name: "create no installments records no event",
+			seed: []model.Loan{approved},
+			mock: func(s script) {
+				s.ExpectQuery(s.query("FROM loan_event WHERE loan_id=?")).WithArgs(1).WillReturnRows(eventRows())
+			},
+			run: func(ctx context.Context, r repository, tx txManager) (interface{}, error) {
+				err := r.CreateInstallments(ctx, nil)
+				if err != nil {
+					return nil, err
+				}
+				events, err := r.GetEventByID(ctx, 1)
+				return eventRecords(events...), err
+			},
+			want: eventRecords(),
+		},
+		{
+			name: "repayment lowers the outstanding principal of borrower",
+			seed: borrowed,
+			mock: func(s script) {
+				s.ExpectInsert("INSERT INTO loan_repayment (", 1)
+				s.ExpectQuery(s.query("FROM loan_repayment WHERE id=?")).WithArgs(1).WillReturnRows(repaymentRows(repayment))
+				s.ExpectEvent(1, model.EventRepaid)
+				s.ExpectQuery(s.query("FROM loan_repayment WHERE loan_id=? ORDER BY id")).WithArgs(1).WillReturnRows(repaymentRows(repayment))
+				s.ExpectQuery(s.query("WHERE l.borrower_id=? AND l.status IN (?, ?, ?, ?)")).
+					WithArgs(1, model.PROPOSED, model.APPROVED, model.INVESTED, model.DISBURSED).
+					WillReturnRows(sqlmock.NewRows([]string{"outstanding"}).AddRow("900.00"))
+			},
+			run: func(ctx context.Context, r repository, tx txManager) (interface{}, error) {
+				param := repayment
+				param.ID = 0
+				data, err := r.Repay(ctx, param)
+				if err != nil {
+					return nil, err
+				}
+				repayments, err := r.GetRepaymentByID(ctx, 1)
+				if err != nil {
+					return nil, err
+				}
+				outstanding, err := r.GetOutstandingPrincipalByBorrower(ctx, 1)
+				return []interface{}{repaymentRecords(data), repaymentRecords(repayments...), outstanding.String()}, err
+			},
+			want: []interface{}{repaymentRecords(repayment), repaymentRecords(repayment), "900.00"},
+		},
+		{
+			name: "outstanding principal of borrower without open loans is zero",
+			seed: []model.Loan{rejected},
+			mock: func(s script) {
+				s.ExpectQuery(s.query("WHERE l.borrower_id=? AND l.status IN (?, ?, ?, ?)")).
+					WithArgs(1, model.PROPOSED, model.APPROVED, model.INVESTED, model.DISBURSED).
+					WillReturnRows(sqlmock.NewRows([]string{"outstanding"}).AddRow("0"))
+			},
+			run: func(ctx context.Context, r repository, tx txManager) (interface{}, error) {
+				outstanding, err := r.GetOutstandingPrincipalByBorrower(ctx, 1)
+				return outstanding.String(), err
+			},
+			want: "0.00",
+		},
+		{
+			name: "overdue funding is approved loans past the deadline, earliest first",
+			seed: overdue,
+			mock: func(s script) {
+				s.ExpectQuery(s.query("WHERE status=? AND funding_deadline < ? ORDER BY funding_deadline")).WithArgs(model.APPROVED, now).
+					WillReturnRows(loanRows(overdue[1], overdue[0]))
+			},
+			run: func(ctx context.Context, r repository, tx txManager) (interface{}, error) {
+				loans, err := r.GetOverdueFunding(ctx, now)
+				return loanRecords(loans...), err
+			},
+			want: loanRecords(overdue[1], overdue[0]),
+		},
+		{
+			name: "list pages through loans newest first",
+			seed: listed,
+			mock: func(s script) {
+				s.ExpectQuery(s.query("SELECT COUNT(*) FROM loan")).WillReturnRows(countRows(3))
+				s.ExpectQuery(s.query("ORDER BY id DESC, id DESC LIMIT ?")).WithArgs(3).WillReturnRows(loanRows(listed[2], listed[1], listed[0]))
+				s.ExpectQuery(s.query("SELECT COUNT(*) FROM loan")).WillReturnRows(countRows(3))
+				s.ExpectQuery(s.query("ORDER BY id DESC, id DESC")).WithArgs("2", 2, 3).WillReturnRows(loanRows(listed[0]))
+			},
+			run: func(ctx context.Context, r repository, tx txManager) (interface{}, error) {
+				first, err := r.GetList(ctx, model.LoanListParam{Limit: 2})
+				if err != nil {
+					return nil, err
+				}
+				second, err := r.GetList(ctx, model.LoanListParam{Limit: 2, Cursor: first.NextCursor})
+				return []interface{}{pageRecord(first), pageRecord(second)}, err
+			},
+			want: []interface{}{
+				record{"total": 3, "data": loanRecords(listed[2], listed[1]), "more": true},
+				record{"total": 3, "data": loanRecords(listed[0]), "more": false},
+			},
+		},
+		{
+			name: "list filters by status",
+			seed: listed,
+			mock: func(s script) {
+				s.ExpectQuery(s.query("SELECT COUNT(*) FROM loan WHERE status = ?")).WithArgs(model.APPROVED).WillReturnRows(countRows(2))
+				s.ExpectQuery(s.query("FROM loan WHERE status = ? ORDER BY id DESC")).WithArgs(model.APPROVED, model.DefaultListLimit+1).
+					WillReturnRows(loanRows(listed[2], listed[1]))
+			},
+			run: func(ctx context.Context, r repository, tx txManager) (interface{}, error) {
+				page, err := r.GetList(ctx, model.LoanListParam{Status: "approved"})
+				return pageRecord(page), err
+			},
+			want: record{"total": 2, "data": loanRecords(listed[2], listed[1]), "more": false},
+		},
+		{
+			name: "list with broken cursor is invalid",
+			seed: listed,
+			mock: func(s script) {
+				s.ExpectQuery(s.query("SELECT COUNT(*) FROM loan")).WillReturnRows(countRows(3))
+			},
+			run: func(ctx context.Context, r repository, tx txManager) (interface{}, error) {
+				return r.GetList(ctx, model.LoanListParam{Cursor: "!"})
+			},
+			wantErr: model.ErrInvalidCursor,
+		},
+		{
+			name: "signature is found by its token",
+			seed: []model.Loan{proposed},
+			mock: func(s script) {
+				s.ExpectSignature(signature)
+				s.ExpectQuery(s.query("FROM loan_agreement_signature WHERE token_hash=? FOR UPDATE")).WithArgs("token-hash").
+					WillReturnRows(signatureRows(signature))
+			},
+			run: func(ctx context.Context, r repository, tx txManager) (interface{}, error) {
+				data, err := r.CreateSignature(ctx, signature)
+				if err != nil {
+					return nil, err
+				}
+				found, err := r.GetSignatureByTokenForUpdate(ctx, "token-hash")
+				return []interface{}{signatureRecords(data), signatureRecords(found)}, err
+			},
+			want: []interface{}{signatureRecords(signature), signatureRecords(signature)},
+		},
+		{
+			name: "unknown token is not found",
+			mock: func(s script) {
+				s.ExpectQuery(s.query("FROM loan_agreement_signature WHERE token_hash=? FOR UPDATE")).WithArgs("other").
+					WillReturnRows(signatureRows())
+			},
+			run: func(ctx context.Context, r repository, tx txManager) (interface{}, error) {
+				return r.GetSignatureByTokenForUpdate(ctx, "other")
+			},
+			wantErr: model.ErrNotFound,
+		},
+		{
+			name: "signature is signed only once",
+			seed: []model.Loan{proposed},
+			mock: func(s script) {
+				s.ExpectSignature(signature)
+				s.ExpectSign(signed, true)
+				s.ExpectSign(signed, false)
+			},
+			run: func(ctx context.Context, r repository, tx txManager) (interface{}, error) {
+				_, err := r.CreateSignature(ctx, signature)
+				if err != nil {
+					return nil, err
+				}
+				data, err := r.Sign(ctx, signed)
+				if err != nil {
+					return nil, err
+				}
+				_, err = r.Sign(ctx, signed)
+				if !errors.Is(err, model.ErrNotFound) {
+					return nil, errors.New("signature is signed twice")
+				}
+				return signatureRecords(data), nil
+			},
+			want: signatureRecords(signed),
+		},
+		{
+			name: "signed signature is found by the agreement letter",
+			seed: []model.Loan{proposed},
+			mock: func(s script) {
+				s.ExpectSignature(signature)
+				s.ExpectSign(signed, true)
+				s.ExpectQuery(s.query("AND signed_at IS NOT NULL ORDER BY signed_at DESC LIMIT 1")).WithArgs(1, "letter-sha", "v1").
+					WillReturnRows(signatureRows(signed))
+			},
+			run: func(ctx context.Context, r repository, tx txManager) (interface{}, error) {
+				_, err := r.CreateSignature(ctx, signature)
+				if err != nil {
+					return nil, err
+				}
+				_, err = r.Sign(ctx, signed)
+				if err != nil {
+					return nil, err
+				}
+				found, err := r.GetSignedSignature(ctx, 1, "letter-sha", "v1")
+				return signatureRecords(found), err
+			},
+			want: signatureRecords(signed),
+		},
+		{
+			name: "unsigned signature is not found by the agreement letter",
+			seed: []model.Loan{proposed},
+			mock: func(s script) {
+				s.ExpectSignature(signature)
+				s.ExpectQuery(s.query("AND signed_at IS NOT NULL ORDER BY signed_at DESC LIMIT 1")).WithArgs(1, "letter-sha", "v1").
+					WillReturnRows(signatureRows())
+			},
+			run: func(ctx context.Context, r repository, tx txManager) (interface{}, error) {
+				_, err := r.CreateSignature(ctx, signature)
+				if err != nil {
+					return nil, err
+				}
+				return r.GetSignedSignature(ctx, 1, "letter-sha", "v1")
+			},
+			wantErr: model.ErrNotFound,
+		},
+		{
+			name: "mutations are recorded as loan events, oldest first",
+			seed: []model.Loan{proposed},
+			mock: func(s script) {
+				s.ExpectLock(1, model.PROPOSED)
+				s.ExpectExec(s.query("UPDATE loan SET")).WillReturnResult(sqlmock.NewResult(0, 1))
+				s.ExpectEvent(1, model.EventStatusChanged)
+				s.ExpectInvests(invest1)
+				s.ExpectQuery(s.query("FROM loan_event WHERE loan_id=?")).WithArgs(1).WillReturnRows(eventRows(
+					model.LoanEvent{ID: 1, LoanID: 1, Event: model.EventStatusChanged},
+					model.LoanEvent{ID: 2, LoanID: 1, Event: model.EventInvested},
+				))
+			},
+			run: func(ctx context.Context, r repository, tx txManager) (interface{}, error) {
+				status := proposed
+				status.Status = model.APPROVED
+				_, err := r.UpdateStatus(ctx, status)
+				if err != nil {
+					return nil, err
+				}
+				err = invest(ctx, r, invest1)
+				if err != nil {
+					return nil, err
+				}
+				events, err := r.GetEventByID(ctx, 1)
+				return eventRecords(events...), err
+			},
+			want: eventRecords(
+				model.LoanEvent{ID: 1, LoanID: 1, Event: model.EventStatusChanged},
+				model.LoanEvent{ID: 2, LoanID: 1, Event: model.EventInvested},
+			),
+		},
+		{
+			name: "rollback discards writes of the transaction",
+			mock: func(s script) {
+				s.ExpectBegin()
+				s.ExpectInsert("INSERT INTO loan (", 1)
+				s.ExpectQuery(s.query("FROM loan WHERE id=?")).WithArgs(1).WillReturnRows(loanRows(stored))
+				s.ExpectEvent(1, model.EventCreated)
+				s.ExpectRollback()
+				s.ExpectQuery(s.query("FROM loan WHERE id=?")).WithArgs(1).WillReturnRows(loanRows())
+			},
+			run: func(ctx context.Context, r repository, tx txManager) (interface{}, error) {
+				err := tx.WithTx(ctx, func(ctx context.Context) error {
+					_, err := r.Create(ctx, created)
+					if err != nil {
+						return err
+					}
+					return errFailed
+				})
+				if !errors.Is(err, errFailed) {
+					return nil, errors.New("error of the transaction is lost")
+				}
+				return r.GetByID(ctx, 1)
+			},
+			wantErr: model.ErrNotFound,
+		},
+		{
+			name: "commit keeps writes of the transaction",
+			mock: func(s script) {
+				s.ExpectBegin()
+				s.ExpectInsert("INSERT INTO loan (", 1)
+				s.ExpectQuery(s.query("FROM loan WHERE id=?")).WithArgs(1).WillReturnRows(loanRows(stored))
+				s.ExpectEvent(1, model.EventCreated)
+				s.ExpectCommit()
+				s.ExpectQuery(s.query("FROM loan WHERE id=?")).WithArgs(1).WillReturnRows(loanRows(stored))
+			},
+			run: func(ctx context.Context, r repository, tx txManager) (interface{}, error) {
+				err := tx.WithTx(ctx, func(ctx context.Context) error {
+					_, err := r.Create(ctx, created)
+					return err
+				})
+				if err != nil {
+					return nil, err
+				}
+				loan, err := r.GetByID(ctx, 1)
+				return loanRecords(loan), err
+			},
+			want: loanRecords(stored),
+		},
+	}
+
+	// sqlBackend is the SQL repository on driver, with the database scripted by mock
+	sqlBackend := func(driver string) func(t *testing.T, seed []model.Loan, mock func(s script)) (repository, txManager) {
+		return func(t *testing.T, seed []model.Loan, mock func(s script)) (repository, txManager) {
+			conn, m, err := sqlmock.New()
+			require.NoError(t, err)
+			t.Cleanup(func() {
+				require.NoError(t, m.ExpectationsWereMet())
+				conn.Close()
+			})
+
+			mock(script{Sqlmock: m, driver: driver})
+
+			db := sqldb.NewFromDB(conn, conn, driver)
+			repo := loan.New(loan.Param{DB: db})
+			return &repo, sqldb.NewTxManager(db, sqldb.TxConfig{})
+		}
+	}
+
+	backends := []struct {
+		name string
+		new  func(t *testing.T, seed []model.Loan, mock func(s script)) (repository, txManager)
+	}{
+		{
+			name: "memory",
+			new: func(t *testing.T, seed []model.Loan, mock func(s script)) (repository, txManager) {
+				repo := memory.New(seed...)
+				return repo, repo
+			},
+		},
+		{
+			name: "postgres",
+			new:  sqlBackend("postgres"),
+		},
+		{
+			name: "mysql",
+			new:  sqlBackend("mysql"),
+		},
+	}
+
+	for _, backend := range backends {
+		for _, tc := range testCases {
+			t.Run(backend.name+"/"+tc.name, func(t *testing.T) {
+				r, tx := backend.new(t, tc.seed, tc.mock)
+
+				got, err := tc.run(context.Background(), r, tx)
+				if tc.wantErr != nil {
+					require.ErrorIs(t, err, tc.wantErr)
+					return
+				}
+
+				require.NoError(t, err)
+				require.Equal(t, tc.want, got)
+			})
+		}
+	}
+}
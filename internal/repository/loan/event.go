@@ -0,0 +1,55 @@
+package loan
+
+import (
+	"encoding/json"
+	"fmt"
+	"simple-app/internal/model"
+	"simple-app/internal/pkg/reqctx"
+	"simple-app/internal/pkg/sqldb"
+
+	"golang.org/x/net/context"
+)
+
+// addEvent record the loan event inside the transaction of the mutation,
+// actor and request ID are taken from the context
+func (l *Loan) addEvent(ctx context.Context, querier sqldb.Querier, event model.LoanEvent, payload interface{}) (err error) {
+	b, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal loan event payload: %w", err)
+	}
+
+	query := `
+		INSERT INTO loan_event (
+			loan_id,
+			event,
+			actor,
+			from_status,
+			to_status,
+			payload,
+			request_id
+		) VALUES (
+			?,
+			?,
+			?,
+			?,
+			?,
+			?,
+			?
+		)
+	`
+
+	_, err = querier.ExecContext(ctx, l.db.Rebind(query),
+		event.LoanID,
+		event.Event,
+		reqctx.Actor(ctx),
+		event.FromStatus,
+		event.ToStatus,
+		string(b),
+		reqctx.RequestID(ctx),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to insert loan event: %w", err)
+	}
+
+	return nil
+}
@@ -2,55 +2,110 @@ package loan
 
 import (
 	"database/sql"
-	"errors"
 	"simple-app/internal/model"
+	"simple-app/internal/pkg/money"
+	"time"
 
 	"golang.org/x/net/context"
 )
 
 // GetByID get loan by ID
 func (u *Loan) GetByID(ctx context.Context, ID int) (loan model.Loan, err error) {
-	var getQuery = `SELECT id ,borrower_id ,principal_amount ,rate ,roi ,status ,agreement_letter_url ,picture_proof_url ,approver_id ,approval_date FROM loan WHERE id=$1`
+	var getQuery = `SELECT id ,borrower_id ,principal_amount ,rate ,roi ,tenor ,repayment_method ,status ,agreement_letter_key ,picture_proof_key ,approver_id ,approval_date ,funding_deadline ,agreement_letter_sha256 ,agreement_letter_version ,version FROM loan WHERE id=?`
 
-	err = u.db.GetContext(ctx, &loan, getQuery, ID)
+	err = u.db.GetContext(ctx, &loan, u.db.Rebind(getQuery), ID)
 	if err != nil && err != sql.ErrNoRows {
 		return loan, err
 	}
 
 	if err == sql.ErrNoRows {
-		return loan, errors.New("not found")
+		return loan, model.ErrNotFound
 	}
 
 	return loan, nil
 }
 
-// GetList get list of loat
-func (u *Loan) GetList(ctx context.Context) (loan []model.Loan, err error) {
-	var getQuery = `SELECT id ,borrower_id ,principal_amount ,rate ,roi ,status ,agreement_letter_url ,picture_proof_url ,approver_id ,approval_date FROM loan`
+// GetByIDForUpdate get loan by ID and lock the row until the transaction ends
+func (u *Loan) GetByIDForUpdate(ctx context.Context, ID int) (loan model.Loan, err error) {
+	querier := u.db.Writer(ctx)
 
-	err = u.db.SelectContext(ctx, &loan, getQuery)
+	var getQuery = `SELECT id ,borrower_id ,principal_amount ,rate ,roi ,tenor ,repayment_method ,status ,agreement_letter_key ,picture_proof_key ,approver_id ,approval_date ,funding_deadline ,agreement_letter_sha256 ,agreement_letter_version ,version FROM loan WHERE id=? FOR UPDATE`
+
+	err = querier.GetContext(ctx, &loan, u.db.Rebind(getQuery), ID)
 	if err != nil && err != sql.ErrNoRows {
 		return loan, err
 	}
 
 	if err == sql.ErrNoRows {
-		return loan, errors.New("not found")
+		return loan, model.ErrNotFound
 	}
 
 	return loan, nil
 }
 
+// GetOverdueFunding get approved loans whose funding deadline is before the given time
+func (u *Loan) GetOverdueFunding(ctx context.Context, before time.Time) (loans []model.Loan, err error) {
+	var getQuery = `SELECT id ,borrower_id ,principal_amount ,rate ,roi ,tenor ,repayment_method ,status ,agreement_letter_key ,picture_proof_key ,approver_id ,approval_date ,funding_deadline ,agreement_letter_sha256 ,agreement_letter_version ,version FROM loan WHERE status=? AND funding_deadline < ? ORDER BY funding_deadline`
+
+	err = u.db.GetMaster().SelectContext(ctx, &loans, u.db.Rebind(getQuery), model.APPROVED, before)
+	if err != nil && err != sql.ErrNoRows {
+		return loans, err
+	}
+
+	return loans, nil
+}
+
+// GetOutstandingPrincipalByBorrower get the principal that borrower still owes over loans that are not closed yet,
+// proposed and approved loans are counted in full
+func (u *Loan) GetOutstandingPrincipalByBorrower(ctx context.Context, borrowerID int) (outstanding money.Money, err error) {
+	querier := u.db.Writer(ctx)
+
+	var getQuery = `
+		SELECT COALESCE(SUM(l.principal_amount - COALESCE(r.paid, 0)), 0)
+		FROM loan l
+		LEFT JOIN (
+			SELECT loan_id, SUM(principal_amount) AS paid FROM loan_repayment GROUP BY loan_id
+		) r ON r.loan_id = l.id
+		WHERE l.borrower_id=? AND l.status IN (?, ?, ?, ?)
+	`
+
+	err = querier.GetContext(ctx, &outstanding, u.db.Rebind(getQuery), borrowerID, model.PROPOSED, model.APPROVED, model.INVESTED, model.DISBURSED)
+	if err != nil {
+		return outstanding, err
+	}
+
+	return outstanding, nil
+}
+
 // GetInvestByID get investment by ID
 func (u *Loan) GetInvestByID(ctx context.Context, ID int) (invests []model.Invest, err error) {
-	var getQuery = `SELECT id ,loan_id ,investor_id ,amount FROM loan_investment WHERE loan_id=$1`
+	var getQuery = `SELECT id ,loan_id ,investor_id ,amount FROM loan_investment WHERE loan_id=? AND withdrawn_at IS NULL ORDER BY id`
 
-	err = u.db.SelectContext(ctx, &invests, getQuery, ID)
+	err = u.db.SelectContext(ctx, &invests, u.db.Rebind(getQuery), ID)
 	if err != nil && err != sql.ErrNoRows {
 		return invests, err
 	}
 
 	if err == sql.ErrNoRows {
-		return invests, errors.New("not found")
+		return invests, model.ErrNotFound
+	}
+
+	return invests, nil
+}
+
+// GetInvestByIDTx get investment by ID inside the transaction
+func (u *Loan) GetInvestByIDTx(ctx context.Context, ID int) (invests []model.Invest, err error) {
+	querier := u.db.Writer(ctx)
+
+	var getQuery = `SELECT id ,loan_id ,investor_id ,amount FROM loan_investment WHERE loan_id=? AND withdrawn_at IS NULL ORDER BY id`
+
+	err = querier.SelectContext(ctx, &invests, u.db.Rebind(getQuery), ID)
+	if err != nil && err != sql.ErrNoRows {
+		return invests, err
+	}
+
+	if err == sql.ErrNoRows {
+		return invests, model.ErrNotFound
 	}
 
 	return invests, nil
@@ -58,16 +113,64 @@ func (u *Loan) GetInvestByID(ctx context.Context, ID int) (invests []model.Inves
 
 // GetDisburseByID get disbursement by ID
 func (u *Loan) GetDisburseByID(ctx context.Context, ID int) (disburses []model.Disburse, err error) {
-	var getQuery = `SELECT id ,loan_id ,signed_agreement_url ,disburser_employee_id, Disbursement_date FROM loan_disbursement WHERE loan_id=$1`
+	var getQuery = `SELECT id ,loan_id ,signed_agreement_key ,disburser_employee_id, Disbursement_date FROM loan_disbursement WHERE loan_id=?`
 
-	err = u.db.SelectContext(ctx, &disburses, getQuery, ID)
+	err = u.db.SelectContext(ctx, &disburses, u.db.Rebind(getQuery), ID)
 	if err != nil && err != sql.ErrNoRows {
 		return disburses, err
 	}
 
 	if err == sql.ErrNoRows {
-		return disburses, errors.New("not found")
+		return disburses, model.ErrNotFound
 	}
 
 	return disburses, nil
 }
+
+// GetInstallmentByID get installment schedule by loan ID
+func (u *Loan) GetInstallmentByID(ctx context.Context, ID int) (installments []model.Installment, err error) {
+	var getQuery = `SELECT id ,loan_id ,installment_number ,due_date ,principal_amount ,interest_amount ,total_amount ,outstanding_balance FROM loan_installment WHERE loan_id=? ORDER BY installment_number`
+
+	err = u.db.SelectContext(ctx, &installments, u.db.Rebind(getQuery), ID)
+	if err != nil && err != sql.ErrNoRows {
+		return installments, err
+	}
+
+	if err == sql.ErrNoRows {
+		return installments, model.ErrNotFound
+	}
+
+	return installments, nil
+}
+
+// GetRepaymentByID get repayments by loan ID
+func (u *Loan) GetRepaymentByID(ctx context.Context, ID int) (repayments []model.Repayment, err error) {
+	var getQuery = `SELECT id ,loan_id ,amount ,principal_amount ,interest_amount ,payment_date FROM loan_repayment WHERE loan_id=? ORDER BY id`
+
+	err = u.db.SelectContext(ctx, &repayments, u.db.Rebind(getQuery), ID)
+	if err != nil && err != sql.ErrNoRows {
+		return repayments, err
+	}
+
+	if err == sql.ErrNoRows {
+		return repayments, model.ErrNotFound
+	}
+
+	return repayments, nil
+}
+
+// GetEventByID get loan events by loan ID, oldest first
+func (u *Loan) GetEventByID(ctx context.Context, ID int) (events []model.LoanEvent, err error) {
+	var getQuery = `SELECT id ,loan_id ,event ,actor ,from_status ,to_status ,payload ,request_id ,created_at FROM loan_event WHERE loan_id=? ORDER BY id`
+
+	err = u.db.SelectContext(ctx, &events, u.db.Rebind(getQuery), ID)
+	if err != nil && err != sql.ErrNoRows {
+		return events, err
+	}
+
+	if err == sql.ErrNoRows {
+		return events, model.ErrNotFound
+	}
+
+	return events, nil
+}
@@ -0,0 +1,197 @@
+package loan
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"simple-app/internal/model"
+	"strings"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+// loanSort is a column loans can be sorted by, id is always the tie breaker so the order is stable for the cursor
+type loanSort struct {
+	expr  string
+	cast  string
+	value func(loan model.Loan) string
+}
+
+// loanSorts are the sortable columns of each driver, the cursor value is cast back to the type of the column
+var loanSorts = map[string]map[string]loanSort{
+	"postgres": {
+		"id": {
+			expr:  "id",
+			cast:  "int",
+			value: func(loan model.Loan) string { return fmt.Sprint(loan.ID) },
+		},
+		"principal_amount": {
+			expr:  "principal_amount",
+			cast:  "numeric",
+			value: func(loan model.Loan) string { return loan.PrincipalAmount.String() },
+		},
+		"approval_date": {
+			// loans that are not approved yet come last on descending order
+			expr: "COALESCE(approval_date, '-infinity')",
+			cast: "timestamptz",
+			value: func(loan model.Loan) string {
+				if loan.ApprovalDate == nil {
+					return "-infinity"
+				}
+				return loan.ApprovalDate.Format(time.RFC3339Nano)
+			},
+		},
+	},
+	"mysql": {
+		"id": {
+			expr:  "id",
+			cast:  "SIGNED",
+			value: func(loan model.Loan) string { return fmt.Sprint(loan.ID) },
+		},
+		"principal_amount": {
+			expr:  "principal_amount",
+			cast:  "DECIMAL(20,2)",
+			value: func(loan model.Loan) string { return loan.PrincipalAmount.String() },
+		},
+		"approval_date": {
+			// mysql has no '-infinity', the lowest DATETIME is used instead
+			expr: "COALESCE(approval_date, CAST('1000-01-01' AS DATETIME(6)))",
+			cast: "DATETIME(6)",
+			value: func(loan model.Loan) string {
+				if loan.ApprovalDate == nil {
+					return "1000-01-01 00:00:00"
+				}
+				return loan.ApprovalDate.UTC().Format("2006-01-02 15:04:05.999999")
+			},
+		},
+	},
+}
+
+// cursor is the position after the last loan of a page
+type cursor struct {
+	Sort  string `json:"s"`
+	Value string `json:"v"`
+	ID    int    `json:"id"`
+}
+
+func encodeCursor(c cursor) string {
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decodeCursor(s string) (c cursor, err error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return c, model.ErrInvalidCursor
+	}
+
+	err = json.Unmarshal(b, &c)
+	if err != nil {
+		return c, model.ErrInvalidCursor
+	}
+
+	return c, nil
+}
+
+// GetList get a page of loans matching the filter, with the total of loans matching the filter
+func (u *Loan) GetList(ctx context.Context, param model.LoanListParam) (page model.LoanPage, err error) {
+	sortName := param.Sort
+	if sortName == "" {
+		sortName = model.DefaultLoanSort
+	}
+	desc := strings.HasPrefix(sortName, "-")
+	sort, ok := loanSorts[u.db.Driver()][strings.TrimPrefix(sortName, "-")]
+	if !ok {
+		return page, fmt.Errorf("unknown sort %s", sortName)
+	}
+
+	limit := param.Limit
+	if limit <= 0 {
+		limit = model.DefaultListLimit
+	}
+
+	var (
+		conds []string
+		args  []interface{}
+	)
+	where := func(cond string, arg interface{}) {
+		args = append(args, arg)
+		conds = append(conds, cond)
+	}
+
+	if param.Status != "" {
+		status, ok := model.ParseLoanStatus(param.Status)
+		if !ok {
+			return page, fmt.Errorf("unknown status %s", param.Status)
+		}
+		where("status = ?", status)
+	}
+	if param.BorrowerID != 0 {
+		where("borrower_id = ?", param.BorrowerID)
+	}
+	if param.MinPrincipal != nil {
+		where("principal_amount >= ?", *param.MinPrincipal)
+	}
+	if param.MaxPrincipal != nil {
+		where("principal_amount <= ?", *param.MaxPrincipal)
+	}
+	if param.ApprovedFrom != nil {
+		where("approval_date >= ?", *param.ApprovedFrom)
+	}
+	if param.ApprovedTo != nil {
+		// the whole day of approved_to is included
+		where("approval_date < ?", param.ApprovedTo.AddDate(0, 0, 1))
+	}
+
+	filter := ""
+	if len(conds) > 0 {
+		filter = " WHERE " + strings.Join(conds, " AND ")
+	}
+
+	err = u.db.GetContext(ctx, &page.Total, u.db.Rebind(`SELECT COUNT(*) FROM loan`+filter), args...)
+	if err != nil {
+		return page, fmt.Errorf("failed to count loans: %w", err)
+	}
+
+	if param.Cursor != "" {
+		after, err := decodeCursor(param.Cursor)
+		if err != nil {
+			return page, err
+		}
+		if after.Sort != sortName {
+			return page, fmt.Errorf("%w: it is made for sort %s", model.ErrInvalidCursor, after.Sort)
+		}
+
+		op := ">"
+		if desc {
+			op = "<"
+		}
+		args = append(args, after.Value, after.ID)
+		conds = append(conds, fmt.Sprintf("(%s, id) %s (CAST(? AS %s), ?)", sort.expr, op, sort.cast))
+		filter = " WHERE " + strings.Join(conds, " AND ")
+	}
+
+	order := "ASC"
+	if desc {
+		order = "DESC"
+	}
+
+	// one more loan is fetched to know whether there is a next page
+	args = append(args, limit+1)
+	getQuery := `SELECT id ,borrower_id ,principal_amount ,rate ,roi ,tenor ,repayment_method ,status ,agreement_letter_key ,picture_proof_key ,approver_id ,approval_date ,funding_deadline ,agreement_letter_sha256 ,agreement_letter_version ,version FROM loan` +
+		filter + fmt.Sprintf(" ORDER BY %s %s, id %s LIMIT ?", sort.expr, order, order)
+
+	err = u.db.SelectContext(ctx, &page.Data, u.db.Rebind(getQuery), args...)
+	if err != nil {
+		return page, fmt.Errorf("failed to get loans: %w", err)
+	}
+
+	if len(page.Data) > limit {
+		page.Data = page.Data[:limit]
+		last := page.Data[limit-1]
+		page.NextCursor = encodeCursor(cursor{Sort: sortName, Value: sort.value(last), ID: last.ID})
+	}
+
+	return page, nil
+}
@@ -0,0 +1,150 @@
+package memory
+
+import (
+	"context"
+	"simple-app/internal/model"
+	"simple-app/internal/pkg/money"
+	"sort"
+	"time"
+)
+
+// GetByID get loan by ID
+func (l *Loan) GetByID(ctx context.Context, ID int) (loan model.Loan, err error) {
+	defer l.lock(ctx)()
+
+	loan, ok := l.state.loans[ID]
+	if !ok {
+		return loan, model.ErrNotFound
+	}
+
+	return loan, nil
+}
+
+// GetByIDForUpdate get loan by ID, the row is held with the whole store inside a transaction
+func (l *Loan) GetByIDForUpdate(ctx context.Context, ID int) (loan model.Loan, err error) {
+	return l.GetByID(ctx, ID)
+}
+
+// GetOverdueFunding get approved loans whose funding deadline is before the given time
+func (l *Loan) GetOverdueFunding(ctx context.Context, before time.Time) (loans []model.Loan, err error) {
+	defer l.lock(ctx)()
+
+	for _, loan := range l.state.loans {
+		if loan.Status == model.APPROVED && loan.FundingDeadline != nil && loan.FundingDeadline.Before(before) {
+			loans = append(loans, loan)
+		}
+	}
+
+	sort.Slice(loans, func(i, j int) bool {
+		return loans[i].FundingDeadline.Before(*loans[j].FundingDeadline)
+	})
+
+	return loans, nil
+}
+
+// GetOutstandingPrincipalByBorrower get the principal that borrower still owes over loans that are not closed yet,
+// proposed and approved loans are counted in full
+func (l *Loan) GetOutstandingPrincipalByBorrower(ctx context.Context, borrowerID int) (outstanding money.Money, err error) {
+	defer l.lock(ctx)()
+
+	outstanding = money.FromMinor(0)
+	for _, loan := range l.state.loans {
+		if loan.BorrowerID != borrowerID {
+			continue
+		}
+
+		switch loan.Status {
+		case model.PROPOSED, model.APPROVED, model.INVESTED, model.DISBURSED:
+		default:
+			continue
+		}
+
+		outstanding = outstanding.Add(loan.PrincipalAmount)
+		for _, repayment := range l.state.repayments {
+			if repayment.LoanID == loan.ID {
+				outstanding = outstanding.Sub(repayment.PrincipalAmount)
+			}
+		}
+	}
+
+	return outstanding, nil
+}
+
+// GetInvestByID get investments of loan that are not withdrawn
+func (l *Loan) GetInvestByID(ctx context.Context, ID int) (invests []model.Invest, err error) {
+	defer l.lock(ctx)()
+
+	for _, investment := range l.state.investments {
+		if investment.LoanID == ID && investment.WithdrawnAt == nil {
+			invests = append(invests, model.Invest{
+				ID:         investment.ID,
+				LoanID:     investment.LoanID,
+				InvestorID: investment.InvestorID,
+				Amount:     investment.Amount,
+			})
+		}
+	}
+
+	return invests, nil
+}
+
+// GetInvestByIDTx get investments of loan inside the transaction
+func (l *Loan) GetInvestByIDTx(ctx context.Context, ID int) (invests []model.Invest, err error) {
+	return l.GetInvestByID(ctx, ID)
+}
+
+// GetDisburseByID get disbursement by loan ID
+func (l *Loan) GetDisburseByID(ctx context.Context, ID int) (disburses []model.Disburse, err error) {
+	defer l.lock(ctx)()
+
+	for _, disburse := range l.state.disburses {
+		if disburse.LoanID == ID {
+			disburses = append(disburses, disburse)
+		}
+	}
+
+	return disburses, nil
+}
+
+// GetInstallmentByID get installment schedule by loan ID
+func (l *Loan) GetInstallmentByID(ctx context.Context, ID int) (installments []model.Installment, err error) {
+	defer l.lock(ctx)()
+
+	for _, installment := range l.state.installments {
+		if installment.LoanID == ID {
+			installments = append(installments, installment)
+		}
+	}
+
+	sort.SliceStable(installments, func(i, j int) bool {
+		return installments[i].Number < installments[j].Number
+	})
+
+	return installments, nil
+}
+
+// GetRepaymentByID get repayments by loan ID
+func (l *Loan) GetRepaymentByID(ctx context.Context, ID int) (repayments []model.Repayment, err error) {
+	defer l.lock(ctx)()
+
+	for _, repayment := range l.state.repayments {
+		if repayment.LoanID == ID {
+			repayments = append(repayments, repayment)
+		}
+	}
+
+	return repayments, nil
+}
+
+// GetEventByID get loan events by loan ID, oldest first
+func (l *Loan) GetEventByID(ctx context.Context, ID int) (events []model.LoanEvent, err error) {
+	defer l.lock(ctx)()
+
+	for _, event := range l.state.events {
+		if event.LoanID == ID {
+			events = append(events, event)
+		}
+	}
+
+	return events, nil
+}
@@ -0,0 +1,150 @@
+// Package memory is a loan repository kept in memory, with the same semantics as the SQL repository of
+// simple-app/internal/repository/loan. It is meant for tests, the data is lost when the process exits
+package memory
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"simple-app/internal/model"
+	"simple-app/internal/pkg/reqctx"
+	"sync"
+	"time"
+)
+
+// Loan is the in-memory loan repository. It is also the transaction manager of its data,
+// a transaction holds the whole store so transactions run one by one like SERIALIZABLE
+type Loan struct {
+	mu    sync.Mutex
+	state state
+}
+
+// state is every table of the repository
+type state struct {
+	seq map[string]int
+
+	loans map[int]model.Loan
+	// investments are kept as Withdraw so they remember when they are withdrawn
+	investments  []model.Withdraw
+	disburses    []model.Disburse
+	installments []model.Installment
+	repayments   []model.Repayment
+	signatures   []model.Signature
+	events       []model.LoanEvent
+}
+
+// New creates the in-memory loan repository holding the given loans, a loan without version gets version 1
+func New(loans ...model.Loan) *Loan {
+	l := &Loan{
+		state: state{
+			seq:   map[string]int{},
+			loans: map[int]model.Loan{},
+		},
+	}
+
+	for _, loan := range loans {
+		if loan.Version == 0 {
+			loan.Version = 1
+		}
+		l.state.loans[loan.ID] = loan
+		if loan.ID > l.state.seq["loan"] {
+			l.state.seq["loan"] = loan.ID
+		}
+	}
+
+	return l
+}
+
+// clone copies the tables, rows are values so a copy of the slices and maps is enough
+func (s state) clone() state {
+	c := state{
+		seq:          make(map[string]int, len(s.seq)),
+		loans:        make(map[int]model.Loan, len(s.loans)),
+		investments:  append([]model.Withdraw(nil), s.investments...),
+		disburses:    append([]model.Disburse(nil), s.disburses...),
+		installments: append([]model.Installment(nil), s.installments...),
+		repayments:   append([]model.Repayment(nil), s.repayments...),
+		signatures:   append([]model.Signature(nil), s.signatures...),
+		events:       append([]model.LoanEvent(nil), s.events...),
+	}
+	for k, v := range s.seq {
+		c.seq[k] = v
+	}
+	for k, v := range s.loans {
+		c.loans[k] = v
+	}
+
+	return c
+}
+
+// next is the next ID of table
+func (s *state) next(table string) int {
+	s.seq[table]++
+	return s.seq[table]
+}
+
+type txKey struct{}
+
+// WithTx runs fn with the store held, the changes of fn are thrown away when it returns an error.
+// A WithTx inside another one only throws away its own changes, like a savepoint
+func (l *Loan) WithTx(ctx context.Context, fn func(ctx context.Context) error) (err error) {
+	if !l.inTx(ctx) {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		ctx = context.WithValue(ctx, txKey{}, l)
+	}
+
+	snapshot := l.state.clone()
+	committed := false
+	defer func() {
+		if !committed {
+			l.state = snapshot
+		}
+	}()
+
+	err = fn(ctx)
+	if err != nil {
+		return err
+	}
+
+	committed = true
+	return nil
+}
+
+// WithSerializableTx is WithTx, transactions of the store already run one by one
+func (l *Loan) WithSerializableTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	return l.WithTx(ctx, fn)
+}
+
+func (l *Loan) inTx(ctx context.Context) bool {
+	tx, _ := ctx.Value(txKey{}).(*Loan)
+	return tx == l
+}
+
+// lock holds the store for a single call, it is already held inside a transaction
+func (l *Loan) lock(ctx context.Context) (unlock func()) {
+	if l.inTx(ctx) {
+		return func() {}
+	}
+
+	l.mu.Lock()
+	return l.mu.Unlock
+}
+
+// addEvent record the loan event like the SQL repository, actor and request ID are taken from the context
+func (l *Loan) addEvent(ctx context.Context, event model.LoanEvent, payload interface{}) (err error) {
+	b, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal loan event payload: %w", err)
+	}
+
+	now := time.Now()
+	event.ID = l.state.next("loan_event")
+	event.Actor = reqctx.Actor(ctx)
+	event.RequestID = reqctx.RequestID(ctx)
+	event.Payload = b
+	event.CreatedAt = &now
+	l.state.events = append(l.state.events, event)
+
+	return nil
+}
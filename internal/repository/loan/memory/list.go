@@ -0,0 +1,155 @@
+package memory
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"simple-app/internal/model"
+	"sort"
+	"strings"
+)
+
+// loanSorts compare two loans by a sortable column, id is always the tie breaker so the order is stable for the cursor
+var loanSorts = map[string]func(a, b model.Loan) int{
+	"id": func(a, b model.Loan) int { return 0 },
+	"principal_amount": func(a, b model.Loan) int {
+		return a.PrincipalAmount.Cmp(b.PrincipalAmount)
+	},
+	"approval_date": func(a, b model.Loan) int {
+		// loans that are not approved yet come first, like '-infinity'
+		switch {
+		case a.ApprovalDate == nil && b.ApprovalDate == nil:
+			return 0
+		case a.ApprovalDate == nil:
+			return -1
+		case b.ApprovalDate == nil:
+			return 1
+		}
+		return a.ApprovalDate.Compare(*b.ApprovalDate)
+	},
+}
+
+// cursor is the position after the last loan of a page, the loan is looked up again to compare the sorted column
+type cursor struct {
+	Sort string `json:"s"`
+	ID   int    `json:"id"`
+}
+
+// GetList get a page of loans matching the filter, with the total of loans matching the filter
+func (l *Loan) GetList(ctx context.Context, param model.LoanListParam) (page model.LoanPage, err error) {
+	defer l.lock(ctx)()
+
+	sortName := param.Sort
+	if sortName == "" {
+		sortName = model.DefaultLoanSort
+	}
+	desc := strings.HasPrefix(sortName, "-")
+	compare, ok := loanSorts[strings.TrimPrefix(sortName, "-")]
+	if !ok {
+		return page, fmt.Errorf("unknown sort %s", sortName)
+	}
+
+	limit := param.Limit
+	if limit <= 0 {
+		limit = model.DefaultListLimit
+	}
+
+	var status model.LoanStatus
+	if param.Status != "" {
+		status, ok = model.ParseLoanStatus(param.Status)
+		if !ok {
+			return page, fmt.Errorf("unknown status %s", param.Status)
+		}
+	}
+
+	less := func(a, b model.Loan) bool {
+		c := compare(a, b)
+		if c == 0 {
+			c = a.ID - b.ID
+		}
+		if desc {
+			return c > 0
+		}
+		return c < 0
+	}
+
+	var loans []model.Loan
+	for _, loan := range l.state.loans {
+		if matchList(loan, param, status) {
+			loans = append(loans, loan)
+		}
+	}
+	page.Total = len(loans)
+
+	sort.Slice(loans, func(i, j int) bool { return less(loans[i], loans[j]) })
+
+	if param.Cursor != "" {
+		after, err := decodeCursor(param.Cursor)
+		if err != nil {
+			return page, err
+		}
+		if after.Sort != sortName {
+			return page, fmt.Errorf("%w: it is made for sort %s", model.ErrInvalidCursor, after.Sort)
+		}
+
+		last, ok := l.state.loans[after.ID]
+		if !ok {
+			return page, model.ErrInvalidCursor
+		}
+		start := sort.Search(len(loans), func(i int) bool { return less(last, loans[i]) })
+		loans = loans[start:]
+	}
+
+	page.Data = loans
+	if len(page.Data) > limit {
+		page.Data = page.Data[:limit]
+		page.NextCursor = encodeCursor(cursor{Sort: sortName, ID: page.Data[limit-1].ID})
+	}
+
+	return page, nil
+}
+
+// matchList tell whether loan matches the filter of the list, status is the parsed param.Status
+func matchList(loan model.Loan, param model.LoanListParam, status model.LoanStatus) bool {
+	if param.Status != "" && loan.Status != status {
+		return false
+	}
+	if param.BorrowerID != 0 && loan.BorrowerID != param.BorrowerID {
+		return false
+	}
+	if param.MinPrincipal != nil && loan.PrincipalAmount.Cmp(*param.MinPrincipal) < 0 {
+		return false
+	}
+	if param.MaxPrincipal != nil && loan.PrincipalAmount.Cmp(*param.MaxPrincipal) > 0 {
+		return false
+	}
+	if param.ApprovedFrom != nil && (loan.ApprovalDate == nil || loan.ApprovalDate.Before(*param.ApprovedFrom)) {
+		return false
+	}
+	// the whole day of approved_to is included
+	if param.ApprovedTo != nil && (loan.ApprovalDate == nil || !loan.ApprovalDate.Before(param.ApprovedTo.AddDate(0, 0, 1))) {
+		return false
+	}
+
+	return true
+}
+
+func encodeCursor(c cursor) string {
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decodeCursor(s string) (c cursor, err error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return c, model.ErrInvalidCursor
+	}
+
+	err = json.Unmarshal(b, &c)
+	if err != nil {
+		return c, model.ErrInvalidCursor
+	}
+
+	return c, nil
+}
@@ -0,0 +1,274 @@
+package memory
+
+import (
+	"context"
+	"simple-app/internal/model"
+	"time"
+)
+
+// Create create loan, the status is "proposed" and the version is 1
+func (l *Loan) Create(ctx context.Context, param model.Loan) (data model.Loan, err error) {
+	defer l.lock(ctx)()
+
+	data = param
+	data.ID = l.state.next("loan")
+	data.Status = model.PROPOSED
+	data.StatusStr = ""
+	data.Version = 1
+	l.state.loans[data.ID] = data
+
+	err = l.addEvent(ctx, model.LoanEvent{
+		LoanID:   data.ID,
+		Event:    model.EventCreated,
+		ToStatus: &data.Status,
+	}, data)
+	if err != nil {
+		return data, err
+	}
+
+	return data, nil
+}
+
+// checkVersion tell why a loan can not be updated at the expected version, nil when it can
+func (l *Loan) checkVersion(id, expected int) (loan model.Loan, err error) {
+	loan, ok := l.state.loans[id]
+	if !ok {
+		return loan, model.ErrNotFound
+	}
+
+	if loan.Version != expected {
+		return loan, &model.VersionConflictError{LoanID: id, Expected: expected, Actual: loan.Version}
+	}
+
+	return loan, nil
+}
+
+// Approve approve the loan when it is still at the version of param, otherwise VersionConflictError is returned
+func (l *Loan) Approve(ctx context.Context, param model.Approve) (id int, err error) {
+	defer l.lock(ctx)()
+
+	loan, err := l.checkVersion(param.ID, param.Version)
+	if err != nil {
+		return id, err
+	}
+
+	from := loan.Status
+	loan.PictureProofKey = param.PictureProofKey
+	loan.ApproverID = &param.ApproverID
+	loan.ApprovalDate = param.ApprovalDate
+	loan.FundingDeadline = param.FundingDeadline
+	loan.Status = param.Status
+	loan.Version++
+	l.state.loans[loan.ID] = loan
+
+	err = l.addEvent(ctx, model.LoanEvent{
+		LoanID:     loan.ID,
+		Event:      model.EventApproved,
+		FromStatus: &from,
+		ToStatus:   &param.Status,
+	}, param)
+	if err != nil {
+		return id, err
+	}
+
+	return loan.ID, nil
+}
+
+// BumpVersion bump the version of loan for a change that is kept in another table, like a signature request.
+// The loan must still be at the expected version, otherwise VersionConflictError is returned
+func (l *Loan) BumpVersion(ctx context.Context, id, version int) (err error) {
+	defer l.lock(ctx)()
+
+	loan, err := l.checkVersion(id, version)
+	if err != nil {
+		return err
+	}
+
+	loan.Version++
+	l.state.loans[loan.ID] = loan
+
+	return nil
+}
+
+// UpdateStatus update status of loan
+func (l *Loan) UpdateStatus(ctx context.Context, status model.Loan) (id int, err error) {
+	return l.UpdateStatusWithReason(ctx, status, "")
+}
+
+// UpdateStatusWithReason update status of loan and keep the reason of the change in the loan event.
+// The loan must still be at the version of status, otherwise VersionConflictError is returned
+func (l *Loan) UpdateStatusWithReason(ctx context.Context, status model.Loan, reason string) (id int, err error) {
+	defer l.lock(ctx)()
+
+	loan, err := l.checkVersion(status.ID, status.Version)
+	if err != nil {
+		return id, err
+	}
+
+	from := loan.Status
+	loan.Status = status.Status
+	loan.Version++
+	l.state.loans[loan.ID] = loan
+
+	err = l.addEvent(ctx, model.LoanEvent{
+		LoanID:     loan.ID,
+		Event:      model.EventStatusChanged,
+		FromStatus: &from,
+		ToStatus:   &status.Status,
+	}, map[string]interface{}{"status": status.Status.ToString(), "reason": reason})
+	if err != nil {
+		return id, err
+	}
+
+	return loan.ID, nil
+}
+
+// Invest insert investment of loan
+func (l *Loan) Invest(ctx context.Context, param model.Invest) (data model.Invest, err error) {
+	defer l.lock(ctx)()
+
+	data = model.Invest{
+		ID:         l.state.next("loan_investment"),
+		LoanID:     param.LoanID,
+		InvestorID: param.InvestorID,
+		Amount:     param.Amount,
+	}
+	l.state.investments = append(l.state.investments, model.Withdraw{
+		ID:         data.ID,
+		LoanID:     data.LoanID,
+		InvestorID: data.InvestorID,
+		Amount:     data.Amount,
+	})
+
+	err = l.addEvent(ctx, model.LoanEvent{
+		LoanID: data.LoanID,
+		Event:  model.EventInvested,
+	}, data)
+	if err != nil {
+		return data, err
+	}
+
+	return data, nil
+}
+
+// Withdraw soft cancel the investment of investor, withdrawn investment is not counted anymore
+func (l *Loan) Withdraw(ctx context.Context, param model.Withdraw) (data model.Withdraw, err error) {
+	defer l.lock(ctx)()
+
+	for i, investment := range l.state.investments {
+		if investment.ID != param.ID || investment.LoanID != param.LoanID || investment.InvestorID != param.InvestorID || investment.WithdrawnAt != nil {
+			continue
+		}
+
+		now := time.Now()
+		investment.Reason = param.Reason
+		investment.WithdrawnAt = &now
+		l.state.investments[i] = investment
+
+		err = l.addEvent(ctx, model.LoanEvent{
+			LoanID: investment.LoanID,
+			Event:  model.EventWithdrawn,
+		}, investment)
+		if err != nil {
+			return data, err
+		}
+
+		return investment, nil
+	}
+
+	return data, model.ErrNotFound
+}
+
+// ReleaseInvestments mark every investment of loan as withdrawn, used when the loan will not be funded anymore
+func (l *Loan) ReleaseInvestments(ctx context.Context, loanID int, reason string) (released []model.Withdraw, err error) {
+	defer l.lock(ctx)()
+
+	now := time.Now()
+	for i, investment := range l.state.investments {
+		if investment.LoanID != loanID || investment.WithdrawnAt != nil {
+			continue
+		}
+
+		investment.Reason = reason
+		investment.WithdrawnAt = &now
+		l.state.investments[i] = investment
+		released = append(released, investment)
+	}
+
+	if len(released) == 0 {
+		return released, nil
+	}
+
+	err = l.addEvent(ctx, model.LoanEvent{
+		LoanID: loanID,
+		Event:  model.EventReleased,
+	}, released)
+	if err != nil {
+		return released, err
+	}
+
+	return released, nil
+}
+
+// Disburse insert disbursement of loan
+func (l *Loan) Disburse(ctx context.Context, param model.Disburse) (data model.Disburse, err error) {
+	defer l.lock(ctx)()
+
+	data = param
+	data.ID = l.state.next("loan_disbursement")
+	data.Version = 0
+	l.state.disburses = append(l.state.disburses, data)
+
+	err = l.addEvent(ctx, model.LoanEvent{
+		LoanID: data.LoanID,
+		Event:  model.EventDisbursed,
+	}, data)
+	if err != nil {
+		return data, err
+	}
+
+	return data, nil
+}
+
+// CreateInstallments insert the installment schedule of loan
+func (l *Loan) CreateInstallments(ctx context.Context, installments []model.Installment) (err error) {
+	if len(installments) == 0 {
+		return nil
+	}
+
+	defer l.lock(ctx)()
+
+	for _, installment := range installments {
+		installment.ID = l.state.next("loan_installment")
+		l.state.installments = append(l.state.installments, installment)
+	}
+
+	err = l.addEvent(ctx, model.LoanEvent{
+		LoanID: installments[0].LoanID,
+		Event:  model.EventScheduleGenerated,
+	}, installments)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Repay insert repayment of loan
+func (l *Loan) Repay(ctx context.Context, param model.Repayment) (data model.Repayment, err error) {
+	defer l.lock(ctx)()
+
+	data = param
+	data.ID = l.state.next("loan_repayment")
+	l.state.repayments = append(l.state.repayments, data)
+
+	err = l.addEvent(ctx, model.LoanEvent{
+		LoanID: data.LoanID,
+		Event:  model.EventRepaid,
+	}, data)
+	if err != nil {
+		return data, err
+	}
+
+	return data, nil
+}
@@ -0,0 +1,101 @@
+package memory
+
+import (
+	"context"
+	"simple-app/internal/model"
+	"time"
+)
+
+// CreateSignature create signature request of the agreement letter of loan
+func (l *Loan) CreateSignature(ctx context.Context, param model.Signature) (data model.Signature, err error) {
+	defer l.lock(ctx)()
+
+	now := time.Now()
+	data = model.Signature{
+		ID:                     l.state.next("loan_agreement_signature"),
+		LoanID:                 param.LoanID,
+		BorrowerID:             param.BorrowerID,
+		TokenHash:              param.TokenHash,
+		AgreementLetterSHA256:  param.AgreementLetterSHA256,
+		AgreementLetterVersion: param.AgreementLetterVersion,
+		ExpiresAt:              param.ExpiresAt,
+		CreatedAt:              &now,
+	}
+	l.state.signatures = append(l.state.signatures, data)
+
+	err = l.addEvent(ctx, model.LoanEvent{
+		LoanID: data.LoanID,
+		Event:  model.EventSignatureRequested,
+	}, data)
+	if err != nil {
+		return data, err
+	}
+
+	return data, nil
+}
+
+// GetSignatureByTokenForUpdate get signature by the SHA-256 of its token
+func (l *Loan) GetSignatureByTokenForUpdate(ctx context.Context, tokenHash string) (signature model.Signature, err error) {
+	defer l.lock(ctx)()
+
+	for _, signature := range l.state.signatures {
+		if signature.TokenHash == tokenHash {
+			return signature, nil
+		}
+	}
+
+	return signature, model.ErrNotFound
+}
+
+// GetSignedSignature get the latest signature of loan that is signed on the given agreement letter
+func (l *Loan) GetSignedSignature(ctx context.Context, loanID int, sha256, version string) (signature model.Signature, err error) {
+	defer l.lock(ctx)()
+
+	found := false
+	for _, s := range l.state.signatures {
+		if s.LoanID != loanID || s.AgreementLetterSHA256 != sha256 || s.AgreementLetterVersion != version || s.SignedAt == nil {
+			continue
+		}
+
+		if !found || s.SignedAt.After(*signature.SignedAt) {
+			signature = s
+			found = true
+		}
+	}
+
+	if !found {
+		return signature, model.ErrNotFound
+	}
+
+	return signature, nil
+}
+
+// Sign record the signature of borrower, a signature is only signed once
+func (l *Loan) Sign(ctx context.Context, param model.Signature) (data model.Signature, err error) {
+	defer l.lock(ctx)()
+
+	for i, signature := range l.state.signatures {
+		if signature.ID != param.ID || signature.SignedAt != nil {
+			continue
+		}
+
+		signature.SignerName = param.SignerName
+		signature.SignerIP = param.SignerIP
+		signature.SignedDocumentSHA256 = param.SignedDocumentSHA256
+		signature.SignedDocumentKey = param.SignedDocumentKey
+		signature.SignedAt = param.SignedAt
+		l.state.signatures[i] = signature
+
+		err = l.addEvent(ctx, model.LoanEvent{
+			LoanID: signature.LoanID,
+			Event:  model.EventSigned,
+		}, signature)
+		if err != nil {
+			return data, err
+		}
+
+		return signature, nil
+	}
+
+	return data, model.ErrNotFound
+}
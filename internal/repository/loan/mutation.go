@@ -4,18 +4,17 @@ import (
 	"database/sql"
 	"fmt"
 	"simple-app/internal/model"
+	"simple-app/internal/pkg/sqldb"
+	"time"
 
 	"github.com/jmoiron/sqlx"
 	"golang.org/x/net/context"
 )
 
-// CreateTx start transaction if needed
-func (l *Loan) CreateTx(ctx context.Context) (*sqlx.Tx, error) {
-	return l.db.GetMaster().BeginTxx(ctx, &sql.TxOptions{})
-}
-
 // Create create loan
 func (l *Loan) Create(ctx context.Context, param model.Loan) (data model.Loan, err error) {
+	querier := l.db.Writer(ctx)
+
 	// Insert file information into the database
 	// STATUS DEFAULT IS "proposed"
 	query := `
@@ -24,95 +23,224 @@ func (l *Loan) Create(ctx context.Context, param model.Loan) (data model.Loan, e
 			principal_amount,
 			rate,
 			roi,
-			agreement_letter_url
+			tenor,
+			repayment_method,
+			agreement_letter_key,
+			agreement_letter_sha256,
+			agreement_letter_version
 		) VALUES (
-			$1,
-			$2,
-			$3,
-			$4,
-			$5
-		) RETURNING
-		 	id,
-			status,
-			borrower_id,
-			principal_amount,
-			rate,
-			roi,
-			agreement_letter_url
+			?,
+			?,
+			?,
+			?,
+			?,
+			?,
+			?,
+			?,
+			?
+		)
 	`
 
-	err = l.db.GetContext(ctx, &data, query,
+	id, err := l.db.Insert(ctx, querier, query,
 		param.BorrowerID,
 		param.PrincipalAmount,
 		param.Rate,
 		param.Roi,
-		param.AgreementLetterURL,
+		param.Tenor,
+		param.RepaymentMethod,
+		param.AgreementLetterKey,
+		param.AgreementLetterSHA256,
+		param.AgreementLetterVersion,
 	)
 	if err != nil {
 		return data, fmt.Errorf("failed to insert loan: %w", err)
 	}
 
+	var getQuery = `SELECT id ,borrower_id ,principal_amount ,rate ,roi ,tenor ,repayment_method ,status ,agreement_letter_key ,agreement_letter_sha256 ,agreement_letter_version ,version FROM loan WHERE id=?`
+
+	err = querier.GetContext(ctx, &data, l.db.Rebind(getQuery), id)
+	if err != nil {
+		return data, fmt.Errorf("failed to get inserted loan: %w", err)
+	}
+
+	err = l.addEvent(ctx, querier, model.LoanEvent{
+		LoanID:   data.ID,
+		Event:    model.EventCreated,
+		ToStatus: &data.Status,
+	}, data)
+	if err != nil {
+		return data, err
+	}
+
 	return data, nil
 }
 
+// loanVersion is the status and version of loan before an update
+type loanVersion struct {
+	Status  model.LoanStatus `db:"status"`
+	Version int              `db:"version"`
+}
+
+// lockVersion lock the loan until the transaction ends and return its status,
+// the loan must still be at the expected version otherwise VersionConflictError is returned
+func (l *Loan) lockVersion(ctx context.Context, querier sqldb.Querier, id, expected int) (status model.LoanStatus, err error) {
+	var current loanVersion
+	err = querier.GetContext(ctx, &current, l.db.Rebind(`SELECT status ,version FROM loan WHERE id=? FOR UPDATE`), id)
+	if err == sql.ErrNoRows {
+		return status, model.ErrNotFound
+	}
+	if err != nil {
+		return status, err
+	}
+
+	if current.Version != expected {
+		return status, &model.VersionConflictError{LoanID: id, Expected: expected, Actual: current.Version}
+	}
+
+	return current.Status, nil
+}
+
+// updateVersion run an update of loan that is only applied at the expected version, the update must bump the version.
+// Outside a transaction the lock of lockVersion is already released, so the loan can still change before the update
+func (l *Loan) updateVersion(ctx context.Context, querier sqldb.Querier, query string, arg interface{}, id, expected int) (err error) {
+	q, args, err := sqlx.Named(query, arg)
+	if err != nil {
+		return err
+	}
+
+	result, err := querier.ExecContext(ctx, l.db.Rebind(q), args...)
+	if err != nil {
+		return err
+	}
+
+	updated, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if updated == 0 {
+		return l.versionConflict(ctx, querier, id, expected)
+	}
+
+	return nil
+}
+
+// versionConflict tell why an update of loan with the expected version changed nothing
+func (l *Loan) versionConflict(ctx context.Context, querier sqldb.Querier, id, expected int) error {
+	var actual int
+	err := querier.GetContext(ctx, &actual, l.db.Rebind(`SELECT version FROM loan WHERE id=?`), id)
+	if err == sql.ErrNoRows {
+		return model.ErrNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	return &model.VersionConflictError{LoanID: id, Expected: expected, Actual: actual}
+}
+
+// Approve approve the loan when it is still at the version of param, otherwise VersionConflictError is returned
 func (l *Loan) Approve(ctx context.Context, param model.Approve) (id int, err error) {
+	querier := l.db.Writer(ctx)
+
+	from, err := l.lockVersion(ctx, querier, param.ID, param.Version)
+	if err != nil {
+		return id, err
+	}
+
 	q := `
 		UPDATE loan SET
-			picture_proof_url = :picture_proof_url,
+			picture_proof_key = :picture_proof_key,
 			approver_id = :approver_id,
 			approval_date = :approval_date,
-			status = :status
+			funding_deadline = :funding_deadline,
+			status = :status,
+			version = version + 1
 		WHERE id = :id
-		RETURNING
-			id
+			AND version = :version
 	`
 
-	q, arg, err := sqlx.Named(q, param)
+	err = l.updateVersion(ctx, querier, q, param, param.ID, param.Version)
 	if err != nil {
 		return id, err
 	}
 
-	err = l.db.GetContext(ctx, &id, l.db.Rebind(q), arg...)
+	err = l.addEvent(ctx, querier, model.LoanEvent{
+		LoanID:     param.ID,
+		Event:      model.EventApproved,
+		FromStatus: &from,
+		ToStatus:   &param.Status,
+	}, param)
 	if err != nil {
 		return id, err
 	}
 
-	return id, nil
+	return param.ID, nil
+}
+
+// BumpVersion bump the version of loan for a change that is kept in another table, like a signature request.
+// The loan must still be at the expected version, otherwise VersionConflictError is returned
+func (l *Loan) BumpVersion(ctx context.Context, id, version int) (err error) {
+	querier := l.db.Writer(ctx)
+
+	_, err = l.lockVersion(ctx, querier, id, version)
+	if err != nil {
+		return err
+	}
+
+	q := `
+		UPDATE loan SET
+			version = version + 1
+		WHERE id = :id
+			AND version = :version
+	`
+
+	return l.updateVersion(ctx, querier, q, model.Loan{ID: id, Version: version}, id, version)
 }
 
-func (l *Loan) UpdateStatus(ctx context.Context, dbTx *sqlx.Tx, status model.Loan) (id int, err error) {
-	querier := dbTx
-	if dbTx == nil {
-		querier = l.db.GetMaster().MustBegin()
+func (l *Loan) UpdateStatus(ctx context.Context, status model.Loan) (id int, err error) {
+	return l.UpdateStatusWithReason(ctx, status, "")
+}
+
+// UpdateStatusWithReason update status of loan and keep the reason of the change in the loan event.
+// The loan must still be at the version of status, otherwise VersionConflictError is returned
+func (l *Loan) UpdateStatusWithReason(ctx context.Context, status model.Loan, reason string) (id int, err error) {
+	querier := l.db.Writer(ctx)
+
+	from, err := l.lockVersion(ctx, querier, status.ID, status.Version)
+	if err != nil {
+		return id, err
 	}
 
 	q := `
 	UPDATE loan SET
-		status = :status
+		status = :status,
+		version = version + 1
 	WHERE id = :id
-	RETURNING
-		id
+		AND version = :version
 `
 
-	q, arg, err := sqlx.Named(q, status)
+	err = l.updateVersion(ctx, querier, q, status, status.ID, status.Version)
 	if err != nil {
 		return id, err
 	}
 
-	err = querier.GetContext(ctx, &id, l.db.Rebind(q), arg...)
+	err = l.addEvent(ctx, querier, model.LoanEvent{
+		LoanID:     status.ID,
+		Event:      model.EventStatusChanged,
+		FromStatus: &from,
+		ToStatus:   &status.Status,
+	}, map[string]interface{}{"status": status.Status.ToString(), "reason": reason})
 	if err != nil {
 		return id, err
 	}
 
-	return id, nil
+	return status.ID, nil
 }
 
-func (l *Loan) Invest(ctx context.Context, dbTx *sqlx.Tx, param model.Invest) (data model.Invest, err error) {
+func (l *Loan) Invest(ctx context.Context, param model.Invest) (data model.Invest, err error) {
 
-	querier := dbTx
-	if dbTx == nil {
-		querier = l.db.GetMaster().MustBegin()
-	}
+	querier := l.db.Writer(ctx)
 
 	query := `
 		INSERT INTO loan_investment (
@@ -120,17 +248,13 @@ func (l *Loan) Invest(ctx context.Context, dbTx *sqlx.Tx, param model.Invest) (d
 			investor_id,
 			amount
 		) VALUES (
-			$1,
-			$2,
-			$3
-		) RETURNING
-		 	id,
-			loan_id
-			investor_id,
-			amount
+			?,
+			?,
+			?
+		)
 	`
 
-	err = querier.GetContext(ctx, &data, query,
+	id, err := l.db.Insert(ctx, querier, query,
 		param.LoanID,
 		param.InvestorID,
 		param.Amount,
@@ -139,39 +263,149 @@ func (l *Loan) Invest(ctx context.Context, dbTx *sqlx.Tx, param model.Invest) (d
 		return data, fmt.Errorf("failed to invest loan: %w", err)
 	}
 
+	data = model.Invest{
+		ID:         id,
+		LoanID:     param.LoanID,
+		InvestorID: param.InvestorID,
+		Amount:     param.Amount,
+	}
+
+	err = l.addEvent(ctx, querier, model.LoanEvent{
+		LoanID: data.LoanID,
+		Event:  model.EventInvested,
+	}, data)
+	if err != nil {
+		return data, err
+	}
+
 	return data, nil
 }
 
-func (l *Loan) Disburse(ctx context.Context, dbTx *sqlx.Tx, param model.Disburse) (data model.Disburse, err error) {
+// Withdraw soft cancel the investment of investor, withdrawn investment is not counted anymore
+func (l *Loan) Withdraw(ctx context.Context, param model.Withdraw) (data model.Withdraw, err error) {
+
+	querier := l.db.Writer(ctx)
+
+	query := `
+		UPDATE loan_investment SET
+			withdrawn_at = NOW(),
+			withdrawal_reason = ?
+		WHERE id = ?
+			AND loan_id = ?
+			AND investor_id = ?
+			AND withdrawn_at IS NULL
+	`
 
-	querier := dbTx
-	if dbTx == nil {
-		querier = l.db.GetMaster().MustBegin()
+	result, err := querier.ExecContext(ctx, l.db.Rebind(query),
+		param.Reason,
+		param.ID,
+		param.LoanID,
+		param.InvestorID,
+	)
+	if err != nil {
+		return data, fmt.Errorf("failed to withdraw investment: %w", err)
+	}
+
+	withdrawn, err := result.RowsAffected()
+	if err != nil {
+		return data, fmt.Errorf("failed to withdraw investment: %w", err)
 	}
 
+	if withdrawn == 0 {
+		return data, model.ErrNotFound
+	}
+
+	var getQuery = `SELECT id ,loan_id ,investor_id ,amount ,withdrawal_reason ,withdrawn_at FROM loan_investment WHERE id=?`
+
+	err = querier.GetContext(ctx, &data, l.db.Rebind(getQuery), param.ID)
+	if err != nil {
+		return data, fmt.Errorf("failed to get withdrawn investment: %w", err)
+	}
+
+	err = l.addEvent(ctx, querier, model.LoanEvent{
+		LoanID: data.LoanID,
+		Event:  model.EventWithdrawn,
+	}, data)
+	if err != nil {
+		return data, err
+	}
+
+	return data, nil
+}
+
+// ReleaseInvestments mark every investment of loan as withdrawn, used when the loan will not be funded anymore
+func (l *Loan) ReleaseInvestments(ctx context.Context, loanID int, reason string) (released []model.Withdraw, err error) {
+
+	querier := l.db.Writer(ctx)
+
+	// the investments are locked first so exactly the ones returned are released
+	var getQuery = `SELECT id ,loan_id ,investor_id ,amount FROM loan_investment WHERE loan_id=? AND withdrawn_at IS NULL ORDER BY id FOR UPDATE`
+
+	err = querier.SelectContext(ctx, &released, l.db.Rebind(getQuery), loanID)
+	if err != nil {
+		return released, fmt.Errorf("failed to release investments: %w", err)
+	}
+
+	if len(released) == 0 {
+		return released, nil
+	}
+
+	now := time.Now()
+	ids := make([]int, 0, len(released))
+	for i := range released {
+		released[i].Reason = reason
+		released[i].WithdrawnAt = &now
+		ids = append(ids, released[i].ID)
+	}
+
+	query, args, err := sqlx.In(`
+		UPDATE loan_investment SET
+			withdrawn_at = ?,
+			withdrawal_reason = ?
+		WHERE id IN (?)
+	`, now, reason, ids)
+	if err != nil {
+		return released, err
+	}
+
+	_, err = querier.ExecContext(ctx, l.db.Rebind(query), args...)
+	if err != nil {
+		return released, fmt.Errorf("failed to release investments: %w", err)
+	}
+
+	err = l.addEvent(ctx, querier, model.LoanEvent{
+		LoanID: loanID,
+		Event:  model.EventReleased,
+	}, released)
+	if err != nil {
+		return released, err
+	}
+
+	return released, nil
+}
+
+func (l *Loan) Disburse(ctx context.Context, param model.Disburse) (data model.Disburse, err error) {
+
+	querier := l.db.Writer(ctx)
+
 	query := `
 		INSERT INTO loan_disbursement (
 			loan_id,
-			signed_agreement_url,
+			signed_agreement_key,
 			disburser_employee_id,
 			disbursement_date
-			
+
 		) VALUES (
-			$1,
-			$2,
-			$3,
-			$4
-		) RETURNING
-		 	id,
-			loan_id,
-			signed_agreement_url,
-			disburser_employee_id,
-			disbursement_date
+			?,
+			?,
+			?,
+			?
+		)
 	`
 
-	err = querier.GetContext(ctx, &data, query,
+	id, err := l.db.Insert(ctx, querier, query,
 		param.LoanID,
-		param.SignedAgreementURL,
+		param.SignedAgreementKey,
 		param.DisburseEmployeeID,
 		param.DisbursementDate,
 	)
@@ -179,5 +413,112 @@ func (l *Loan) Disburse(ctx context.Context, dbTx *sqlx.Tx, param model.Disburse
 		return data, fmt.Errorf("failed to disburse loan: %w", err)
 	}
 
+	var getQuery = `SELECT id ,loan_id ,signed_agreement_key ,disburser_employee_id ,disbursement_date FROM loan_disbursement WHERE id=?`
+
+	err = querier.GetContext(ctx, &data, l.db.Rebind(getQuery), id)
+	if err != nil {
+		return data, fmt.Errorf("failed to get disbursement: %w", err)
+	}
+
+	err = l.addEvent(ctx, querier, model.LoanEvent{
+		LoanID: data.LoanID,
+		Event:  model.EventDisbursed,
+	}, data)
+	if err != nil {
+		return data, err
+	}
+
+	return data, nil
+}
+
+func (l *Loan) CreateInstallments(ctx context.Context, installments []model.Installment) (err error) {
+	if len(installments) == 0 {
+		return nil
+	}
+
+	querier := l.db.Writer(ctx)
+
+	query := `
+		INSERT INTO loan_installment (
+			loan_id,
+			installment_number,
+			due_date,
+			principal_amount,
+			interest_amount,
+			total_amount,
+			outstanding_balance
+		) VALUES (
+			:loan_id,
+			:installment_number,
+			:due_date,
+			:principal_amount,
+			:interest_amount,
+			:total_amount,
+			:outstanding_balance
+		)
+	`
+
+	_, err = querier.NamedExecContext(ctx, query, installments)
+	if err != nil {
+		return fmt.Errorf("failed to create installments: %w", err)
+	}
+
+	err = l.addEvent(ctx, querier, model.LoanEvent{
+		LoanID: installments[0].LoanID,
+		Event:  model.EventScheduleGenerated,
+	}, installments)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (l *Loan) Repay(ctx context.Context, param model.Repayment) (data model.Repayment, err error) {
+
+	querier := l.db.Writer(ctx)
+
+	query := `
+		INSERT INTO loan_repayment (
+			loan_id,
+			amount,
+			principal_amount,
+			interest_amount,
+			payment_date
+		) VALUES (
+			?,
+			?,
+			?,
+			?,
+			?
+		)
+	`
+
+	id, err := l.db.Insert(ctx, querier, query,
+		param.LoanID,
+		param.Amount,
+		param.PrincipalAmount,
+		param.InterestAmount,
+		param.PaymentDate,
+	)
+	if err != nil {
+		return data, fmt.Errorf("failed to repay loan: %w", err)
+	}
+
+	var getQuery = `SELECT id ,loan_id ,amount ,principal_amount ,interest_amount ,payment_date FROM loan_repayment WHERE id=?`
+
+	err = querier.GetContext(ctx, &data, l.db.Rebind(getQuery), id)
+	if err != nil {
+		return data, fmt.Errorf("failed to get repayment: %w", err)
+	}
+
+	err = l.addEvent(ctx, querier, model.LoanEvent{
+		LoanID: data.LoanID,
+		Event:  model.EventRepaid,
+	}, data)
+	if err != nil {
+		return data, err
+	}
+
 	return data, nil
 }
@@ -0,0 +1,151 @@
+package loan
+
+import (
+	"database/sql"
+	"fmt"
+	"simple-app/internal/model"
+
+	"golang.org/x/net/context"
+)
+
+// CreateSignature create signature request of the agreement letter of loan
+func (l *Loan) CreateSignature(ctx context.Context, param model.Signature) (data model.Signature, err error) {
+	querier := l.db.Writer(ctx)
+
+	query := `
+		INSERT INTO loan_agreement_signature (
+			loan_id,
+			borrower_id,
+			token_hash,
+			agreement_letter_sha256,
+			agreement_letter_version,
+			expires_at
+		) VALUES (
+			?,
+			?,
+			?,
+			?,
+			?,
+			?
+		)
+	`
+
+	id, err := l.db.Insert(ctx, querier, query,
+		param.LoanID,
+		param.BorrowerID,
+		param.TokenHash,
+		param.AgreementLetterSHA256,
+		param.AgreementLetterVersion,
+		param.ExpiresAt,
+	)
+	if err != nil {
+		return data, fmt.Errorf("failed to insert signature: %w", err)
+	}
+
+	var getQuery = `SELECT id ,loan_id ,borrower_id ,token_hash ,agreement_letter_sha256 ,agreement_letter_version ,expires_at ,created_at FROM loan_agreement_signature WHERE id=?`
+
+	err = querier.GetContext(ctx, &data, l.db.Rebind(getQuery), id)
+	if err != nil {
+		return data, fmt.Errorf("failed to get inserted signature: %w", err)
+	}
+
+	err = l.addEvent(ctx, querier, model.LoanEvent{
+		LoanID: data.LoanID,
+		Event:  model.EventSignatureRequested,
+	}, data)
+	if err != nil {
+		return data, err
+	}
+
+	return data, nil
+}
+
+// GetSignatureByTokenForUpdate get signature by the SHA-256 of its token and lock the row until the transaction ends
+func (l *Loan) GetSignatureByTokenForUpdate(ctx context.Context, tokenHash string) (signature model.Signature, err error) {
+	querier := l.db.Writer(ctx)
+
+	var getQuery = `SELECT id ,loan_id ,borrower_id ,token_hash ,agreement_letter_sha256 ,agreement_letter_version ,signer_name ,signer_ip ,signed_document_sha256 ,signed_document_key ,expires_at ,signed_at ,created_at FROM loan_agreement_signature WHERE token_hash=? FOR UPDATE`
+
+	err = querier.GetContext(ctx, &signature, l.db.Rebind(getQuery), tokenHash)
+	if err != nil && err != sql.ErrNoRows {
+		return signature, err
+	}
+
+	if err == sql.ErrNoRows {
+		return signature, model.ErrNotFound
+	}
+
+	return signature, nil
+}
+
+// GetSignedSignature get the latest signature of loan that is signed on the given agreement letter
+func (l *Loan) GetSignedSignature(ctx context.Context, loanID int, sha256, version string) (signature model.Signature, err error) {
+	querier := l.db.Writer(ctx)
+
+	var getQuery = `SELECT id ,loan_id ,borrower_id ,token_hash ,agreement_letter_sha256 ,agreement_letter_version ,signer_name ,signer_ip ,signed_document_sha256 ,signed_document_key ,expires_at ,signed_at ,created_at FROM loan_agreement_signature WHERE loan_id=? AND agreement_letter_sha256=? AND agreement_letter_version=? AND signed_at IS NOT NULL ORDER BY signed_at DESC LIMIT 1`
+
+	err = querier.GetContext(ctx, &signature, l.db.Rebind(getQuery), loanID, sha256, version)
+	if err != nil && err != sql.ErrNoRows {
+		return signature, err
+	}
+
+	if err == sql.ErrNoRows {
+		return signature, model.ErrNotFound
+	}
+
+	return signature, nil
+}
+
+// Sign record the signature of borrower, a signature is only signed once
+func (l *Loan) Sign(ctx context.Context, param model.Signature) (data model.Signature, err error) {
+	querier := l.db.Writer(ctx)
+
+	query := `
+		UPDATE loan_agreement_signature SET
+			signer_name = ?,
+			signer_ip = ?,
+			signed_document_sha256 = ?,
+			signed_document_key = ?,
+			signed_at = ?
+		WHERE id = ?
+			AND signed_at IS NULL
+	`
+
+	result, err := querier.ExecContext(ctx, l.db.Rebind(query),
+		param.SignerName,
+		param.SignerIP,
+		param.SignedDocumentSHA256,
+		param.SignedDocumentKey,
+		param.SignedAt,
+		param.ID,
+	)
+	if err != nil {
+		return data, fmt.Errorf("failed to sign agreement letter: %w", err)
+	}
+
+	signed, err := result.RowsAffected()
+	if err != nil {
+		return data, fmt.Errorf("failed to sign agreement letter: %w", err)
+	}
+
+	if signed == 0 {
+		return data, model.ErrNotFound
+	}
+
+	var getQuery = `SELECT id ,loan_id ,borrower_id ,token_hash ,agreement_letter_sha256 ,agreement_letter_version ,signer_name ,signer_ip ,signed_document_sha256 ,signed_document_key ,expires_at ,signed_at ,created_at FROM loan_agreement_signature WHERE id=?`
+
+	err = querier.GetContext(ctx, &data, l.db.Rebind(getQuery), param.ID)
+	if err != nil {
+		return data, fmt.Errorf("failed to get signature: %w", err)
+	}
+
+	err = l.addEvent(ctx, querier, model.LoanEvent{
+		LoanID: data.LoanID,
+		Event:  model.EventSigned,
+	}, data)
+	if err != nil {
+		return data, err
+	}
+
+	return data, nil
+}
@@ -0,0 +1,33 @@
+package outbox
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"simple-app/internal/model"
+	"time"
+)
+
+// ClaimDue get pending messages that are due and not claimed, and claim them until the given time.
+// It must run inside a short transaction, locked messages are skipped so dispatchers never claim the same message,
+// and a message that is not updated before its claim runs out is claimed again
+func (o *Outbox) ClaimDue(ctx context.Context, now, until time.Time, limit int) (messages []model.OutboxMessage, err error) {
+	querier := o.db.Writer(ctx)
+
+	var getQuery = `SELECT id ,topic ,channel ,recipient_id ,payload ,status ,attempts ,max_attempts ,next_attempt_at ,last_error ,created_at ,sent_at FROM outbox WHERE status=? AND next_attempt_at <= ? AND (claimed_until IS NULL OR claimed_until <= ?) ORDER BY id LIMIT ? FOR UPDATE SKIP LOCKED`
+
+	err = querier.SelectContext(ctx, &messages, o.db.Rebind(getQuery), model.OutboxPending, now, now, limit)
+	if err != nil && err != sql.ErrNoRows {
+		return messages, err
+	}
+
+	query := `UPDATE outbox SET claimed_until = ? WHERE id = ?`
+	for _, message := range messages {
+		_, err = querier.ExecContext(ctx, o.db.Rebind(query), until, message.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to claim outbox message: %w", err)
+		}
+	}
+
+	return messages, nil
+}
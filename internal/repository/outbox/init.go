@@ -0,0 +1,19 @@
+package outbox
+
+import (
+	"simple-app/internal/pkg/sqldb"
+)
+
+type Outbox struct {
+	db *sqldb.DB
+}
+
+type Param struct {
+	DB *sqldb.DB
+}
+
+func New(p Param) Outbox {
+	return Outbox{
+		db: p.DB,
+	}
+}
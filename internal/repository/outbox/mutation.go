@@ -0,0 +1,75 @@
+package outbox
+
+import (
+	"context"
+	"fmt"
+	"simple-app/internal/model"
+)
+
+// Create insert messages, it must be called inside the transaction of the change the messages are about
+func (o *Outbox) Create(ctx context.Context, messages []model.OutboxMessage) (err error) {
+	querier := o.db.Writer(ctx)
+
+	query := `
+		INSERT INTO outbox (
+			topic,
+			channel,
+			recipient_id,
+			payload,
+			max_attempts
+		) VALUES (
+			?,
+			?,
+			?,
+			?,
+			?
+		)
+	`
+
+	for _, message := range messages {
+		_, err = querier.ExecContext(ctx, o.db.Rebind(query),
+			message.Topic,
+			message.Channel,
+			message.RecipientID,
+			string(message.Payload),
+			message.MaxAttempts,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to insert outbox message: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// Update update the delivery state and the payload of message and release its claim
+func (o *Outbox) Update(ctx context.Context, message model.OutboxMessage) (err error) {
+	querier := o.db.Writer(ctx)
+
+	query := `
+		UPDATE outbox SET
+			payload = ?,
+			status = ?,
+			attempts = ?,
+			next_attempt_at = ?,
+			last_error = ?,
+			sent_at = ?,
+			claimed_until = NULL
+		WHERE id = ?
+	`
+
+	_, err = querier.ExecContext(ctx, o.db.Rebind(query),
+		message.Payload,
+		message.Status,
+		message.Attempts,
+		message.NextAttemptAt,
+		message.LastError,
+		message.SentAt,
+		message.ID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update outbox message: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,23 @@
+package payout
+
+import (
+	"context"
+	"database/sql"
+	"simple-app/internal/model"
+)
+
+// GetByInvestorID get payouts by investor ID
+func (p *Payout) GetByInvestorID(ctx context.Context, ID int) (payouts []model.Payout, err error) {
+	var getQuery = `SELECT id ,loan_id ,repayment_id ,invest_id ,investor_id ,principal_amount ,return_amount ,amount ,created_at FROM loan_payout WHERE investor_id=? ORDER BY id`
+
+	err = p.db.SelectContext(ctx, &payouts, p.db.Rebind(getQuery), ID)
+	if err != nil && err != sql.ErrNoRows {
+		return payouts, err
+	}
+
+	if err == sql.ErrNoRows {
+		return payouts, model.ErrNotFound
+	}
+
+	return payouts, nil
+}
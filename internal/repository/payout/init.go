@@ -0,0 +1,19 @@
+package payout
+
+import (
+	"simple-app/internal/pkg/sqldb"
+)
+
+type Payout struct {
+	db *sqldb.DB
+}
+
+type Param struct {
+	DB *sqldb.DB
+}
+
+func New(p Param) Payout {
+	return Payout{
+		db: p.DB,
+	}
+}
@@ -0,0 +1,43 @@
+package payout
+
+import (
+	"context"
+	"fmt"
+	"simple-app/internal/model"
+)
+
+// Create insert payouts of a repayment
+func (p *Payout) Create(ctx context.Context, payouts []model.Payout) (err error) {
+	if len(payouts) == 0 {
+		return nil
+	}
+
+	querier := p.db.Writer(ctx)
+
+	query := `
+		INSERT INTO loan_payout (
+			loan_id,
+			repayment_id,
+			invest_id,
+			investor_id,
+			principal_amount,
+			return_amount,
+			amount
+		) VALUES (
+			:loan_id,
+			:repayment_id,
+			:invest_id,
+			:investor_id,
+			:principal_amount,
+			:return_amount,
+			:amount
+		)
+	`
+
+	_, err = querier.NamedExecContext(ctx, query, payouts)
+	if err != nil {
+		return fmt.Errorf("failed to create payouts: %w", err)
+	}
+
+	return nil
+}
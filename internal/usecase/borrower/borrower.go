@@ -0,0 +1,40 @@
+package borrower
+
+import (
+	"context"
+	"simple-app/internal/model"
+)
+
+// Usecase instance struct for borrower
+type Usecase struct {
+	borrowerRepo borrowerRepo
+}
+
+type borrowerRepo interface {
+	GetByID(ctx context.Context, ID int) (borrower model.Borrower, err error)
+
+	Create(ctx context.Context, param model.Borrower) (data model.Borrower, err error)
+	UpdateKYC(ctx context.Context, param model.KYC) (data model.Borrower, err error)
+}
+
+// New will instantiate new borrower usecase
+func New(borrowerRepo borrowerRepo) *Usecase {
+	return &Usecase{
+		borrowerRepo: borrowerRepo,
+	}
+}
+
+// Register borrower, the borrower can not propose loan until the KYC is verified
+func (u *Usecase) Register(ctx context.Context, param model.Borrower) (data model.Borrower, err error) {
+	return u.borrowerRepo.Create(ctx, param)
+}
+
+// UpdateKYC update the result of KYC verification of borrower
+func (u *Usecase) UpdateKYC(ctx context.Context, param model.KYC) (data model.Borrower, err error) {
+	return u.borrowerRepo.UpdateKYC(ctx, param)
+}
+
+// Get get borrower by ID
+func (u *Usecase) Get(ctx context.Context, id int) (data model.Borrower, err error) {
+	return u.borrowerRepo.GetByID(ctx, id)
+}
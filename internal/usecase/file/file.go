@@ -0,0 +1,166 @@
+package file
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"simple-app/internal/model"
+	"simple-app/internal/pkg/blob"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultDownloadTTL is how long a download URL is valid when no TTL is configured
+	DefaultDownloadTTL = 15 * time.Minute
+	// DefaultAttachmentTTL is how long the download URL of an email attachment is valid when no TTL is configured
+	DefaultAttachmentTTL = 7 * 24 * time.Hour
+)
+
+// rule is what is accepted for a kind of file
+type rule struct {
+	dir     string
+	maxSize int
+	types   map[string]string // content type to extension
+}
+
+var rules = map[model.FileKind]rule{
+	model.FilePictureProof: {
+		dir:     "picture-proofs",
+		maxSize: 5 << 20,
+		types:   map[string]string{"image/jpeg": ".jpg", "image/png": ".png"},
+	},
+	model.FileSignedAgreement: {
+		dir:     "signed-agreements",
+		maxSize: 10 << 20,
+		types:   map[string]string{"application/pdf": ".pdf"},
+	},
+}
+
+// Usecase instance struct for file
+type Usecase struct {
+	store      store
+	signingKey []byte
+	ttl        time.Duration
+	now        func() time.Time
+}
+
+type store interface {
+	Put(ctx context.Context, key string, data []byte) (err error)
+	Get(ctx context.Context, key string) (data []byte, err error)
+	URL(key string) string
+}
+
+// New will instantiate new file usecase, download URLs are signed with signingKey and valid for ttl
+// or DefaultDownloadTTL when it is zero
+func New(store store, signingKey string, ttl time.Duration) *Usecase {
+	if ttl <= 0 {
+		ttl = DefaultDownloadTTL
+	}
+
+	return &Usecase{
+		store:      store,
+		signingKey: []byte(signingKey),
+		ttl:        ttl,
+		now:        time.Now,
+	}
+}
+
+// Upload stores the file, the type is detected from the content and not trusted from the client.
+// The key is the SHA-256 of the content, so the same file is only stored once
+func (u *Usecase) Upload(ctx context.Context, kind model.FileKind, data []byte) (file model.File, err error) {
+	rule, ok := rules[kind]
+	if !ok {
+		return file, model.ErrUnsupportedFileType
+	}
+
+	if len(data) > rule.maxSize {
+		return file, fmt.Errorf("%w, max %d bytes", model.ErrFileTooLarge, rule.maxSize)
+	}
+
+	contentType := http.DetectContentType(data)
+	ext, ok := rule.types[contentType]
+	if !ok {
+		return file, fmt.Errorf("%w: %s", model.ErrUnsupportedFileType, contentType)
+	}
+
+	sum := sha256.Sum256(data)
+	file = model.File{
+		Kind:        kind,
+		ContentType: contentType,
+		Size:        len(data),
+		SHA256:      hex.EncodeToString(sum[:]),
+	}
+	file.Key = fmt.Sprintf("uploads/%s/%s%s", rule.dir, file.SHA256, ext)
+
+	err = u.store.Put(ctx, file.Key, data)
+	if err != nil {
+		return file, fmt.Errorf("failed to store file: %w", err)
+	}
+
+	file.URL = u.store.URL(file.Key)
+	file.DownloadURL = u.DownloadURL(file.Key)
+
+	return file, nil
+}
+
+// Verify checks that url is a file of kind uploaded to this service and return its key
+func (u *Usecase) Verify(ctx context.Context, url string, kind model.FileKind) (key string, err error) {
+	rule, ok := rules[kind]
+	if !ok {
+		return key, model.ErrInvalidFile
+	}
+
+	key, ok = strings.CutPrefix(url, u.store.URL(""))
+	if !ok || !strings.HasPrefix(key, "uploads/"+rule.dir+"/") {
+		return "", model.ErrInvalidFile
+	}
+
+	_, err = u.store.Get(ctx, key)
+	if errors.Is(err, blob.ErrNotFound) || errors.Is(err, blob.ErrInvalidKey) {
+		return "", model.ErrInvalidFile
+	}
+	if err != nil {
+		return "", err
+	}
+
+	return key, nil
+}
+
+// DownloadURL is the URL of key with a signature that expires after the TTL, empty when there is no key
+func (u *Usecase) DownloadURL(key string) string {
+	if key == "" {
+		return ""
+	}
+
+	expires := u.now().Add(u.ttl).Unix()
+	return fmt.Sprintf("%s?expires=%d&signature=%s", u.store.URL(key), expires, u.sign(key, expires))
+}
+
+// Download get the file of key when the signature of download URL is valid and not expired
+func (u *Usecase) Download(ctx context.Context, param model.Download) (data []byte, contentType string, err error) {
+	if u.now().Unix() > param.Expires || !hmac.Equal([]byte(param.Signature), []byte(u.sign(param.Key, param.Expires))) {
+		return data, contentType, model.ErrInvalidDownloadSignature
+	}
+
+	data, err = u.store.Get(ctx, param.Key)
+	if errors.Is(err, blob.ErrNotFound) || errors.Is(err, blob.ErrInvalidKey) {
+		return data, contentType, model.ErrNotFound
+	}
+	if err != nil {
+		return data, contentType, err
+	}
+
+	return data, http.DetectContentType(data), nil
+}
+
+func (u *Usecase) sign(key string, expires int64) string {
+	mac := hmac.New(sha256.New, u.signingKey)
+	mac.Write([]byte(key + "\n" + strconv.FormatInt(expires, 10)))
+	return hex.EncodeToString(mac.Sum(nil))
+}
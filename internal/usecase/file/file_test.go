@@ -0,0 +1,116 @@
+package file
+
+import (
+	"bytes"
+	"context"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"simple-app/internal/model"
+	"simple-app/internal/pkg/blob"
+	"simple-app/internal/pkg/pdf"
+
+	"github.com/stretchr/testify/require"
+)
+
+var png = append([]byte("\x89PNG\r\n\x1a\n"), bytes.Repeat([]byte{0}, 64)...)
+
+func newUsecase(t *testing.T) *Usecase {
+	return New(blob.NewLocal(t.TempDir(), "http://localhost:4040/files"), "secret", time.Minute)
+}
+
+func TestUpload(t *testing.T) {
+	testCases := []struct {
+		name     string
+		kind     model.FileKind
+		data     []byte
+		wantType string
+		wantErr  error
+	}{
+		{name: "picture proof", kind: model.FilePictureProof, data: png, wantType: "image/png"},
+		{name: "signed agreement", kind: model.FileSignedAgreement, data: pdf.FromText("signed"), wantType: "application/pdf"},
+		{name: "pdf as picture proof", kind: model.FilePictureProof, data: pdf.FromText("signed"), wantErr: model.ErrUnsupportedFileType},
+		{name: "text", kind: model.FileSignedAgreement, data: []byte("hello"), wantErr: model.ErrUnsupportedFileType},
+		{name: "too large", kind: model.FilePictureProof, data: append(png, make([]byte, 5<<20)...), wantErr: model.ErrFileTooLarge},
+		{name: "unknown kind", kind: "avatar", data: png, wantErr: model.ErrUnsupportedFileType},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+			uc := newUsecase(t)
+
+			file, err := uc.Upload(ctx, tc.kind, tc.data)
+			if tc.wantErr != nil {
+				require.ErrorIs(t, err, tc.wantErr)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, tc.wantType, file.ContentType)
+			require.Equal(t, "http://localhost:4040/files/"+file.Key, file.URL)
+			require.True(t, strings.HasPrefix(file.DownloadURL, file.URL+"?expires="))
+			key, err := uc.Verify(ctx, file.URL, tc.kind)
+			require.NoError(t, err)
+			require.Equal(t, file.Key, key)
+		})
+	}
+}
+
+func TestVerify(t *testing.T) {
+	ctx := context.Background()
+	uc := newUsecase(t)
+
+	file, err := uc.Upload(ctx, model.FilePictureProof, png)
+	require.NoError(t, err)
+
+	key, err := uc.Verify(ctx, file.URL, model.FilePictureProof)
+	require.NoError(t, err)
+	require.Equal(t, file.Key, key)
+
+	for _, url := range []string{
+		"http://example.com/proof.png",
+		"http://localhost:4040/files/uploads/picture-proofs/missing.png",
+		"http://localhost:4040/files/uploads/picture-proofs/../../etc/passwd",
+	} {
+		_, err = uc.Verify(ctx, url, model.FilePictureProof)
+		require.ErrorIs(t, err, model.ErrInvalidFile, url)
+	}
+
+	_, err = uc.Verify(ctx, file.URL, model.FileSignedAgreement)
+	require.ErrorIs(t, err, model.ErrInvalidFile)
+	require.Empty(t, uc.DownloadURL(""))
+}
+
+func TestDownload(t *testing.T) {
+	ctx := context.Background()
+	uc := newUsecase(t)
+
+	file, err := uc.Upload(ctx, model.FilePictureProof, png)
+	require.NoError(t, err)
+
+	u, err := url.Parse(file.DownloadURL)
+	require.NoError(t, err)
+	expires, err := strconv.ParseInt(u.Query().Get("expires"), 10, 64)
+	require.NoError(t, err)
+	signature := u.Query().Get("signature")
+
+	data, contentType, err := uc.Download(ctx, model.Download{Key: file.Key, Expires: expires, Signature: signature})
+	require.NoError(t, err)
+	require.Equal(t, png, data)
+	require.Equal(t, "image/png", contentType)
+
+	// the signature is only valid for its key and expiry
+	_, _, err = uc.Download(ctx, model.Download{Key: "agreement-letters/v1/x.pdf", Expires: expires, Signature: signature})
+	require.ErrorIs(t, err, model.ErrInvalidDownloadSignature)
+
+	_, _, err = uc.Download(ctx, model.Download{Key: file.Key, Expires: expires + 1, Signature: signature})
+	require.ErrorIs(t, err, model.ErrInvalidDownloadSignature)
+
+	uc.now = func() time.Time { return time.Now().Add(2 * time.Minute) }
+	_, _, err = uc.Download(ctx, model.Download{Key: file.Key, Expires: expires, Signature: signature})
+	require.ErrorIs(t, err, model.ErrInvalidDownloadSignature)
+}
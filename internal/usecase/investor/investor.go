@@ -0,0 +1,100 @@
+package investor
+
+import (
+	"context"
+	"simple-app/internal/model"
+)
+
+// Usecase instance struct for investor
+type Usecase struct {
+	tx           txManager
+	investorRepo investorRepo
+}
+
+// txManager runs fn inside a transaction, repositories called with the ctx given to fn join the transaction
+type txManager interface {
+	WithTx(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+type investorRepo interface {
+	GetByID(ctx context.Context, ID int) (investor model.Investor, err error)
+	GetWalletByInvestorID(ctx context.Context, ID int) (wallet model.Wallet, err error)
+	GetWalletForUpdate(ctx context.Context, ID int) (wallet model.Wallet, err error)
+	GetWalletTransactionByInvestorID(ctx context.Context, ID int) (transactions []model.WalletTransaction, err error)
+	GetPositionByInvestorID(ctx context.Context, ID int) (positions []model.Position, err error)
+
+	Create(ctx context.Context, param model.Investor) (data model.Investor, err error)
+	UpdateWallet(ctx context.Context, wallet model.Wallet) (data model.Wallet, err error)
+	CreateWalletTransaction(ctx context.Context, param model.WalletTransaction) (data model.WalletTransaction, err error)
+}
+
+// New will instantiate new investor usecase
+func New(tx txManager, investorRepo investorRepo) *Usecase {
+	return &Usecase{
+		tx:           tx,
+		investorRepo: investorRepo,
+	}
+}
+
+// Register investor together with an empty wallet
+func (u *Usecase) Register(ctx context.Context, param model.Investor) (data model.Investor, err error) {
+	err = u.tx.WithTx(ctx, func(ctx context.Context) error {
+		data, err = u.investorRepo.Create(ctx, param)
+		return err
+	})
+	if err != nil {
+		return data, err
+	}
+
+	return data, nil
+}
+
+// Get get investor by ID
+func (u *Usecase) Get(ctx context.Context, id int) (data model.Investor, err error) {
+	return u.investorRepo.GetByID(ctx, id)
+}
+
+// GetWallet get wallet of investor and its ledger
+func (u *Usecase) GetWallet(ctx context.Context, id int) (wallet model.Wallet, transactions []model.WalletTransaction, err error) {
+	wallet, err = u.investorRepo.GetWalletByInvestorID(ctx, id)
+	if err != nil {
+		return wallet, transactions, err
+	}
+
+	transactions, err = u.investorRepo.GetWalletTransactionByInvestorID(ctx, id)
+	if err != nil {
+		return wallet, transactions, err
+	}
+
+	return wallet, transactions, nil
+}
+
+// Deposit add money to the wallet of investor
+func (u *Usecase) Deposit(ctx context.Context, param model.WalletFund) (wallet model.Wallet, err error) {
+	return u.fund(ctx, model.WalletTransaction{
+		InvestorID: param.InvestorID,
+		Type:       model.WalletDeposit,
+		Amount:     param.Amount,
+	})
+}
+
+// Withdraw take money out of the wallet of investor, held money can not be withdrawn
+func (u *Usecase) Withdraw(ctx context.Context, param model.WalletFund) (wallet model.Wallet, err error) {
+	return u.fund(ctx, model.WalletTransaction{
+		InvestorID: param.InvestorID,
+		Type:       model.WalletWithdrawal,
+		Amount:     param.Amount,
+	})
+}
+
+func (u *Usecase) fund(ctx context.Context, param model.WalletTransaction) (wallet model.Wallet, err error) {
+	err = u.tx.WithTx(ctx, func(ctx context.Context) error {
+		wallet, err = u.apply(ctx, param)
+		return err
+	})
+	if err != nil {
+		return wallet, err
+	}
+
+	return wallet, nil
+}
@@ -0,0 +1,45 @@
+package investor
+
+import (
+	"context"
+	"simple-app/internal/model"
+	"simple-app/internal/pkg/money"
+)
+
+// GetPortfolio get every investment of investor that is not withdrawn, with the return expected from the ROI of the loan
+// and the principal and return paid out so far. It is read from the follower so it may lag behind the latest payouts
+func (u *Usecase) GetPortfolio(ctx context.Context, id int) (portfolio model.Portfolio, err error) {
+
+	// make sure the investor exists
+	_, err = u.investorRepo.GetByID(ctx, id)
+	if err != nil {
+		return portfolio, err
+	}
+
+	positions, err := u.investorRepo.GetPositionByInvestorID(ctx, id)
+	if err != nil {
+		return portfolio, err
+	}
+
+	portfolio = model.Portfolio{
+		InvestorID:             id,
+		TotalInvested:          money.FromMinor(0),
+		TotalExpectedReturn:    money.FromMinor(0),
+		TotalPrincipalReceived: money.FromMinor(0),
+		TotalReturnReceived:    money.FromMinor(0),
+		Positions:              make([]model.Position, 0, len(positions)),
+	}
+
+	for _, position := range positions {
+		position.StatusStr = position.Status.ToString()
+		position.ExpectedReturn = position.Amount.MulRate(position.Roi)
+
+		portfolio.TotalInvested = portfolio.TotalInvested.Add(position.Amount)
+		portfolio.TotalExpectedReturn = portfolio.TotalExpectedReturn.Add(position.ExpectedReturn)
+		portfolio.TotalPrincipalReceived = portfolio.TotalPrincipalReceived.Add(position.PrincipalReceived)
+		portfolio.TotalReturnReceived = portfolio.TotalReturnReceived.Add(position.ReturnReceived)
+		portfolio.Positions = append(portfolio.Positions, position)
+	}
+
+	return portfolio, nil
+}
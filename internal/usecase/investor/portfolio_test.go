@@ -0,0 +1,65 @@
+package investor
+
+import (
+	"context"
+	"testing"
+
+	"simple-app/internal/model"
+	"simple-app/internal/pkg/money"
+
+	"github.com/stretchr/testify/require"
+)
+
+type fakePortfolioRepo struct {
+	investorRepo
+
+	positions []model.Position
+}
+
+func (r fakePortfolioRepo) GetByID(ctx context.Context, ID int) (investor model.Investor, err error) {
+	if ID != 1 {
+		return investor, model.ErrNotFound
+	}
+	return model.Investor{ID: ID}, nil
+}
+
+func (r fakePortfolioRepo) GetPositionByInvestorID(ctx context.Context, ID int) (positions []model.Position, err error) {
+	return r.positions, nil
+}
+
+func TestGetPortfolio(t *testing.T) {
+	uc := New(nil, fakePortfolioRepo{positions: []model.Position{
+		{
+			InvestID:          1,
+			LoanID:            1,
+			Status:            model.DISBURSED,
+			Roi:               0.1,
+			Amount:            money.MustParse("1000"),
+			PrincipalReceived: money.MustParse("250"),
+			ReturnReceived:    money.MustParse("25"),
+		},
+		{
+			InvestID:          2,
+			LoanID:            2,
+			Status:            model.APPROVED,
+			Roi:               0.125,
+			Amount:            money.MustParse("333.33"),
+			PrincipalReceived: money.MustParse("0"),
+			ReturnReceived:    money.MustParse("0"),
+		},
+	}})
+
+	portfolio, err := uc.GetPortfolio(context.Background(), 1)
+	require.NoError(t, err)
+	require.Len(t, portfolio.Positions, 2)
+	require.Equal(t, "disbursed", portfolio.Positions[0].StatusStr)
+	require.Equal(t, money.MustParse("100"), portfolio.Positions[0].ExpectedReturn)
+	require.Equal(t, money.MustParse("41.67"), portfolio.Positions[1].ExpectedReturn)
+	require.Equal(t, money.MustParse("1333.33"), portfolio.TotalInvested)
+	require.Equal(t, money.MustParse("141.67"), portfolio.TotalExpectedReturn)
+	require.Equal(t, money.MustParse("250"), portfolio.TotalPrincipalReceived)
+	require.Equal(t, money.MustParse("25"), portfolio.TotalReturnReceived)
+
+	_, err = uc.GetPortfolio(context.Background(), 2)
+	require.ErrorIs(t, err, model.ErrNotFound)
+}
@@ -0,0 +1,114 @@
+package investor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"simple-app/internal/model"
+	"simple-app/internal/pkg/money"
+)
+
+// Hold reserve the amount of investment in the wallet of investor.
+// It must be called inside the investment transaction so the money is never held for a rolled back investment
+func (u *Usecase) Hold(ctx context.Context, invest model.Invest) (err error) {
+	_, err = u.apply(ctx, investTransaction(model.WalletHold, invest))
+	return err
+}
+
+// Release give back the held amount of investment that will not be disbursed, e.g. withdrawn, cancelled or expired
+func (u *Usecase) Release(ctx context.Context, invest model.Invest) (err error) {
+	_, err = u.apply(ctx, investTransaction(model.WalletRelease, invest))
+	return err
+}
+
+// Capture take the held amount of investment out of the wallet when the loan is disbursed to borrower
+func (u *Usecase) Capture(ctx context.Context, invest model.Invest) (err error) {
+	_, err = u.apply(ctx, investTransaction(model.WalletCapture, invest))
+	return err
+}
+
+// Credit add the payouts of a repayment to the wallet of investors
+func (u *Usecase) Credit(ctx context.Context, payouts []model.Payout) (err error) {
+	for _, payout := range payouts {
+		loanID, investID := payout.LoanID, payout.InvestID
+		_, err = u.apply(ctx, model.WalletTransaction{
+			InvestorID: payout.InvestorID,
+			Type:       model.WalletPayout,
+			Amount:     payout.Amount,
+			LoanID:     &loanID,
+			InvestID:   &investID,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func investTransaction(txType model.WalletTransactionType, invest model.Invest) model.WalletTransaction {
+	return model.WalletTransaction{
+		InvestorID: invest.InvestorID,
+		Type:       txType,
+		Amount:     invest.Amount,
+		LoanID:     &invest.LoanID,
+		InvestID:   &invest.ID,
+	}
+}
+
+// apply lock the wallet, move the balance and held amount by the transaction type and record it in the ledger,
+// it must run inside the transaction of ctx
+func (u *Usecase) apply(ctx context.Context, param model.WalletTransaction) (wallet model.Wallet, err error) {
+	wallet, err = u.investorRepo.GetWalletForUpdate(ctx, param.InvestorID)
+	if errors.Is(err, model.ErrNotFound) {
+		return wallet, model.ErrInvestorNotRegistered
+	}
+	if err != nil {
+		return wallet, err
+	}
+
+	balance, held := movement(param.Type, param.Amount)
+	wallet.Balance = wallet.Balance.Add(balance)
+	wallet.Held = wallet.Held.Add(held)
+
+	if wallet.Held.IsNegative() {
+		return wallet, fmt.Errorf("held amount of investor %d can not be negative", param.InvestorID)
+	}
+
+	if wallet.Available().IsNegative() {
+		return wallet, model.ErrInsufficientBalance
+	}
+
+	wallet, err = u.investorRepo.UpdateWallet(ctx, wallet)
+	if err != nil {
+		return wallet, err
+	}
+
+	_, err = u.investorRepo.CreateWalletTransaction(ctx, param)
+	if err != nil {
+		return wallet, err
+	}
+
+	return wallet, nil
+}
+
+// movement returns how much the balance and held amount change for the transaction type
+func movement(txType model.WalletTransactionType, amount money.Money) (balance, held money.Money) {
+	zero := money.FromMinor(0)
+	negative := zero.Sub(amount)
+
+	switch txType {
+	case model.WalletDeposit, model.WalletPayout:
+		return amount, zero
+	case model.WalletWithdrawal:
+		return negative, zero
+	case model.WalletHold:
+		return zero, amount
+	case model.WalletRelease:
+		return zero, negative
+	case model.WalletCapture:
+		return negative, negative
+	}
+
+	return zero, zero
+}
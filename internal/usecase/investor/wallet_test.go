@@ -0,0 +1,144 @@
+package investor
+
+import (
+	"context"
+	"testing"
+
+	"simple-app/internal/model"
+	"simple-app/internal/pkg/money"
+
+	"github.com/stretchr/testify/require"
+)
+
+// fakeRepo keeps wallets and the ledger in memory
+type fakeRepo struct {
+	investorRepo
+
+	wallets      map[int]model.Wallet
+	transactions []model.WalletTransaction
+}
+
+func (r *fakeRepo) GetWalletForUpdate(ctx context.Context, ID int) (wallet model.Wallet, err error) {
+	wallet, ok := r.wallets[ID]
+	if !ok {
+		return wallet, model.ErrNotFound
+	}
+	return wallet, nil
+}
+
+func (r *fakeRepo) UpdateWallet(ctx context.Context, wallet model.Wallet) (data model.Wallet, err error) {
+	r.wallets[wallet.InvestorID] = wallet
+	return wallet, nil
+}
+
+func (r *fakeRepo) CreateWalletTransaction(ctx context.Context, param model.WalletTransaction) (data model.WalletTransaction, err error) {
+	r.transactions = append(r.transactions, param)
+	return param, nil
+}
+
+func TestWalletMovement(t *testing.T) {
+	testCases := []struct {
+		name        string
+		txType      model.WalletTransactionType
+		amount      string
+		investorID  int
+		wantBalance string
+		wantHeld    string
+		wantErr     error
+	}{
+		{
+			name:        "deposit",
+			txType:      model.WalletDeposit,
+			amount:      "100",
+			investorID:  1,
+			wantBalance: "1100",
+			wantHeld:    "300",
+		},
+		{
+			name:        "withdraw available",
+			txType:      model.WalletWithdrawal,
+			amount:      "700",
+			investorID:  1,
+			wantBalance: "300",
+			wantHeld:    "300",
+		},
+		{
+			name:       "withdraw held money",
+			txType:     model.WalletWithdrawal,
+			amount:     "700.01",
+			investorID: 1,
+			wantErr:    model.ErrInsufficientBalance,
+		},
+		{
+			name:        "hold",
+			txType:      model.WalletHold,
+			amount:      "700",
+			investorID:  1,
+			wantBalance: "1000",
+			wantHeld:    "1000",
+		},
+		{
+			name:       "hold more than available",
+			txType:     model.WalletHold,
+			amount:     "700.01",
+			investorID: 1,
+			wantErr:    model.ErrInsufficientBalance,
+		},
+		{
+			name:        "release",
+			txType:      model.WalletRelease,
+			amount:      "300",
+			investorID:  1,
+			wantBalance: "1000",
+			wantHeld:    "0",
+		},
+		{
+			name:        "capture",
+			txType:      model.WalletCapture,
+			amount:      "300",
+			investorID:  1,
+			wantBalance: "700",
+			wantHeld:    "0",
+		},
+		{
+			name:        "payout",
+			txType:      model.WalletPayout,
+			amount:      "125.35",
+			investorID:  1,
+			wantBalance: "1125.35",
+			wantHeld:    "300",
+		},
+		{
+			name:       "unknown investor",
+			txType:     model.WalletDeposit,
+			amount:     "100",
+			investorID: 2,
+			wantErr:    model.ErrInvestorNotRegistered,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			repo := &fakeRepo{wallets: map[int]model.Wallet{
+				1: {InvestorID: 1, Balance: money.MustParse("1000"), Held: money.MustParse("300")},
+			}}
+			uc := New(nil, repo)
+
+			wallet, err := uc.apply(context.Background(), model.WalletTransaction{
+				InvestorID: tc.investorID,
+				Type:       tc.txType,
+				Amount:     money.MustParse(tc.amount),
+			})
+			if tc.wantErr != nil {
+				require.ErrorIs(t, err, tc.wantErr)
+				require.Empty(t, repo.transactions)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, money.MustParse(tc.wantBalance), wallet.Balance)
+			require.Equal(t, money.MustParse(tc.wantHeld), wallet.Held)
+			require.Len(t, repo.transactions, 1)
+		})
+	}
+}
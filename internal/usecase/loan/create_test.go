@@ -0,0 +1,112 @@
+package loan
+
+import (
+	"context"
+	"testing"
+
+	"simple-app/internal/model"
+	"simple-app/internal/pkg/money"
+
+	"github.com/stretchr/testify/require"
+)
+
+// fakeBorrowerRepo keeps borrowers in memory
+type fakeBorrowerRepo struct {
+	borrowers map[int]model.Borrower
+}
+
+func (r fakeBorrowerRepo) GetByID(ctx context.Context, ID int) (borrower model.Borrower, err error) {
+	return r.GetByIDForUpdate(ctx, ID)
+}
+
+func (r fakeBorrowerRepo) GetByIDForUpdate(ctx context.Context, ID int) (borrower model.Borrower, err error) {
+	borrower, ok := r.borrowers[ID]
+	if !ok {
+		return borrower, model.ErrNotFound
+	}
+	return borrower, nil
+}
+
+func TestCreate(t *testing.T) {
+	borrowers := fakeBorrowerRepo{borrowers: map[int]model.Borrower{
+		1: {ID: 1, KYCStatus: model.KYCVerified, CreditLimit: money.MustParse("5000")},
+		2: {ID: 2, KYCStatus: model.KYCPending, CreditLimit: money.MustParse("5000")},
+	}}
+
+	testCases := []struct {
+		name      string
+		borrower  int
+		principal string
+		existing  []model.Loan
+		wantErr   error
+	}{
+		{
+			name:      "within limit",
+			borrower:  1,
+			principal: "5000",
+		},
+		{
+			name:      "unknown borrower",
+			borrower:  3,
+			principal: "1000",
+			wantErr:   model.ErrBorrowerNotRegistered,
+		},
+		{
+			name:      "not verified",
+			borrower:  2,
+			principal: "1000",
+			wantErr:   model.ErrBorrowerNotVerified,
+		},
+		{
+			name:      "exceeds limit",
+			borrower:  1,
+			principal: "5000.01",
+			wantErr:   model.ErrCreditLimitExceeded,
+		},
+		{
+			name:      "exceeds limit with open loans",
+			borrower:  1,
+			principal: "2000",
+			existing: []model.Loan{
+				{ID: 100, BorrowerID: 1, PrincipalAmount: money.MustParse("2000"), Status: model.APPROVED},
+				{ID: 101, BorrowerID: 1, PrincipalAmount: money.MustParse("1500"), Status: model.DISBURSED},
+			},
+			wantErr: model.ErrCreditLimitExceeded,
+		},
+		{
+			name:      "closed loans are not counted",
+			borrower:  1,
+			principal: "5000",
+			existing: []model.Loan{
+				{ID: 100, BorrowerID: 1, PrincipalAmount: money.MustParse("2000"), Status: model.REPAID},
+				{ID: 101, BorrowerID: 1, PrincipalAmount: money.MustParse("1500"), Status: model.CANCELLED},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+			uc, repo := newMemoryUsecase(tc.existing...)
+			uc.borrowerRepo = borrowers
+
+			data, err := uc.Create(ctx, model.Loan{
+				BorrowerID:      tc.borrower,
+				PrincipalAmount: money.MustParse(tc.principal),
+			})
+
+			page, listErr := repo.GetList(ctx, model.LoanListParam{})
+			require.NoError(t, listErr)
+			if tc.wantErr != nil {
+				require.ErrorIs(t, err, tc.wantErr)
+				require.Equal(t, len(tc.existing), page.Total)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, len(tc.existing)+1, page.Total)
+			require.Equal(t, "letter-1", data.AgreementLetterKey)
+			require.Equal(t, "http://localhost:4040/files/letter-1?signature=valid", data.AgreementLetterURL)
+		})
+	}
+}
@@ -0,0 +1,92 @@
+package loan
+
+import (
+	"context"
+	"log"
+	"simple-app/internal/model"
+	"simple-app/internal/pkg/reqctx"
+	"time"
+)
+
+// DefaultFundingPeriod is how long an approved loan waits for investors when no period is configured
+const DefaultFundingPeriod = 14 * 24 * time.Hour
+
+const expiredReason = "funding deadline passed"
+
+// Expire moves approved loans that are not fully funded before their deadline to "expired",
+// releases their investments and notifies the investors through the outbox. It returns the ID of expired loans
+func (u *Usecase) Expire(ctx context.Context, now time.Time) (ids []int, err error) {
+	ctx = reqctx.WithActor(ctx, "system", 0)
+
+	loans, err := u.loanRepo.GetOverdueFunding(ctx, now)
+	if err != nil {
+		return ids, err
+	}
+
+	for _, loan := range loans {
+		released, err := u.expire(ctx, loan.ID, now)
+		if err != nil {
+			// keep going, the loan is picked up again on the next run
+			log.Printf("failed to expire loan %d, err = %v", loan.ID, err)
+			continue
+		}
+
+		if released == nil {
+			continue
+		}
+
+		ids = append(ids, loan.ID)
+	}
+
+	return ids, nil
+}
+
+// expire expires one loan, the deadline is checked again under the row lock
+// since the loan may be invested or cancelled after it was listed.
+// Released is nil when the loan does not need to expire anymore
+func (u *Usecase) expire(ctx context.Context, id int, now time.Time) (released []model.Withdraw, err error) {
+	err = u.tx.WithTx(ctx, func(ctx context.Context) error {
+		loan, err := u.loanRepo.GetByIDForUpdate(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		if loan.FundingDeadline == nil || !loan.FundingDeadline.Before(now) || !loan.Status.CanTransitionTo(model.EXPIRED) {
+			released = nil
+			return nil
+		}
+
+		released, err = u.releaseInvestments(ctx, id, expiredReason)
+		if err != nil {
+			return err
+		}
+
+		loan.Status = model.EXPIRED
+		_, err = u.loanRepo.UpdateStatusWithReason(ctx, loan, expiredReason)
+		if err != nil {
+			return err
+		}
+
+		// the investors are only told once the loan is expired
+		messages := make([]model.OutboxMessage, 0, len(released))
+		for _, withdraw := range released {
+			messages = append(messages, loanExpiredMessage(loan, withdraw))
+		}
+
+		err = u.outbox.Enqueue(ctx, messages)
+		if err != nil {
+			return err
+		}
+
+		if released == nil {
+			released = []model.Withdraw{}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return released, nil
+}
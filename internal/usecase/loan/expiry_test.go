@@ -0,0 +1,125 @@
+package loan
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"simple-app/internal/model"
+	"simple-app/internal/pkg/money"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestExpire(t *testing.T) {
+	now := time.Now()
+	past := now.Add(-time.Hour)
+	future := now.Add(time.Hour)
+
+	testCases := []struct {
+		name          string
+		status        model.LoanStatus
+		deadline      *time.Time
+		wantExpired   bool
+		wantStatus    model.LoanStatus
+		wantReceivers []int
+	}{
+		{
+			name:          "deadline passed",
+			status:        model.APPROVED,
+			deadline:      &past,
+			wantExpired:   true,
+			wantStatus:    model.EXPIRED,
+			wantReceivers: []int{2, 3},
+		},
+		{
+			name:       "deadline not passed",
+			status:     model.APPROVED,
+			deadline:   &future,
+			wantStatus: model.APPROVED,
+		},
+		{
+			name:       "no deadline",
+			status:     model.APPROVED,
+			wantStatus: model.APPROVED,
+		},
+		{
+			name:       "already invested",
+			status:     model.INVESTED,
+			deadline:   &past,
+			wantStatus: model.INVESTED,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+			loan := memoryLoan(tc.status)
+			loan.FundingDeadline = tc.deadline
+			uc, repo := newMemoryUsecase(loan)
+			invest(t, repo)
+
+			ids, err := uc.Expire(ctx, now)
+			require.NoError(t, err)
+
+			var receivers []int
+			for _, message := range uc.outbox.(*recordOutbox).messages {
+				require.Equal(t, model.TopicLoanExpired, message.Topic)
+				receivers = append(receivers, message.RecipientID)
+			}
+			require.Equal(t, tc.wantReceivers, receivers)
+
+			loan, err = repo.GetByID(ctx, 1)
+			require.NoError(t, err)
+			require.Equal(t, tc.wantStatus, loan.Status)
+
+			invests, err := repo.GetInvestByID(ctx, 1)
+			require.NoError(t, err)
+			if tc.wantExpired {
+				require.Equal(t, []int{1}, ids)
+				require.Empty(t, invests)
+			} else {
+				require.Empty(t, ids)
+				require.Len(t, invests, 2)
+			}
+		})
+	}
+}
+
+func TestExpireOutboxFailure(t *testing.T) {
+	ctx := context.Background()
+	past := time.Now().Add(-time.Hour)
+	loan := memoryLoan(model.APPROVED)
+	loan.FundingDeadline = &past
+	uc, repo := newMemoryUsecase(loan)
+	invest(t, repo)
+	uc.outbox = &recordOutbox{err: errOutbox}
+
+	// the investors can not be told, so the loan is left for the next run
+	ids, err := uc.Expire(ctx, time.Now())
+	require.NoError(t, err)
+	require.Empty(t, ids)
+
+	loan, err = repo.GetByID(ctx, 1)
+	require.NoError(t, err)
+	require.Equal(t, model.APPROVED, loan.Status)
+
+	invests, err := repo.GetInvestByID(ctx, 1)
+	require.NoError(t, err)
+	require.Len(t, invests, 2)
+}
+
+func TestInvestAfterFundingDeadline(t *testing.T) {
+	ctx := context.Background()
+	past := time.Now().Add(-time.Hour)
+	loan := memoryLoan(model.APPROVED)
+	loan.FundingDeadline = &past
+	uc, repo := newMemoryUsecase(loan)
+
+	_, _, _, err := uc.Invest(ctx, model.Invest{LoanID: 1, InvestorID: 1, Amount: money.MustParse("100"), Version: 1})
+	require.ErrorIs(t, err, model.ErrFundingClosed)
+
+	invests, err := repo.GetInvestByID(ctx, 1)
+	require.NoError(t, err)
+	require.Empty(t, invests)
+}
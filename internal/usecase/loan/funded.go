@@ -0,0 +1,47 @@
+package loan
+
+import (
+	"context"
+	"simple-app/internal/model"
+)
+
+// fullyFunded runs inside the transaction of the investment that fills the loan. It moves the loan to "invested"
+// and queues a letter of agreement, personalised with the share of the investor, for every investor of the loan.
+// Investors are read again inside the transaction so the one whose investment completed the funding is included
+func (u *Usecase) fullyFunded(ctx context.Context, loan model.Loan) (model.Loan, error) {
+	invests, err := u.loanRepo.GetInvestByIDTx(ctx, loan.ID)
+	if err != nil {
+		return loan, err
+	}
+
+	borrower, err := u.borrowerRepo.GetByID(ctx, loan.BorrowerID)
+	if err != nil {
+		return loan, err
+	}
+
+	// letters are stored by their hash, a letter of a transaction that rolls back is only an unused document
+	messages := make([]model.OutboxMessage, 0, len(invests))
+	for _, invest := range invests {
+		letter, err := u.agreementLetter.GenerateForInvestor(ctx, loan, borrower, invest)
+		if err != nil {
+			return loan, err
+		}
+
+		messages = append(messages, agreementLetterMessage(loan, invest, letter))
+	}
+
+	// the letters are only sent once the transaction is committed
+	err = u.outbox.Enqueue(ctx, messages)
+	if err != nil {
+		return loan, err
+	}
+
+	// update status of loan to be "invested"
+	loan.Status = model.INVESTED
+	_, err = u.loanRepo.UpdateStatus(ctx, loan)
+	if err != nil {
+		return loan, err
+	}
+
+	return loan, nil
+}
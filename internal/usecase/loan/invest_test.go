@@ -0,0 +1,221 @@
+package loan
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"simple-app/internal/model"
+	"simple-app/internal/pkg/money"
+
+	"github.com/stretchr/testify/require"
+)
+
+type noopWallet struct{}
+
+func (noopWallet) Hold(context.Context, model.Invest) error     { return nil }
+func (noopWallet) Release(context.Context, model.Invest) error  { return nil }
+func (noopWallet) Capture(context.Context, model.Invest) error  { return nil }
+func (noopWallet) Credit(context.Context, []model.Payout) error { return nil }
+
+type noopLetter struct{}
+
+func (noopLetter) Generate(ctx context.Context, loan model.Loan, borrower model.Borrower) (model.AgreementLetter, error) {
+	return model.AgreementLetter{Key: fmt.Sprintf("letter-%d", borrower.ID)}, nil
+}
+
+func (noopLetter) GenerateForInvestor(ctx context.Context, loan model.Loan, borrower model.Borrower, invest model.Invest) (model.AgreementLetter, error) {
+	return model.AgreementLetter{Key: fmt.Sprintf("letter-%d-%d", loan.ID, invest.InvestorID)}, nil
+}
+
+func (noopLetter) Certify(ctx context.Context, loan model.Loan, signature model.Signature) (model.AgreementLetter, error) {
+	return model.AgreementLetter{Key: fmt.Sprintf("signed-%d", loan.ID), SHA256: "certificate"}, nil
+}
+
+var borrowers = fakeBorrowerRepo{borrowers: map[int]model.Borrower{
+	1: {ID: 1, Name: "Budi", KYCStatus: model.KYCVerified},
+}}
+
+// recordOutbox keeps the enqueued messages, or fails every enqueue when err is set
+type recordOutbox struct {
+	mu       sync.Mutex
+	err      error
+	messages []model.OutboxMessage
+}
+
+func (o *recordOutbox) Enqueue(ctx context.Context, messages []model.OutboxMessage) error {
+	if o.err != nil {
+		return o.err
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.messages = append(o.messages, messages...)
+	return nil
+}
+
+// TestInvestConcurrent runs the investments on the memory repository, whose transactions run one by one.
+// The SQL repository gets the same order from `SELECT ... FOR UPDATE`, which its contract test checks
+func TestInvestConcurrent(t *testing.T) {
+	testCases := []struct {
+		name        string
+		principal   string
+		amount      string
+		investors   int
+		wantSuccess int
+		wantStatus  model.LoanStatus
+	}{
+		{
+			name:        "fully funded",
+			principal:   "1000",
+			amount:      "100",
+			investors:   20,
+			wantSuccess: 10,
+			wantStatus:  model.INVESTED,
+		},
+		{
+			name:        "remaining amount too small",
+			principal:   "1000",
+			amount:      "300",
+			investors:   10,
+			wantSuccess: 3,
+			wantStatus:  model.APPROVED,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+			loan := memoryLoan(model.APPROVED)
+			loan.PrincipalAmount = money.MustParse(tc.principal)
+			uc, repo := newMemoryUsecase(loan)
+
+			var (
+				wg      sync.WaitGroup
+				success int32
+			)
+			for i := 0; i < tc.investors; i++ {
+				wg.Add(1)
+				go func(investorID int) {
+					defer wg.Done()
+
+					// like a client, the loan is read again when it has been changed meanwhile
+					for {
+						loan, err := repo.GetByID(ctx, 1)
+						if err != nil {
+							return
+						}
+
+						_, _, _, err = uc.Invest(ctx, model.Invest{
+							LoanID:     1,
+							InvestorID: investorID,
+							Amount:     money.MustParse(tc.amount),
+							Version:    loan.Version,
+						})
+						if errors.Is(err, model.ErrVersionConflict) {
+							continue
+						}
+						if err == nil {
+							atomic.AddInt32(&success, 1)
+						}
+						return
+					}
+				}(i + 1)
+			}
+			wg.Wait()
+
+			invests, err := repo.GetInvestByID(ctx, 1)
+			require.NoError(t, err)
+			total := money.Sum(investAmounts(invests)...)
+
+			require.LessOrEqual(t, total.Cmp(money.MustParse(tc.principal)), 0)
+			require.Equal(t, tc.wantSuccess, int(success))
+			require.Len(t, invests, tc.wantSuccess)
+
+			loan, err = repo.GetByID(ctx, 1)
+			require.NoError(t, err)
+			require.Equal(t, tc.wantStatus, loan.Status)
+
+			// every investor gets a letter once the loan is fully funded
+			wantMessages := 0
+			if tc.wantStatus == model.INVESTED {
+				wantMessages = tc.wantSuccess
+			}
+			require.Len(t, uc.outbox.(*recordOutbox).messages, wantMessages)
+		})
+	}
+}
+
+func TestInvestExceedsRemaining(t *testing.T) {
+	ctx := context.Background()
+	uc, repo := newMemoryUsecase(memoryLoan(model.APPROVED))
+
+	_, _, _, err := uc.Invest(ctx, model.Invest{LoanID: 1, InvestorID: 1, Amount: money.MustParse("1000.01"), Version: 1})
+	require.ErrorIs(t, err, model.ErrAmountExceedsRemaining)
+
+	invests, err := repo.GetInvestByID(ctx, 1)
+	require.NoError(t, err)
+	require.Empty(t, invests)
+}
+
+func TestInvestEnqueueFailed(t *testing.T) {
+	ctx := context.Background()
+	uc, repo := newMemoryUsecase(memoryLoan(model.APPROVED))
+	uc.outbox = &recordOutbox{err: errOutbox}
+
+	// the letters can not be queued, so the loan must not become invested
+	_, _, _, err := uc.Invest(ctx, model.Invest{LoanID: 1, InvestorID: 1, Amount: money.MustParse("1000"), Version: 1})
+	require.ErrorIs(t, err, errOutbox)
+
+	loan, err := repo.GetByID(ctx, 1)
+	require.NoError(t, err)
+	require.Equal(t, model.APPROVED, loan.Status)
+
+	invests, err := repo.GetInvestByID(ctx, 1)
+	require.NoError(t, err)
+	require.Empty(t, invests)
+}
+
+func TestInvestFullyFunded(t *testing.T) {
+	ctx := context.Background()
+	uc, _ := newMemoryUsecase(memoryLoan(model.APPROVED))
+	outbox := uc.outbox.(*recordOutbox)
+
+	_, _, status, err := uc.Invest(ctx, model.Invest{LoanID: 1, InvestorID: 7, Amount: money.MustParse("600"), Version: 1})
+	require.NoError(t, err)
+	require.Equal(t, "approved", status)
+	require.Empty(t, outbox.messages)
+
+	// the investment that fills the loan
+	// the client still has the version before the first investment
+	_, _, _, err = uc.Invest(ctx, model.Invest{LoanID: 1, InvestorID: 8, Amount: money.MustParse("400"), Version: 1})
+	require.ErrorIs(t, err, model.ErrVersionConflict)
+
+	_, _, status, err = uc.Invest(ctx, model.Invest{LoanID: 1, InvestorID: 8, Amount: money.MustParse("400"), Version: 2})
+	require.NoError(t, err)
+	require.Equal(t, "invested", status)
+	require.Len(t, outbox.messages, 2)
+	require.Equal(t, 4, loanVersion(t, uc, 1))
+
+	for i, want := range []struct {
+		investorID int
+		amount     string
+	}{
+		{investorID: 7, amount: "600.00"},
+		{investorID: 8, amount: "400.00"},
+	} {
+		message := outbox.messages[i]
+		require.Equal(t, model.TopicAgreementLetter, message.Topic)
+		require.Equal(t, want.investorID, message.RecipientID)
+
+		var content model.Message
+		require.NoError(t, json.Unmarshal(message.Payload, &content))
+		require.Equal(t, fmt.Sprintf("letter-1-%d", want.investorID), content.AttachmentKey)
+		require.Contains(t, content.Body, want.amount)
+	}
+}
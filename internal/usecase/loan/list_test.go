@@ -0,0 +1,82 @@
+package loan
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"simple-app/internal/model"
+
+	"github.com/stretchr/testify/require"
+)
+
+// listLoans are loans 1 to 120, the even ones approved, loan 120 has a stored letter and loan 119 a letter kept as URL
+func listLoans() (loans []model.Loan) {
+	for id := 1; id <= 120; id++ {
+		loan := model.Loan{ID: id, BorrowerID: 1, Status: model.PROPOSED, AgreementLetterKey: fmt.Sprintf("agreement-letters/v1/%d.pdf", id)}
+		if id%2 == 0 {
+			loan.Status = model.APPROVED
+		}
+		loans = append(loans, loan)
+	}
+	loans[118].AgreementLetterKey = "http://example.com/letter.pdf"
+
+	return loans
+}
+
+func TestGetList(t *testing.T) {
+	testCases := []struct {
+		name      string
+		param     model.LoanListParam
+		wantLen   int
+		wantTotal int
+		wantFirst int
+	}{
+		{
+			name:      "defaults",
+			wantLen:   20,
+			wantTotal: 120,
+			wantFirst: 120,
+		},
+		{
+			name:      "limit is capped",
+			param:     model.LoanListParam{Sort: "id", Limit: 1000},
+			wantLen:   100,
+			wantTotal: 120,
+			wantFirst: 1,
+		},
+		{
+			name:      "status",
+			param:     model.LoanListParam{Status: "approved"},
+			wantLen:   20,
+			wantTotal: 60,
+			wantFirst: 120,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			uc, _ := newMemoryUsecase(listLoans()...)
+
+			page, err := uc.GetList(context.Background(), tc.param)
+			require.NoError(t, err)
+			require.Len(t, page.Data, tc.wantLen)
+			require.Equal(t, tc.wantTotal, page.Total)
+			require.NotEmpty(t, page.NextCursor)
+			require.Equal(t, tc.wantFirst, page.Data[0].ID)
+			require.Equal(t, page.Data[0].Status.ToString(), page.Data[0].StatusStr)
+		})
+	}
+}
+
+func TestGetListLinks(t *testing.T) {
+	uc, _ := newMemoryUsecase(listLoans()...)
+
+	page, err := uc.GetList(context.Background(), model.LoanListParam{Limit: 2})
+	require.NoError(t, err)
+	require.Equal(t, []string{"approved", "proposed"}, []string{page.Data[0].StatusStr, page.Data[1].StatusStr})
+
+	// a URL kept from before letters were stored by key is shown as it is
+	require.Equal(t, "http://localhost:4040/files/agreement-letters/v1/120.pdf?signature=valid", page.Data[0].AgreementLetterURL)
+	require.Equal(t, "http://example.com/letter.pdf", page.Data[1].AgreementLetterURL)
+}
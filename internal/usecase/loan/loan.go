@@ -3,81 +3,215 @@ package loan
 import (
 	"context"
 	"errors"
+	"fmt"
 	"simple-app/internal/model"
-	agrmnt "simple-app/internal/pkg/agreementLetter"
+	"simple-app/internal/pkg/amortization"
+	"simple-app/internal/pkg/money"
+	"simple-app/internal/pkg/reqctx"
+	"strings"
 	"time"
-
-	"log"
-
-	"github.com/jmoiron/sqlx"
 )
 
 // Usecase instance struct for loan
 type Usecase struct {
+	tx              txManager
 	loanRepo        loanRepo
+	borrowerRepo    borrowerRepo
+	payout          payout
+	wallet          wallet
 	agreementLetter agreementLetter
+	outbox          outbox
+	files           files
+	fundingPeriod   time.Duration
 }
 
-type loanRepo interface {
-	CreateTx(ctx context.Context) (*sqlx.Tx, error)
+// txManager runs fn inside a transaction, repositories called with the ctx given to fn join the transaction.
+// A transaction failing on serialization or deadlock runs fn again
+type txManager interface {
+	WithTx(ctx context.Context, fn func(ctx context.Context) error) error
+	WithSerializableTx(ctx context.Context, fn func(ctx context.Context) error) error
+}
 
+type loanRepo interface {
 	GetByID(ctx context.Context, ID int) (loan model.Loan, err error)
+	GetByIDForUpdate(ctx context.Context, ID int) (loan model.Loan, err error)
 	GetInvestByID(ctx context.Context, ID int) (invests []model.Invest, err error)
+	GetInvestByIDTx(ctx context.Context, ID int) (invests []model.Invest, err error)
 	GetDisburseByID(ctx context.Context, ID int) (disburses []model.Disburse, err error)
-	GetList(ctx context.Context) (loan []model.Loan, err error)
+	GetInstallmentByID(ctx context.Context, ID int) (installments []model.Installment, err error)
+	GetRepaymentByID(ctx context.Context, ID int) (repayments []model.Repayment, err error)
+	GetList(ctx context.Context, param model.LoanListParam) (page model.LoanPage, err error)
+	GetOverdueFunding(ctx context.Context, before time.Time) (loans []model.Loan, err error)
+	GetOutstandingPrincipalByBorrower(ctx context.Context, borrowerID int) (outstanding money.Money, err error)
+	GetEventByID(ctx context.Context, ID int) (events []model.LoanEvent, err error)
 
 	Create(ctx context.Context, param model.Loan) (data model.Loan, err error)
 	Approve(ctx context.Context, param model.Approve) (id int, err error)
-	Invest(ctx context.Context, dbTx *sqlx.Tx, param model.Invest) (data model.Invest, err error)
-	Disburse(ctx context.Context, dbTx *sqlx.Tx, param model.Disburse) (data model.Disburse, err error)
+	Invest(ctx context.Context, param model.Invest) (data model.Invest, err error)
+	Withdraw(ctx context.Context, param model.Withdraw) (data model.Withdraw, err error)
+	ReleaseInvestments(ctx context.Context, loanID int, reason string) (released []model.Withdraw, err error)
+	Disburse(ctx context.Context, param model.Disburse) (data model.Disburse, err error)
+	CreateInstallments(ctx context.Context, installments []model.Installment) (err error)
+	Repay(ctx context.Context, param model.Repayment) (data model.Repayment, err error)
+
+	UpdateStatus(ctx context.Context, status model.Loan) (id int, err error)
+	UpdateStatusWithReason(ctx context.Context, status model.Loan, reason string) (id int, err error)
+	BumpVersion(ctx context.Context, id, version int) (err error)
+
+	CreateSignature(ctx context.Context, param model.Signature) (data model.Signature, err error)
+	GetSignatureByTokenForUpdate(ctx context.Context, tokenHash string) (signature model.Signature, err error)
+	GetSignedSignature(ctx context.Context, loanID int, sha256, version string) (signature model.Signature, err error)
+	Sign(ctx context.Context, param model.Signature) (data model.Signature, err error)
+}
+
+type borrowerRepo interface {
+	GetByID(ctx context.Context, ID int) (borrower model.Borrower, err error)
+	GetByIDForUpdate(ctx context.Context, ID int) (borrower model.Borrower, err error)
+}
 
-	UpdateStatus(ctx context.Context, dbTx *sqlx.Tx, status model.Loan) (id int, err error)
+type payout interface {
+	Distribute(ctx context.Context, loan model.Loan, invests []model.Invest, repayment model.Repayment) (payouts []model.Payout, err error)
+}
+
+type wallet interface {
+	Hold(ctx context.Context, invest model.Invest) (err error)
+	Release(ctx context.Context, invest model.Invest) (err error)
+	Capture(ctx context.Context, invest model.Invest) (err error)
+	Credit(ctx context.Context, payouts []model.Payout) (err error)
 }
 
 type agreementLetter interface {
-	Generate(param model.Loan) string
-	Send(receiver int, agreementLetter string) error
+	Generate(ctx context.Context, loan model.Loan, borrower model.Borrower) (letter model.AgreementLetter, err error)
+	GenerateForInvestor(ctx context.Context, loan model.Loan, borrower model.Borrower, invest model.Invest) (letter model.AgreementLetter, err error)
+	Certify(ctx context.Context, loan model.Loan, signature model.Signature) (document model.AgreementLetter, err error)
+}
+
+type outbox interface {
+	Enqueue(ctx context.Context, messages []model.OutboxMessage) (err error)
 }
 
-// New will instantiate new loan usecase
-func New(loanRepo loanRepo) *Usecase {
+type files interface {
+	Verify(ctx context.Context, url string, kind model.FileKind) (key string, err error)
+	DownloadURL(key string) string
+}
+
+// New will instantiate new loan usecase, approved loans wait for investors during fundingPeriod
+// or DefaultFundingPeriod when it is zero
+func New(tx txManager, loanRepo loanRepo, borrowerRepo borrowerRepo, payout payout, wallet wallet, agreementLetter agreementLetter, outbox outbox, files files, fundingPeriod time.Duration) *Usecase {
+	if fundingPeriod <= 0 {
+		fundingPeriod = DefaultFundingPeriod
+	}
+
 	return &Usecase{
+		tx:              tx,
 		loanRepo:        loanRepo,
-		agreementLetter: agrmnt.New(),
+		borrowerRepo:    borrowerRepo,
+		payout:          payout,
+		wallet:          wallet,
+		agreementLetter: agreementLetter,
+		outbox:          outbox,
+		files:           files,
+		fundingPeriod:   fundingPeriod,
 	}
 }
 
-// Create loan request, it will generate agreement letter url first, then submit it to db
+// Create loan request, it will generate and store agreement letter first, then submit it to db.
+// Repayment method defaults to annuity when not given.
+// The borrower must be KYC verified and the principal of every loan that is not closed yet,
+// including this one, must fit in the credit limit of the borrower
 func (u *Usecase) Create(ctx context.Context, param model.Loan) (data model.Loan, err error) {
+	ctx = reqctx.WithActor(ctx, "borrower", param.BorrowerID)
 
-	// Generate Agreement Letter
-	url := u.agreementLetter.Generate(param)
-	param.AgreementLetterURL = url
+	if param.RepaymentMethod == "" {
+		param.RepaymentMethod = model.ANNUITY
+	}
+
+	err = u.tx.WithTx(ctx, func(ctx context.Context) error {
+		// lock borrower so concurrent proposals are checked against the credit limit one by one
+		borrower, err := u.borrowerRepo.GetByIDForUpdate(ctx, param.BorrowerID)
+		if errors.Is(err, model.ErrNotFound) {
+			return model.ErrBorrowerNotRegistered
+		}
+		if err != nil {
+			return err
+		}
+
+		if borrower.KYCStatus != model.KYCVerified {
+			return model.ErrBorrowerNotVerified
+		}
+
+		outstanding, err := u.loanRepo.GetOutstandingPrincipalByBorrower(ctx, param.BorrowerID)
+		if err != nil {
+			return err
+		}
+
+		available := borrower.CreditLimit.Sub(outstanding)
+		if param.PrincipalAmount.Cmp(available) > 0 {
+			return fmt.Errorf("%w, available %s", model.ErrCreditLimitExceeded, available)
+		}
+
+		// Generate Agreement Letter
+		letter, err := u.agreementLetter.Generate(ctx, param, borrower)
+		if err != nil {
+			return err
+		}
+		param.AgreementLetterKey = letter.Key
+		param.AgreementLetterSHA256 = letter.SHA256
+		param.AgreementLetterVersion = letter.Version
 
-	data, err = u.loanRepo.Create(ctx, param)
+		data, err = u.loanRepo.Create(ctx, param)
+		return err
+	})
 	if err != nil {
 		return data, err
 	}
 
+	data.AgreementLetterURL = u.link(data.AgreementLetterKey)
+
 	return data, nil
 }
 
-// Approve loan request, it will update approver_id, status, and picture of proof url
+// Approve loan request, it will update approver_id, status, and picture of proof url.
+// Investors have until the funding deadline to fully fund the loan, otherwise it expires
 func (u *Usecase) Approve(ctx context.Context, param model.Approve) (id int, err error) {
+	ctx = reqctx.WithActor(ctx, "approver", param.ApproverID)
 
 	loan, err := u.loanRepo.GetByID(ctx, param.ID)
 	if err != nil {
 		return id, err
 	}
 
-	if loan.Status >= model.APPROVED {
-		return id, errors.New("status is already approved")
+	err = checkVersion(loan, param.Version)
+	if err != nil {
+		return id, err
+	}
+
+	err = loan.Status.TransitionTo(model.APPROVED)
+	if err != nil {
+		return id, err
+	}
+
+	// the picture proof must be uploaded to this service first
+	if param.PictureProofURL == nil {
+		return id, model.ErrInvalidFile
+	}
+	key, err := u.files.Verify(ctx, *param.PictureProofURL, model.FilePictureProof)
+	if err != nil {
+		return id, err
 	}
+	param.PictureProofKey = &key
 
 	now := time.Now()
 	param.ApprovalDate = &now
+	deadline := now.Add(u.fundingPeriod)
+	param.FundingDeadline = &deadline
 	param.Status = model.APPROVED
-	id, err = u.loanRepo.Approve(ctx, param)
+
+	// the approval and its loan event are written together
+	err = u.tx.WithTx(ctx, func(ctx context.Context) error {
+		id, err = u.loanRepo.Approve(ctx, param)
+		return err
+	})
 	if err != nil {
 		return id, err
 	}
@@ -85,63 +219,88 @@ func (u *Usecase) Approve(ctx context.Context, param model.Approve) (id int, err
 	return id, nil
 }
 
-// Invest, the amount of money that given by investor
-func (u *Usecase) Invest(ctx context.Context, param model.Invest) (id int, total float32, status string, err error) {
+// Invest, the amount of money that given by investor, on the loan at the version of param.
+// The loan row is locked for the whole serializable transaction, so concurrent investments are checked against
+// the remaining amount one by one and the total can never exceed the principal amount
+func (u *Usecase) Invest(ctx context.Context, param model.Invest) (id int, total money.Money, status string, err error) {
+	ctx = reqctx.WithActor(ctx, "investor", param.InvestorID)
+
+	var (
+		loan model.Loan
+		data model.Invest
+	)
+	err = u.tx.WithSerializableTx(ctx, func(ctx context.Context) (err error) {
+		// get and lock approved loan
+		loan, err = u.loanRepo.GetByIDForUpdate(ctx, param.LoanID)
+		if err != nil {
+			return err
+		}
 
-	// get approved loan
-	loan, err := u.loanRepo.GetByID(ctx, param.LoanID)
-	if err != nil {
-		return id, total, status, err
-	}
+		err = checkVersion(loan, param.Version)
+		if err != nil {
+			return err
+		}
 
-	if loan.Status != model.APPROVED {
-		return id, total, status, errors.New("status of loan is invalid")
-	}
+		// only approved loan can be invested
+		err = loan.Status.TransitionTo(model.INVESTED)
+		if err != nil {
+			return err
+		}
 
-	// get current investment
-	invests, err := u.loanRepo.GetInvestByID(ctx, param.LoanID)
-	if err != nil {
-		return id, total, status, err
-	}
+		// the loan is waiting for the expiry job
+		if loan.FundingDeadline != nil && time.Now().After(*loan.FundingDeadline) {
+			return model.ErrFundingClosed
+		}
 
-	// calculate current total of invested
-	var current float32 = 0
-	for _, invest := range invests {
-		current += invest.Amount
-	}
+		// get current investment
+		invests, err := u.loanRepo.GetInvestByIDTx(ctx, param.LoanID)
+		if err != nil {
+			return err
+		}
 
-	// init db transaction
-	dbTx, _ := u.loanRepo.CreateTx(ctx)
-	defer dbTx.Rollback()
+		// calculate current total of invested
+		current := money.FromMinor(0)
+		for _, invest := range invests {
+			current = current.Add(invest.Amount)
+		}
 
-	// insert investment
-	data, err := u.loanRepo.Invest(ctx, dbTx, param)
-	if err != nil {
-		return id, total, status, err
-	}
+		// reject investment that is more than the remaining amount
+		if param.Amount.Cmp(loan.PrincipalAmount.Sub(current)) > 0 {
+			return model.ErrAmountExceedsRemaining
+		}
 
-	// calculate total of investment
-	total = current + param.Amount
+		// insert investment
+		data, err = u.loanRepo.Invest(ctx, param)
+		if err != nil {
+			return err
+		}
 
-	// check if total is equal of more than principal amount
-	if total >= float32(loan.PrincipalAmount) {
-		// Send  all of the investors the letter of agreement
-		for _, invest := range invests {
-			err = u.agreementLetter.Send(invest.InvestorID, loan.AgreementLetterURL)
-			if err != nil {
-				log.Printf("failed to send email, err = %v", err)
-			}
+		// the investment is a change of loan
+		err = u.loanRepo.BumpVersion(ctx, loan.ID, param.Version)
+		if err != nil {
+			return err
 		}
+		loan.Version++
 
-		// update status of loan to be "invested"
-		loan.Status = model.INVESTED
-		id, err = u.loanRepo.UpdateStatus(ctx, dbTx, loan)
+		// reserve the money of investor until the loan is disbursed
+		err = u.wallet.Hold(ctx, data)
 		if err != nil {
-			return id, total, status, err
+			return err
 		}
-	}
 
-	err = dbTx.Commit()
+		// calculate total of investment
+		total = current.Add(param.Amount)
+
+		// check if total is equal of more than principal amount
+		if total.Cmp(loan.PrincipalAmount) >= 0 {
+			loan, err = u.fullyFunded(ctx, loan)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
 	if err != nil {
 		return id, total, status, err
 	}
@@ -150,8 +309,76 @@ func (u *Usecase) Invest(ctx context.Context, param model.Invest) (id int, total
 
 }
 
-// Disburse, the amount of money that will be disbursed by borrower
+// Withdraw, investor pulls back the investment while the loan is not fully funded yet, at the version of param.
+// The loan row is locked like in Invest, so the funded total stays consistent with concurrent investments
+func (u *Usecase) Withdraw(ctx context.Context, param model.Withdraw) (data model.Withdraw, total money.Money, err error) {
+	ctx = reqctx.WithActor(ctx, "investor", param.InvestorID)
+
+	err = u.tx.WithTx(ctx, func(ctx context.Context) error {
+		// get and lock loan
+		loan, err := u.loanRepo.GetByIDForUpdate(ctx, param.LoanID)
+		if err != nil {
+			return err
+		}
+
+		err = checkVersion(loan, param.Version)
+		if err != nil {
+			return err
+		}
+
+		// an investment can only be pulled back while the loan can still be invested,
+		// once it is invested the money is committed to the borrower
+		err = loan.Status.TransitionTo(model.INVESTED)
+		if err != nil {
+			return err
+		}
+
+		data, err = u.loanRepo.Withdraw(ctx, param)
+		if err != nil {
+			return err
+		}
+
+		// the withdrawal is a change of loan
+		err = u.loanRepo.BumpVersion(ctx, loan.ID, param.Version)
+		if err != nil {
+			return err
+		}
+
+		err = u.wallet.Release(ctx, model.Invest{
+			ID:         data.ID,
+			LoanID:     data.LoanID,
+			InvestorID: data.InvestorID,
+			Amount:     data.Amount,
+		})
+		if err != nil {
+			return err
+		}
+
+		// recalculate total of invested without the withdrawn investment
+		invests, err := u.loanRepo.GetInvestByIDTx(ctx, param.LoanID)
+		if err != nil {
+			return err
+		}
+
+		total = money.FromMinor(0)
+		for _, invest := range invests {
+			total = total.Add(invest.Amount)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return data, total, err
+	}
+
+	return data, total, nil
+}
+
+// Disburse, the amount of money that will be disbursed by borrower.
+// The installment schedule is generated here since the due dates start from disbursement date.
+// The held money of investors is captured in a serializable transaction
 func (u *Usecase) Disburse(ctx context.Context, param model.Disburse) (id int, err error) {
+	ctx = reqctx.WithActor(ctx, "employee", param.DisburseEmployeeID)
 
 	// get approved loan
 	loan, err := u.loanRepo.GetByID(ctx, param.LoanID)
@@ -159,38 +386,262 @@ func (u *Usecase) Disburse(ctx context.Context, param model.Disburse) (id int, e
 		return id, err
 	}
 
-	if loan.Status != model.INVESTED {
-		return id, errors.New("status of loan is invalid")
+	// the loan is updated with the version read here, a change made meanwhile fails the update
+	err = checkVersion(loan, param.Version)
+	if err != nil {
+		return id, err
 	}
 
-	// calculate current total of invested
+	err = loan.Status.TransitionTo(model.DISBURSED)
+	if err != nil {
+		return id, err
+	}
 
-	// init db transaction
-	dbTx, _ := u.loanRepo.CreateTx(ctx)
-	defer dbTx.Rollback()
+	var data model.Disburse
+	err = u.tx.WithSerializableTx(ctx, func(ctx context.Context) error {
+		// the borrower must have signed the current agreement letter, the signed document is kept with the disbursement
+		signature, err := u.loanRepo.GetSignedSignature(ctx, loan.ID, loan.AgreementLetterSHA256, loan.AgreementLetterVersion)
+		if errors.Is(err, model.ErrNotFound) {
+			return model.ErrSignatureRequired
+		}
+		if err != nil {
+			return err
+		}
 
-	// insert investment
-	now := time.Now()
-	param.DisbursementDate = &now
-	data, err := u.loanRepo.Disburse(ctx, dbTx, param)
+		// an uploaded scan of the signed letter is kept when given, otherwise the signature certificate
+		disburse := param
+		disburse.SignedAgreementKey = signature.SignedDocumentKey
+		if disburse.SignedAgreementURL != "" {
+			disburse.SignedAgreementKey, err = u.files.Verify(ctx, disburse.SignedAgreementURL, model.FileSignedAgreement)
+			if err != nil {
+				return err
+			}
+		}
+
+		// insert investment
+		now := time.Now()
+		disburse.DisbursementDate = &now
+		data, err = u.loanRepo.Disburse(ctx, disburse)
+		if err != nil {
+			return err
+		}
+
+		// generate repayment schedule starting from disbursement date
+		installments, err := amortization.Generate(amortization.Param{
+			LoanID:    loan.ID,
+			Principal: loan.PrincipalAmount,
+			Rate:      loan.Rate,
+			Tenor:     loan.Tenor,
+			Method:    loan.RepaymentMethod,
+			StartDate: now,
+		})
+		if err != nil {
+			return err
+		}
+
+		err = u.loanRepo.CreateInstallments(ctx, installments)
+		if err != nil {
+			return err
+		}
+
+		// the held money of investors goes to borrower
+		invests, err := u.loanRepo.GetInvestByIDTx(ctx, param.LoanID)
+		if err != nil {
+			return err
+		}
+
+		for _, invest := range invests {
+			err = u.wallet.Capture(ctx, invest)
+			if err != nil {
+				return err
+			}
+		}
+
+		// update status of loan to be "disbursed"
+		disbursed := loan
+		disbursed.Status = model.DISBURSED
+		_, err = u.loanRepo.UpdateStatus(ctx, disbursed)
+		return err
+	})
 	if err != nil {
 		return id, err
 	}
 
-	// update status of loan to be "disbursed"
-	loan.Status = model.DISBURSED
-	id, err = u.loanRepo.UpdateStatus(ctx, dbTx, loan)
+	return data.LoanID, err
+
+}
+
+// Repay, the amount of money that paid back by borrower, on the loan at the version of param.
+// The payment is allocated to the installments in order, the interest of an installment before its principal,
+// the principal is paid out to investors, and the loan becomes "repaid" once nothing is left outstanding.
+// The outstanding amount is read inside a serializable transaction, a concurrent repayment makes it run again
+// with the new outstanding amount, so a loan is never paid out more than it is owed
+func (u *Usecase) Repay(ctx context.Context, param model.Repayment) (data model.Repayment, outstanding money.Money, status string, err error) {
+	var loan model.Loan
+	err = u.tx.WithSerializableTx(ctx, func(ctx context.Context) (err error) {
+		// get disbursed loan
+		loan, err = u.loanRepo.GetByID(ctx, param.LoanID)
+		if err != nil {
+			return err
+		}
+
+		err = checkVersion(loan, param.Version)
+		if err != nil {
+			return err
+		}
+
+		// only disbursed loan can be repaid
+		err = loan.Status.TransitionTo(model.REPAID)
+		if err != nil {
+			return err
+		}
+
+		ctx = reqctx.WithActor(ctx, "borrower", loan.BorrowerID)
+
+		installments, err := u.loanRepo.GetInstallmentByID(ctx, param.LoanID)
+		if err != nil {
+			return err
+		}
+
+		repayments, err := u.loanRepo.GetRepaymentByID(ctx, param.LoanID)
+		if err != nil {
+			return err
+		}
+
+		invests, err := u.loanRepo.GetInvestByID(ctx, param.LoanID)
+		if err != nil {
+			return err
+		}
+
+		// allocate payment to the installments in order, interest then principal of each
+		repayment := param
+		repayment.InterestAmount, repayment.PrincipalAmount, err = amortization.Allocate(param.Amount, installments, repayments)
+		if err != nil {
+			return err
+		}
+
+		interest, principal := amortization.Outstanding(installments, repayments)
+
+		outstanding = interest.Add(principal).Sub(param.Amount)
+
+		// insert repayment
+		now := time.Now()
+		repayment.PaymentDate = &now
+		data, err = u.loanRepo.Repay(ctx, repayment)
+		if err != nil {
+			return err
+		}
+
+		// the repayment is a change of loan
+		err = u.loanRepo.BumpVersion(ctx, loan.ID, param.Version)
+		if err != nil {
+			return err
+		}
+		loan.Version++
+
+		// pay investors their share
+		payouts, err := u.payout.Distribute(ctx, loan, invests, data)
+		if err != nil {
+			return err
+		}
+
+		err = u.wallet.Credit(ctx, payouts)
+		if err != nil {
+			return err
+		}
+
+		// update status of loan to be "repaid" when fully settled
+		if !outstanding.IsPositive() {
+			loan.Status = model.REPAID
+			_, err = u.loanRepo.UpdateStatus(ctx, loan)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
 	if err != nil {
-		return id, err
+		return data, outstanding, status, err
 	}
 
-	err = dbTx.Commit()
+	return data, outstanding, loan.Status.ToString(), nil
+}
+
+// Reject proposed loan request, done by approver
+func (u *Usecase) Reject(ctx context.Context, param model.StatusChange) (err error) {
+	ctx = reqctx.WithActor(ctx, "approver", param.ActorID)
+	return u.transition(ctx, param.ID, param.Version, model.REJECTED, param.Reason)
+}
+
+// Cancel loan request that is not invested yet
+func (u *Usecase) Cancel(ctx context.Context, param model.StatusChange) (err error) {
+	ctx = reqctx.WithActor(ctx, "user", param.ActorID)
+	return u.transition(ctx, param.ID, param.Version, model.CANCELLED, param.Reason)
+}
+
+// transition moves the loan at the given version to the given status if the state machine allows it
+func (u *Usecase) transition(ctx context.Context, id, version int, to model.LoanStatus, reason string) (err error) {
+	return u.tx.WithTx(ctx, func(ctx context.Context) error {
+		loan, err := u.loanRepo.GetByIDForUpdate(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		err = checkVersion(loan, version)
+		if err != nil {
+			return err
+		}
+
+		err = loan.Status.TransitionTo(to)
+		if err != nil {
+			return err
+		}
+
+		// investments of approved loan will never be disbursed
+		if loan.Status == model.APPROVED {
+			_, err = u.releaseInvestments(ctx, id, reason)
+			if err != nil {
+				return err
+			}
+		}
+
+		loan.Status = to
+		_, err = u.loanRepo.UpdateStatusWithReason(ctx, loan, reason)
+		return err
+	})
+}
+
+// checkVersion make sure the loan is still at the version the client has seen
+func checkVersion(loan model.Loan, version int) error {
+	if loan.Version != version {
+		return &model.VersionConflictError{LoanID: loan.ID, Expected: version, Actual: loan.Version}
+	}
+
+	return nil
+}
+
+// releaseInvestments mark every investment of loan as withdrawn and give the held money back to investors,
+// it must run inside the transaction of ctx
+func (u *Usecase) releaseInvestments(ctx context.Context, loanID int, reason string) (released []model.Withdraw, err error) {
+	released, err = u.loanRepo.ReleaseInvestments(ctx, loanID, reason)
 	if err != nil {
-		return id, err
+		return released, err
 	}
 
-	return data.LoanID, err
+	for _, withdraw := range released {
+		err = u.wallet.Release(ctx, model.Invest{
+			ID:         withdraw.ID,
+			LoanID:     withdraw.LoanID,
+			InvestorID: withdraw.InvestorID,
+			Amount:     withdraw.Amount,
+		})
+		if err != nil {
+			return released, err
+		}
+	}
 
+	return released, nil
 }
 
 func (u *Usecase) GetDetail(ctx context.Context, id int) (detail model.Detail, err error) {
@@ -213,26 +664,93 @@ func (u *Usecase) GetDetail(ctx context.Context, id int) (detail model.Detail, e
 		return detail, err
 	}
 
+	// get installment schedule
+	installments, err := u.loanRepo.GetInstallmentByID(ctx, id)
+	if err != nil {
+		return detail, err
+	}
+
+	// get repayment list
+	repayments, err := u.loanRepo.GetRepaymentByID(ctx, id)
+	if err != nil {
+		return detail, err
+	}
+
 	loan.StatusStr = loan.Status.ToString()
 
+	// stored documents are shown as download URLs that expire
+	loan = u.linkLoan(loan)
+	for i := range disbursement {
+		disbursement[i].SignedAgreementURL = u.link(disbursement[i].SignedAgreementKey)
+	}
+
 	// collect into one struct
 	detail.Loan = loan
 	detail.Investors = investors
 	detail.Disbursements = disbursement
+	detail.Installments = installments
+	detail.Repayments = repayments
 
 	return detail, err
 }
 
-// GetList get list of loans
-func (u *Usecase) GetList(ctx context.Context) (loans []model.Loan, err error) {
-	loans, err = u.loanRepo.GetList(ctx)
+// GetHistory get the events of loan, oldest first
+func (u *Usecase) GetHistory(ctx context.Context, id int) (events []model.LoanEvent, err error) {
+
+	// make sure the loan exists
+	_, err = u.loanRepo.GetByID(ctx, id)
+	if err != nil {
+		return events, err
+	}
+
+	events, err = u.loanRepo.GetEventByID(ctx, id)
+	if err != nil {
+		return events, err
+	}
+
+	return events, nil
+}
+
+// GetList get a page of loans matching the filter, newest first unless another sort is given
+func (u *Usecase) GetList(ctx context.Context, param model.LoanListParam) (page model.LoanPage, err error) {
+	if param.Sort == "" {
+		param.Sort = model.DefaultLoanSort
+	}
+	if param.Limit <= 0 {
+		param.Limit = model.DefaultListLimit
+	}
+	if param.Limit > model.MaxListLimit {
+		param.Limit = model.MaxListLimit
+	}
+
+	page, err = u.loanRepo.GetList(ctx, param)
 	if err != nil {
-		return loans, err
+		return page, err
 	}
 
-	for i, loan := range loans {
-		loans[i].StatusStr = loan.Status.ToString()
+	for i, loan := range page.Data {
+		page.Data[i] = u.linkLoan(loan)
+		page.Data[i].StatusStr = loan.Status.ToString()
+	}
+
+	return page, nil
+}
+
+// linkLoan show the stored documents of loan as download URLs
+func (u *Usecase) linkLoan(loan model.Loan) model.Loan {
+	loan.AgreementLetterURL = u.link(loan.AgreementLetterKey)
+	if loan.PictureProofKey != nil {
+		loan.PictureProofURL = u.link(*loan.PictureProofKey)
+	}
+
+	return loan
+}
+
+// link is the download URL of a stored document, a URL kept from before documents were stored by key is shown as it is
+func (u *Usecase) link(key string) string {
+	if strings.Contains(key, "://") {
+		return key
 	}
 
-	return loans, nil
+	return u.files.DownloadURL(key)
 }
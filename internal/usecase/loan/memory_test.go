@@ -0,0 +1,666 @@
+package loan
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"simple-app/internal/model"
+	"simple-app/internal/pkg/amortization"
+	"simple-app/internal/pkg/money"
+	"simple-app/internal/repository/loan/memory"
+
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	proofURL = "http://localhost:4040/files/uploads/picture-proofs/proof.jpg"
+	scanURL  = "http://localhost:4040/files/uploads/signed-agreements/scan.pdf"
+)
+
+type noopPayout struct{}
+
+func (noopPayout) Distribute(context.Context, model.Loan, []model.Invest, model.Repayment) ([]model.Payout, error) {
+	return nil, nil
+}
+
+// newMemoryUsecase runs the usecase on the in-memory repository, which is also its transaction manager
+func newMemoryUsecase(loans ...model.Loan) (*Usecase, *memory.Loan) {
+	repo := memory.New(loans...)
+
+	return &Usecase{
+		tx:              repo,
+		loanRepo:        repo,
+		borrowerRepo:    borrowers,
+		payout:          noopPayout{},
+		wallet:          noopWallet{},
+		agreementLetter: noopLetter{},
+		outbox:          &recordOutbox{},
+		files:           fakeFiles{proofURL: model.FilePictureProof, scanURL: model.FileSignedAgreement},
+		fundingPeriod:   DefaultFundingPeriod,
+	}, repo
+}
+
+// memoryLoan is loan 1 at version 1, without interest so it is repaid with its principal
+func memoryLoan(status model.LoanStatus) model.Loan {
+	return model.Loan{
+		ID:                     1,
+		BorrowerID:             1,
+		PrincipalAmount:        money.MustParse("1000"),
+		Tenor:                  4,
+		RepaymentMethod:        model.ANNUITY,
+		Status:                 status,
+		AgreementLetterSHA256:  letterSHA256,
+		AgreementLetterVersion: "v1",
+		Version:                1,
+	}
+}
+
+// invest puts investment 1 of investor 2 and investment 2 of investor 3 in loan 1
+func invest(t *testing.T, repo *memory.Loan) {
+	for _, invest := range []model.Invest{
+		{LoanID: 1, InvestorID: 2, Amount: money.MustParse("300")},
+		{LoanID: 1, InvestorID: 3, Amount: money.MustParse("200")},
+	} {
+		_, err := repo.Invest(context.Background(), invest)
+		require.NoError(t, err)
+	}
+}
+
+func TestApprove(t *testing.T) {
+	unknownURL := "http://localhost:4040/files/uploads/picture-proofs/unknown.jpg"
+
+	testCases := []struct {
+		name    string
+		status  model.LoanStatus
+		param   model.Approve
+		wantErr error
+	}{
+		{
+			name:   "approve proposed loan",
+			status: model.PROPOSED,
+			param:  model.Approve{ID: 1, PictureProofURL: &proofURL, ApproverID: 7, Version: 1},
+		},
+		{
+			name:    "stale version",
+			status:  model.PROPOSED,
+			param:   model.Approve{ID: 1, PictureProofURL: &proofURL, ApproverID: 7, Version: 0},
+			wantErr: model.ErrVersionConflict,
+		},
+		{
+			name:    "loan is already approved",
+			status:  model.APPROVED,
+			param:   model.Approve{ID: 1, PictureProofURL: &proofURL, ApproverID: 7, Version: 1},
+			wantErr: model.ErrInvalidTransition,
+		},
+		{
+			name:    "picture proof is not uploaded",
+			status:  model.PROPOSED,
+			param:   model.Approve{ID: 1, PictureProofURL: &unknownURL, ApproverID: 7, Version: 1},
+			wantErr: model.ErrInvalidFile,
+		},
+		{
+			name:    "unknown loan",
+			status:  model.PROPOSED,
+			param:   model.Approve{ID: 9, PictureProofURL: &proofURL, ApproverID: 7, Version: 1},
+			wantErr: model.ErrNotFound,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+			uc, repo := newMemoryUsecase(memoryLoan(tc.status))
+
+			_, err := uc.Approve(ctx, tc.param)
+			loan, _ := repo.GetByID(ctx, 1)
+			if tc.wantErr != nil {
+				require.ErrorIs(t, err, tc.wantErr)
+				require.Equal(t, tc.status, loan.Status)
+				require.Equal(t, 1, loan.Version)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, model.APPROVED, loan.Status)
+			require.Equal(t, 2, loan.Version)
+			require.Equal(t, 7, *loan.ApproverID)
+			require.WithinDuration(t, time.Now().Add(DefaultFundingPeriod), *loan.FundingDeadline, time.Minute)
+
+			// the picture proof is kept by its key and shown as a download URL
+			require.Equal(t, "uploads/picture-proofs/proof.jpg", *loan.PictureProofKey)
+			detail, err := uc.GetDetail(ctx, 1)
+			require.NoError(t, err)
+			require.Equal(t, "http://localhost:4040/files/uploads/picture-proofs/proof.jpg?signature=valid", detail.PictureProofURL)
+		})
+	}
+}
+
+func TestWithdraw(t *testing.T) {
+	testCases := []struct {
+		name      string
+		status    model.LoanStatus
+		withdrawn []model.Withdraw
+		param     model.Withdraw
+		wantErr   error
+		wantTotal string
+	}{
+		{
+			name:      "withdraw own investment",
+			status:    model.APPROVED,
+			param:     model.Withdraw{ID: 1, LoanID: 1, InvestorID: 2, Reason: "changed mind", Version: 1},
+			wantTotal: "200.00",
+		},
+		{
+			name:      "investment of another investor",
+			status:    model.APPROVED,
+			param:     model.Withdraw{ID: 1, LoanID: 1, InvestorID: 3, Reason: "changed mind", Version: 1},
+			wantErr:   model.ErrNotFound,
+			wantTotal: "500.00",
+		},
+		{
+			name:      "investment is already withdrawn",
+			status:    model.APPROVED,
+			withdrawn: []model.Withdraw{{ID: 1, LoanID: 1, InvestorID: 2}},
+			param:     model.Withdraw{ID: 1, LoanID: 1, InvestorID: 2, Reason: "changed mind", Version: 1},
+			wantErr:   model.ErrNotFound,
+			wantTotal: "200.00",
+		},
+		{
+			name:      "loan is fully funded",
+			status:    model.INVESTED,
+			param:     model.Withdraw{ID: 1, LoanID: 1, InvestorID: 2, Reason: "changed mind", Version: 1},
+			wantErr:   model.ErrInvalidTransition,
+			wantTotal: "500.00",
+		},
+		{
+			name:      "stale version",
+			status:    model.APPROVED,
+			param:     model.Withdraw{ID: 1, LoanID: 1, InvestorID: 2, Reason: "changed mind", Version: 2},
+			wantErr:   model.ErrVersionConflict,
+			wantTotal: "500.00",
+		},
+		{
+			name:      "loan is cancelled",
+			status:    model.CANCELLED,
+			param:     model.Withdraw{ID: 1, LoanID: 1, InvestorID: 2, Reason: "changed mind", Version: 1},
+			wantErr:   model.ErrInvalidTransition,
+			wantTotal: "500.00",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+			uc, repo := newMemoryUsecase(memoryLoan(tc.status))
+			invest(t, repo)
+			for _, withdraw := range tc.withdrawn {
+				_, err := repo.Withdraw(ctx, withdraw)
+				require.NoError(t, err)
+			}
+
+			_, total, err := uc.Withdraw(ctx, tc.param)
+			loan, _ := repo.GetByID(ctx, 1)
+			if tc.wantErr != nil {
+				require.Equal(t, 1, loan.Version)
+				require.ErrorIs(t, err, tc.wantErr)
+				if errors.Is(tc.wantErr, model.ErrInvalidTransition) {
+					var transition *model.TransitionError
+					require.ErrorAs(t, err, &transition)
+					require.Equal(t, model.TransitionError{From: tc.status, To: model.INVESTED}, *transition)
+				}
+			} else {
+				require.NoError(t, err)
+				require.Equal(t, tc.wantTotal, total.String())
+				require.Equal(t, 2, loan.Version)
+			}
+
+			invests, err := repo.GetInvestByID(ctx, 1)
+			require.NoError(t, err)
+			require.Equal(t, tc.wantTotal, money.Sum(investAmounts(invests)...).String())
+		})
+	}
+}
+
+func investAmounts(invests []model.Invest) (amounts []money.Money) {
+	for _, invest := range invests {
+		amounts = append(amounts, invest.Amount)
+	}
+	return amounts
+}
+
+func TestDisburse(t *testing.T) {
+	testCases := []struct {
+		name    string
+		status  model.LoanStatus
+		signed  bool
+		param   model.Disburse
+		wantErr error
+	}{
+		{
+			name:   "signed agreement",
+			status: model.INVESTED,
+			signed: true,
+			param:  model.Disburse{LoanID: 1, DisburseEmployeeID: 9, Version: 1},
+		},
+		{
+			name:   "signed agreement with uploaded scan",
+			status: model.INVESTED,
+			signed: true,
+			param:  model.Disburse{LoanID: 1, DisburseEmployeeID: 9, SignedAgreementURL: scanURL, Version: 1},
+		},
+		{
+			name:    "agreement is not signed",
+			status:  model.INVESTED,
+			param:   model.Disburse{LoanID: 1, DisburseEmployeeID: 9, Version: 1},
+			wantErr: model.ErrSignatureRequired,
+		},
+		{
+			name:    "scan is not uploaded",
+			status:  model.INVESTED,
+			signed:  true,
+			param:   model.Disburse{LoanID: 1, DisburseEmployeeID: 9, SignedAgreementURL: "http://localhost/scan.pdf", Version: 1},
+			wantErr: model.ErrInvalidFile,
+		},
+		{
+			name:    "stale version",
+			status:  model.INVESTED,
+			signed:  true,
+			param:   model.Disburse{LoanID: 1, DisburseEmployeeID: 9, Version: 2},
+			wantErr: model.ErrVersionConflict,
+		},
+		{
+			name:    "loan is not fully funded",
+			status:  model.APPROVED,
+			signed:  true,
+			param:   model.Disburse{LoanID: 1, DisburseEmployeeID: 9, Version: 1},
+			wantErr: model.ErrInvalidTransition,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+			uc, repo := newMemoryUsecase(memoryLoan(tc.status))
+			if tc.signed {
+				sign(t, uc)
+			}
+
+			// the param is made at version 1, the signature has changed the loan since
+			version := loanVersion(t, uc, 1)
+			param := tc.param
+			param.Version += version - 1
+
+			_, err := uc.Disburse(ctx, param)
+
+			loan, _ := repo.GetByID(ctx, 1)
+			disburses, _ := repo.GetDisburseByID(ctx, 1)
+			installments, _ := repo.GetInstallmentByID(ctx, 1)
+			if tc.wantErr != nil {
+				require.ErrorIs(t, err, tc.wantErr)
+				require.Equal(t, tc.status, loan.Status)
+				require.Empty(t, disburses)
+				require.Empty(t, installments)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, model.DISBURSED, loan.Status)
+			require.Equal(t, version+1, loan.Version)
+			require.Len(t, disburses, 1)
+			require.Len(t, installments, loan.Tenor)
+
+			wantKey := strings.TrimPrefix(tc.param.SignedAgreementURL, "http://localhost:4040/files/")
+			if wantKey == "" {
+				wantKey = "signed-1"
+			}
+			require.Equal(t, wantKey, disburses[0].SignedAgreementKey)
+
+			// the stored key is shown as a download URL
+			detail, err := uc.GetDetail(ctx, 1)
+			require.NoError(t, err)
+			require.Equal(t, "http://localhost:4040/files/"+wantKey+"?signature=valid", detail.Disbursements[0].SignedAgreementURL)
+		})
+	}
+}
+
+func TestRepay(t *testing.T) {
+	testCases := []struct {
+		name            string
+		status          model.LoanStatus
+		amount          string
+		version         int
+		wantErr         error
+		wantOutstanding string
+		wantStatus      model.LoanStatus
+	}{
+		{
+			name:            "partial repayment",
+			status:          model.DISBURSED,
+			amount:          "400",
+			wantOutstanding: "600.00",
+			wantStatus:      model.DISBURSED,
+		},
+		{
+			name:            "full repayment",
+			status:          model.DISBURSED,
+			amount:          "1000",
+			wantOutstanding: "0.00",
+			wantStatus:      model.REPAID,
+		},
+		{
+			name:       "overpayment",
+			status:     model.DISBURSED,
+			amount:     "1000.01",
+			wantErr:    model.ErrOverpayment,
+			wantStatus: model.DISBURSED,
+		},
+		{
+			name:       "stale version",
+			status:     model.DISBURSED,
+			amount:     "400",
+			version:    2,
+			wantErr:    model.ErrVersionConflict,
+			wantStatus: model.DISBURSED,
+		},
+		{
+			name:       "loan is not disbursed",
+			status:     model.INVESTED,
+			amount:     "400",
+			wantErr:    model.ErrInvalidTransition,
+			wantStatus: model.INVESTED,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+			loan := memoryLoan(tc.status)
+			uc, repo := newMemoryUsecase(loan)
+
+			installments, err := amortization.Generate(amortization.Param{
+				LoanID:    loan.ID,
+				Principal: loan.PrincipalAmount,
+				Tenor:     loan.Tenor,
+				Method:    loan.RepaymentMethod,
+				StartDate: time.Now(),
+			})
+			require.NoError(t, err)
+			require.NoError(t, repo.CreateInstallments(ctx, installments))
+
+			version := tc.version
+			if version == 0 {
+				version = 1
+			}
+
+			_, outstanding, status, err := uc.Repay(ctx, model.Repayment{LoanID: 1, Amount: money.MustParse(tc.amount), Version: version})
+
+			repayments, _ := repo.GetRepaymentByID(ctx, 1)
+			loan, _ = repo.GetByID(ctx, 1)
+			require.Equal(t, tc.wantStatus, loan.Status)
+			if tc.wantErr != nil {
+				require.ErrorIs(t, err, tc.wantErr)
+				require.Empty(t, repayments)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, tc.wantOutstanding, outstanding.String())
+			require.Equal(t, tc.wantStatus.ToString(), status)
+			require.Len(t, repayments, 1)
+		})
+	}
+}
+
+func TestCancel(t *testing.T) {
+	testCases := []struct {
+		name         string
+		status       model.LoanStatus
+		change       func(uc *Usecase, ctx context.Context, param model.StatusChange) error
+		wantErr      error
+		wantStatus   model.LoanStatus
+		wantInvested int
+	}{
+		{
+			name:         "cancel approved loan releases investments",
+			status:       model.APPROVED,
+			change:       (*Usecase).Cancel,
+			wantStatus:   model.CANCELLED,
+			wantInvested: 0,
+		},
+		{
+			name:         "reject approved loan",
+			status:       model.APPROVED,
+			change:       (*Usecase).Reject,
+			wantErr:      model.ErrInvalidTransition,
+			wantStatus:   model.APPROVED,
+			wantInvested: 2,
+		},
+		{
+			name:         "cancel fully funded loan",
+			status:       model.INVESTED,
+			change:       (*Usecase).Cancel,
+			wantErr:      model.ErrInvalidTransition,
+			wantStatus:   model.INVESTED,
+			wantInvested: 2,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+			uc, repo := newMemoryUsecase(memoryLoan(tc.status))
+			invest(t, repo)
+
+			err := tc.change(uc, ctx, model.StatusChange{ID: 1, ActorID: 7, Reason: "borrower request", Version: 1})
+			if tc.wantErr != nil {
+				require.ErrorIs(t, err, tc.wantErr)
+			} else {
+				require.NoError(t, err)
+			}
+
+			loan, _ := repo.GetByID(ctx, 1)
+			require.Equal(t, tc.wantStatus, loan.Status)
+
+			invests, _ := repo.GetInvestByID(ctx, 1)
+			require.Len(t, invests, tc.wantInvested)
+		})
+	}
+}
+
+func TestReject(t *testing.T) {
+	testCases := []struct {
+		name        string
+		status      model.LoanStatus
+		param       model.StatusChange
+		wantErr     error
+		wantStatus  model.LoanStatus
+		wantVersion int
+	}{
+		{
+			name:        "reject proposed loan",
+			status:      model.PROPOSED,
+			param:       model.StatusChange{ID: 1, ActorID: 7, Reason: "incomplete documents", Version: 1},
+			wantStatus:  model.REJECTED,
+			wantVersion: 2,
+		},
+		{
+			name:        "stale version",
+			status:      model.PROPOSED,
+			param:       model.StatusChange{ID: 1, ActorID: 7, Reason: "incomplete documents", Version: 2},
+			wantErr:     model.ErrVersionConflict,
+			wantStatus:  model.PROPOSED,
+			wantVersion: 1,
+		},
+		{
+			name:        "reject fully funded loan",
+			status:      model.INVESTED,
+			param:       model.StatusChange{ID: 1, ActorID: 7, Reason: "incomplete documents", Version: 1},
+			wantErr:     model.ErrInvalidTransition,
+			wantStatus:  model.INVESTED,
+			wantVersion: 1,
+		},
+		{
+			name:        "unknown loan",
+			status:      model.PROPOSED,
+			param:       model.StatusChange{ID: 9, ActorID: 7, Reason: "incomplete documents", Version: 1},
+			wantErr:     model.ErrNotFound,
+			wantStatus:  model.PROPOSED,
+			wantVersion: 1,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+			uc, repo := newMemoryUsecase(memoryLoan(tc.status))
+
+			err := uc.Reject(ctx, tc.param)
+
+			loan, _ := repo.GetByID(ctx, 1)
+			require.Equal(t, tc.wantStatus, loan.Status)
+			require.Equal(t, tc.wantVersion, loan.Version)
+
+			events, _ := repo.GetEventByID(ctx, 1)
+			if tc.wantErr != nil {
+				require.ErrorIs(t, err, tc.wantErr)
+				require.Empty(t, events)
+				return
+			}
+
+			// the approver and the reason are kept in the history of loan
+			require.NoError(t, err)
+			require.Len(t, events, 1)
+			require.Equal(t, model.EventStatusChanged, events[0].Event)
+			require.Equal(t, "approver:7", events[0].Actor)
+			require.Contains(t, string(events[0].Payload), tc.param.Reason)
+		})
+	}
+}
+
+func TestGetHistory(t *testing.T) {
+	borrowers := fakeBorrowerRepo{borrowers: map[int]model.Borrower{
+		1: {ID: 1, KYCStatus: model.KYCVerified, CreditLimit: money.MustParse("5000")},
+	}}
+
+	testCases := []struct {
+		name       string
+		id         int
+		wantErr    error
+		wantEvents []string
+		wantActors []string
+	}{
+		{
+			name:       "created and approved loan",
+			id:         1,
+			wantEvents: []string{model.EventCreated, model.EventApproved},
+			wantActors: []string{"borrower:1", "approver:7"},
+		},
+		{
+			name:    "unknown loan",
+			id:      9,
+			wantErr: model.ErrNotFound,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+			uc, _ := newMemoryUsecase()
+			uc.borrowerRepo = borrowers
+
+			loan, err := uc.Create(ctx, model.Loan{BorrowerID: 1, PrincipalAmount: money.MustParse("1000"), Tenor: 4})
+			require.NoError(t, err)
+			_, err = uc.Approve(ctx, model.Approve{ID: loan.ID, PictureProofURL: &proofURL, ApproverID: 7, Version: loan.Version})
+			require.NoError(t, err)
+
+			detail, err := uc.GetDetail(ctx, tc.id)
+			if tc.wantErr != nil {
+				require.ErrorIs(t, err, tc.wantErr)
+			} else {
+				require.NoError(t, err)
+				require.Equal(t, "approved", detail.Loan.StatusStr)
+			}
+
+			events, err := uc.GetHistory(ctx, tc.id)
+			if tc.wantErr != nil {
+				require.ErrorIs(t, err, tc.wantErr)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Len(t, events, len(tc.wantEvents))
+			for i, event := range events {
+				require.Equal(t, tc.wantEvents[i], event.Event)
+				require.Equal(t, tc.wantActors[i], event.Actor)
+			}
+		})
+	}
+}
+
+func TestGetDetail(t *testing.T) {
+	testCases := []struct {
+		name          string
+		id            int
+		letterKey     string
+		proofKey      string
+		wantErr       error
+		wantLetterURL string
+		wantProofURL  string
+	}{
+		{
+			name:          "stored documents are shown as download URL",
+			id:            1,
+			letterKey:     "agreement-letters/loan-1.pdf",
+			proofKey:      "uploads/picture-proofs/proof.jpg",
+			wantLetterURL: "http://localhost:4040/files/agreement-letters/loan-1.pdf?signature=valid",
+			wantProofURL:  "http://localhost:4040/files/uploads/picture-proofs/proof.jpg?signature=valid",
+		},
+		{
+			name:          "documents kept as URL are shown as they are",
+			id:            1,
+			letterKey:     "http://localhost:4040/files/agreement-letters/loan-1.pdf",
+			proofKey:      "http://example-of-proof",
+			wantLetterURL: "http://localhost:4040/files/agreement-letters/loan-1.pdf",
+			wantProofURL:  "http://example-of-proof",
+		},
+		{
+			name:    "unknown loan",
+			id:      9,
+			wantErr: model.ErrNotFound,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+			loan := memoryLoan(model.INVESTED)
+			loan.AgreementLetterKey = tc.letterKey
+			loan.PictureProofKey = &tc.proofKey
+			uc, repo := newMemoryUsecase(loan)
+			invest(t, repo)
+
+			sign(t, uc)
+			_, err := uc.Disburse(ctx, model.Disburse{LoanID: 1, DisburseEmployeeID: 9, Version: loanVersion(t, uc, 1)})
+			require.NoError(t, err)
+
+			detail, err := uc.GetDetail(ctx, tc.id)
+			if tc.wantErr != nil {
+				require.ErrorIs(t, err, tc.wantErr)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, "disbursed", detail.StatusStr)
+			require.Equal(t, tc.wantLetterURL, detail.AgreementLetterURL)
+			require.Equal(t, tc.wantProofURL, detail.PictureProofURL)
+			require.Len(t, detail.Investors, 2)
+			require.Len(t, detail.Disbursements, 1)
+			require.Equal(t, "http://localhost:4040/files/signed-1?signature=valid", detail.Disbursements[0].SignedAgreementURL)
+			require.Len(t, detail.Installments, loan.Tenor)
+			require.Empty(t, detail.Repayments)
+		})
+	}
+}
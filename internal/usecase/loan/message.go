@@ -0,0 +1,57 @@
+package loan
+
+import (
+	"encoding/json"
+	"fmt"
+	"simple-app/internal/model"
+	"time"
+)
+
+// agreementLetterMessage build the outbox message that sends the letter of agreement to the investor
+func agreementLetterMessage(loan model.Loan, invest model.Invest, letter model.AgreementLetter) model.OutboxMessage {
+	payload, _ := json.Marshal(model.Message{
+		Subject:       fmt.Sprintf("Agreement letter of loan %d", loan.ID),
+		Body:          fmt.Sprintf("Loan %d is fully funded. Your investment of %s out of %s is committed, please find your agreement letter attached.", loan.ID, invest.Amount, loan.PrincipalAmount),
+		LoanID:        loan.ID,
+		AttachmentKey: letter.Key,
+	})
+
+	return model.OutboxMessage{
+		Topic:       model.TopicAgreementLetter,
+		RecipientID: invest.InvestorID,
+		Payload:     payload,
+	}
+}
+
+// loanExpiredMessage build the outbox message that tells the investor the investment is released from the expired loan
+func loanExpiredMessage(loan model.Loan, withdraw model.Withdraw) model.OutboxMessage {
+	payload, _ := json.Marshal(model.Message{
+		Subject: fmt.Sprintf("Loan %d is expired", loan.ID),
+		Body:    fmt.Sprintf("Loan %d is not fully funded before its deadline. Your investment of %s is released back to your wallet.", loan.ID, withdraw.Amount),
+		LoanID:  loan.ID,
+	})
+
+	return model.OutboxMessage{
+		Topic:       model.TopicLoanExpired,
+		RecipientID: withdraw.InvestorID,
+		Payload:     payload,
+	}
+}
+
+// signatureRequestMessage build the outbox message that sends the signature token to the borrower,
+// the token is the secret of the message so it is not kept once the message is sent
+func signatureRequestMessage(loan model.Loan, token string, expiresAt time.Time) model.OutboxMessage {
+	payload, _ := json.Marshal(model.Message{
+		Subject: fmt.Sprintf("Sign the agreement letter of loan %d", loan.ID),
+		Body: fmt.Sprintf("Please sign the agreement letter %s of loan %d before %s with this token, keep it secret:",
+			loan.AgreementLetterVersion, loan.ID, expiresAt.Format(time.RFC1123)),
+		LoanID: loan.ID,
+		Secret: token,
+	})
+
+	return model.OutboxMessage{
+		Topic:       model.TopicSignatureRequest,
+		RecipientID: loan.BorrowerID,
+		Payload:     payload,
+	}
+}
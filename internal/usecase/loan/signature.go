@@ -0,0 +1,176 @@
+package loan
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"simple-app/internal/model"
+	"simple-app/internal/pkg/reqctx"
+	"time"
+)
+
+// DefaultSignatureTTL is how long the borrower can use a signature token
+const DefaultSignatureTTL = 72 * time.Hour
+
+// RequestSignature issue a token for the borrower to sign the current agreement letter of loan at the given version.
+// The token is only sent to the borrower through the outbox, the database keeps its SHA-256
+func (u *Usecase) RequestSignature(ctx context.Context, id, version int) (request model.SignatureRequest, err error) {
+	loan, err := u.loanRepo.GetByID(ctx, id)
+	if err != nil {
+		return request, err
+	}
+
+	err = checkVersion(loan, version)
+	if err != nil {
+		return request, err
+	}
+
+	ctx = reqctx.WithActor(ctx, "borrower", loan.BorrowerID)
+
+	if !signable(loan.Status) {
+		return request, model.ErrInvalidTransition
+	}
+
+	// loans created before agreement letters are hashed have nothing to sign
+	if loan.AgreementLetterSHA256 == "" {
+		return request, model.ErrAgreementMismatch
+	}
+
+	plain, hash, err := newSignatureToken()
+	if err != nil {
+		return request, err
+	}
+
+	expiresAt := time.Now().Add(DefaultSignatureTTL)
+	err = u.tx.WithTx(ctx, func(ctx context.Context) error {
+		err := u.loanRepo.BumpVersion(ctx, loan.ID, version)
+		if err != nil {
+			return err
+		}
+
+		_, err = u.loanRepo.CreateSignature(ctx, model.Signature{
+			LoanID:                 loan.ID,
+			BorrowerID:             loan.BorrowerID,
+			TokenHash:              hash,
+			AgreementLetterSHA256:  loan.AgreementLetterSHA256,
+			AgreementLetterVersion: loan.AgreementLetterVersion,
+			ExpiresAt:              &expiresAt,
+		})
+		if err != nil {
+			return err
+		}
+
+		// the token is only sent once the signature is committed
+		return u.outbox.Enqueue(ctx, []model.OutboxMessage{signatureRequestMessage(loan, plain, expiresAt)})
+	})
+	if err != nil {
+		return request, err
+	}
+
+	return model.SignatureRequest{
+		LoanID:    loan.ID,
+		ExpiresAt: &expiresAt,
+	}, nil
+}
+
+// Sign record the signature of borrower on the agreement letter of loan. The token must be issued for the loan,
+// unused and not expired, and the letter the borrower has read must be the current agreement letter of loan
+// at the version of param
+func (u *Usecase) Sign(ctx context.Context, param model.Sign) (data model.Signature, err error) {
+	err = u.tx.WithTx(ctx, func(ctx context.Context) error {
+		signature, err := u.loanRepo.GetSignatureByTokenForUpdate(ctx, hashSignatureToken(param.Token))
+		if errors.Is(err, model.ErrNotFound) {
+			return model.ErrInvalidSignatureToken
+		}
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		if signature.LoanID != param.LoanID || signature.SignedAt != nil || signature.ExpiresAt == nil || !now.Before(*signature.ExpiresAt) {
+			return model.ErrInvalidSignatureToken
+		}
+
+		ctx = reqctx.WithActor(ctx, "borrower", signature.BorrowerID)
+
+		// lock the loan so its agreement letter does not change while signing
+		loan, err := u.loanRepo.GetByIDForUpdate(ctx, param.LoanID)
+		if err != nil {
+			return err
+		}
+
+		err = checkVersion(loan, param.Version)
+		if err != nil {
+			return err
+		}
+
+		if !signable(loan.Status) {
+			return model.ErrInvalidTransition
+		}
+
+		if param.AgreementLetterSHA256 != loan.AgreementLetterSHA256 ||
+			signature.AgreementLetterSHA256 != loan.AgreementLetterSHA256 ||
+			signature.AgreementLetterVersion != loan.AgreementLetterVersion {
+			return model.ErrAgreementMismatch
+		}
+
+		signature.SignerName = param.SignerName
+		signature.SignerIP = param.SignerIP
+		signature.SignedAt = &now
+
+		document, err := u.agreementLetter.Certify(ctx, loan, signature)
+		if err != nil {
+			return err
+		}
+
+		signature.SignedDocumentSHA256 = document.SHA256
+		signature.SignedDocumentKey = document.Key
+
+		data, err = u.loanRepo.Sign(ctx, signature)
+		if errors.Is(err, model.ErrNotFound) {
+			return model.ErrInvalidSignatureToken
+		}
+		if err != nil {
+			return err
+		}
+
+		return u.loanRepo.BumpVersion(ctx, loan.ID, param.Version)
+	})
+	if err != nil {
+		return data, err
+	}
+
+	data.SignedDocumentURL = u.link(data.SignedDocumentKey)
+
+	return data, nil
+}
+
+// signable, the letter can be signed until the loan is disbursed, and not after the loan is closed
+func signable(status model.LoanStatus) bool {
+	switch status {
+	case model.PROPOSED, model.APPROVED, model.INVESTED:
+		return true
+	}
+
+	return false
+}
+
+// newSignatureToken generate a random token and its SHA-256
+func newSignatureToken() (token, hash string, err error) {
+	b := make([]byte, 32)
+	_, err = rand.Read(b)
+	if err != nil {
+		return token, hash, err
+	}
+
+	token = base64.RawURLEncoding.EncodeToString(b)
+	return token, hashSignatureToken(token), nil
+}
+
+func hashSignatureToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
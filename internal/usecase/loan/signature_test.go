@@ -0,0 +1,337 @@
+package loan
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"simple-app/internal/model"
+	"simple-app/internal/repository/loan/memory"
+
+	"github.com/stretchr/testify/require"
+)
+
+// fakeFiles are the URLs of uploaded files
+type fakeFiles map[string]model.FileKind
+
+func (f fakeFiles) Verify(ctx context.Context, url string, kind model.FileKind) (key string, err error) {
+	if f[url] != kind {
+		return key, model.ErrInvalidFile
+	}
+	return strings.TrimPrefix(url, "http://localhost:4040/files/"), nil
+}
+
+func (f fakeFiles) DownloadURL(key string) string {
+	return "http://localhost:4040/files/" + key + "?signature=valid"
+}
+
+var (
+	letterSHA256 = strings.Repeat("a", 64)
+	errOutbox    = errors.New("outbox is down")
+)
+
+// requestSignature request the signature of loan 1 and return the token sent to its borrower
+func requestSignature(t *testing.T, uc *Usecase) string {
+	t.Helper()
+
+	request, err := uc.RequestSignature(context.Background(), 1, loanVersion(t, uc, 1))
+	require.NoError(t, err)
+	require.Equal(t, 1, request.LoanID)
+
+	return sentToken(t, uc)
+}
+
+// sign request the signature of loan 1 and sign it as its borrower
+func sign(t *testing.T, uc *Usecase) {
+	t.Helper()
+
+	token := requestSignature(t, uc)
+	_, err := uc.Sign(context.Background(), model.Sign{LoanID: 1, Token: token, SignerName: "Budi", AgreementLetterSHA256: letterSHA256, Version: loanVersion(t, uc, 1)})
+	require.NoError(t, err)
+}
+
+// loanVersion is the current version of loan, the ETag a client sends back in If-Match
+func loanVersion(t *testing.T, uc *Usecase, id int) int {
+	t.Helper()
+
+	loan, err := uc.loanRepo.GetByID(context.Background(), id)
+	require.NoError(t, err)
+	return loan.Version
+}
+
+// sentToken is the token of the last signature request sent to the borrower of loan 1
+func sentToken(t *testing.T, uc *Usecase) string {
+	t.Helper()
+
+	outbox := uc.outbox.(*recordOutbox)
+	message := outbox.messages[len(outbox.messages)-1]
+	require.Equal(t, model.TopicSignatureRequest, message.Topic)
+	require.Equal(t, 1, message.RecipientID)
+
+	var content model.Message
+	require.NoError(t, json.Unmarshal(message.Payload, &content))
+	require.NotEmpty(t, content.Secret)
+	require.NotContains(t, content.Body, content.Secret)
+	return content.Secret
+}
+
+func TestRequestSignature(t *testing.T) {
+	testCases := []struct {
+		name      string
+		id        int
+		status    model.LoanStatus
+		version   int
+		noHash    bool
+		outboxErr error
+		wantErr   error
+	}{
+		{
+			name:   "approved loan",
+			id:     1,
+			status: model.APPROVED,
+		},
+		{
+			name:   "proposed loan",
+			id:     1,
+			status: model.PROPOSED,
+		},
+		{
+			name:    "stale version",
+			id:      1,
+			status:  model.APPROVED,
+			version: 2,
+			wantErr: model.ErrVersionConflict,
+		},
+		{
+			name:    "unknown loan",
+			id:      9,
+			status:  model.APPROVED,
+			wantErr: model.ErrNotFound,
+		},
+		{
+			name:    "disbursed loan",
+			id:      1,
+			status:  model.DISBURSED,
+			wantErr: model.ErrInvalidTransition,
+		},
+		{
+			name:    "loan without hashed agreement letter",
+			id:      1,
+			status:  model.APPROVED,
+			noHash:  true,
+			wantErr: model.ErrAgreementMismatch,
+		},
+		{
+			name:      "token can not be sent",
+			id:        1,
+			status:    model.APPROVED,
+			outboxErr: errOutbox,
+			wantErr:   errOutbox,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+			loan := memoryLoan(tc.status)
+			if tc.noHash {
+				loan.AgreementLetterSHA256 = ""
+			}
+			uc, repo := newMemoryUsecase(loan)
+			uc.outbox = &recordOutbox{err: tc.outboxErr}
+
+			version := tc.version
+			if version == 0 {
+				version = 1
+			}
+
+			request, err := uc.RequestSignature(ctx, tc.id, version)
+			if tc.wantErr != nil {
+				require.ErrorIs(t, err, tc.wantErr)
+				require.Empty(t, uc.outbox.(*recordOutbox).messages)
+				require.Equal(t, 1, loanVersion(t, uc, 1))
+
+				events, _ := repo.GetEventByID(ctx, 1)
+				require.Empty(t, events)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, 2, loanVersion(t, uc, 1))
+			require.Equal(t, tc.id, request.LoanID)
+			require.WithinDuration(t, time.Now().Add(DefaultSignatureTTL), *request.ExpiresAt, time.Minute)
+
+			// only the hash of the token sent to the borrower is kept
+			token := sentToken(t, uc)
+			signature, err := repo.GetSignatureByTokenForUpdate(ctx, hashSignatureToken(token))
+			require.NoError(t, err)
+			require.Equal(t, letterSHA256, signature.AgreementLetterSHA256)
+			require.Equal(t, "v1", signature.AgreementLetterVersion)
+			require.Equal(t, 1, signature.BorrowerID)
+			require.Equal(t, request.ExpiresAt, signature.ExpiresAt)
+		})
+	}
+}
+
+// createSignature create a signature request of loan 1 on the given letter version with a known token
+func createSignature(t *testing.T, repo *memory.Loan, token, version string, expiresAt time.Time) model.Signature {
+	t.Helper()
+
+	signature, err := repo.CreateSignature(context.Background(), model.Signature{
+		LoanID:                 1,
+		BorrowerID:             1,
+		TokenHash:              hashSignatureToken(token),
+		AgreementLetterSHA256:  letterSHA256,
+		AgreementLetterVersion: version,
+		ExpiresAt:              &expiresAt,
+	})
+	require.NoError(t, err)
+
+	return signature
+}
+
+func TestSign(t *testing.T) {
+	testCases := []struct {
+		name    string
+		loanID  int
+		token   func(t *testing.T, uc *Usecase, repo *memory.Loan, sent string) string
+		sha256  string
+		version int
+		wantErr error
+	}{
+		{
+			name:   "signed",
+			loanID: 1,
+			sha256: letterSHA256,
+		},
+		{
+			name:    "wrong token",
+			loanID:  1,
+			token:   func(*testing.T, *Usecase, *memory.Loan, string) string { return "wrong" },
+			sha256:  letterSHA256,
+			wantErr: model.ErrInvalidSignatureToken,
+		},
+		{
+			name:    "token of other loan",
+			loanID:  2,
+			sha256:  letterSHA256,
+			wantErr: model.ErrInvalidSignatureToken,
+		},
+		{
+			name:    "stale version",
+			loanID:  1,
+			sha256:  letterSHA256,
+			version: 1,
+			wantErr: model.ErrVersionConflict,
+		},
+		{
+			name:    "other agreement letter",
+			loanID:  1,
+			sha256:  strings.Repeat("b", 64),
+			wantErr: model.ErrAgreementMismatch,
+		},
+		{
+			name:   "expired token",
+			loanID: 1,
+			sha256: letterSHA256,
+			token: func(t *testing.T, uc *Usecase, repo *memory.Loan, sent string) string {
+				createSignature(t, repo, "expired", "v1", time.Now().Add(-time.Minute))
+				return "expired"
+			},
+			wantErr: model.ErrInvalidSignatureToken,
+		},
+		{
+			name:   "token already used",
+			loanID: 1,
+			sha256: letterSHA256,
+			token: func(t *testing.T, uc *Usecase, repo *memory.Loan, sent string) string {
+				_, err := uc.Sign(context.Background(), model.Sign{LoanID: 1, Token: sent, SignerName: "Budi", AgreementLetterSHA256: letterSHA256, Version: 2})
+				require.NoError(t, err)
+				return sent
+			},
+			wantErr: model.ErrInvalidSignatureToken,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+			other := memoryLoan(model.APPROVED)
+			other.ID = 2
+			uc, repo := newMemoryUsecase(memoryLoan(model.APPROVED), other)
+
+			token := requestSignature(t, uc)
+			require.NotEmpty(t, token)
+			if tc.token != nil {
+				token = tc.token(t, uc, repo, token)
+			}
+
+			// the signature request is a change of loan 1, it is at version 2
+			version := tc.version
+			if version == 0 {
+				version = loanVersion(t, uc, tc.loanID)
+			}
+
+			signature, err := uc.Sign(ctx, model.Sign{
+				LoanID:                tc.loanID,
+				Token:                 token,
+				SignerName:            "Budi",
+				AgreementLetterSHA256: tc.sha256,
+				Version:               version,
+			})
+			if tc.wantErr != nil {
+				require.ErrorIs(t, err, tc.wantErr)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, 3, loanVersion(t, uc, 1))
+			require.Equal(t, "Budi", signature.SignerName)
+			require.Equal(t, "signed-1", signature.SignedDocumentKey)
+			require.Equal(t, "http://localhost:4040/files/signed-1?signature=valid", signature.SignedDocumentURL)
+			require.NotNil(t, signature.SignedAt)
+
+			signed, err := repo.GetSignedSignature(ctx, 1, letterSHA256, "v1")
+			require.NoError(t, err)
+			require.Equal(t, signature.ID, signed.ID)
+		})
+	}
+}
+
+func TestDisburseRequiresSignature(t *testing.T) {
+	ctx := context.Background()
+	loan := memoryLoan(model.INVESTED)
+	loan.AgreementLetterVersion = "v2"
+	uc, repo := newMemoryUsecase(loan)
+
+	_, err := uc.Disburse(ctx, model.Disburse{LoanID: 1, DisburseEmployeeID: 9, Version: 1})
+	require.ErrorIs(t, err, model.ErrSignatureRequired)
+
+	// a signature on an older agreement letter does not count
+	now := time.Now()
+	old := createSignature(t, repo, "old", "v1", now.Add(time.Hour))
+	_, err = repo.Sign(ctx, model.Signature{ID: old.ID, SignerName: "Budi", SignedDocumentKey: "signed-old", SignedAt: &now})
+	require.NoError(t, err)
+	_, err = uc.Disburse(ctx, model.Disburse{LoanID: 1, DisburseEmployeeID: 9, Version: 1})
+	require.ErrorIs(t, err, model.ErrSignatureRequired)
+
+	disburses, err := repo.GetDisburseByID(ctx, 1)
+	require.NoError(t, err)
+	require.Empty(t, disburses)
+
+	sign(t, uc)
+	_, err = uc.Disburse(ctx, model.Disburse{LoanID: 1, DisburseEmployeeID: 9, Version: loanVersion(t, uc, 1)})
+	require.NoError(t, err)
+
+	disburses, err = repo.GetDisburseByID(ctx, 1)
+	require.NoError(t, err)
+	require.Len(t, disburses, 1)
+	require.Equal(t, "signed-1", disburses[0].SignedAgreementKey)
+
+	loan, err = repo.GetByID(ctx, 1)
+	require.NoError(t, err)
+	require.Equal(t, model.DISBURSED, loan.Status)
+}
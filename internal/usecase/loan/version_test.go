@@ -0,0 +1,94 @@
+package loan
+
+import (
+	"context"
+	"testing"
+
+	"simple-app/internal/model"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTransitionVersion(t *testing.T) {
+	testCases := []struct {
+		name        string
+		change      func(uc *Usecase, ctx context.Context, param model.StatusChange) error
+		version     int
+		wantErr     error
+		wantStatus  model.LoanStatus
+		wantVersion int
+	}{
+		{
+			name:        "reject latest version",
+			change:      (*Usecase).Reject,
+			version:     3,
+			wantStatus:  model.REJECTED,
+			wantVersion: 4,
+		},
+		{
+			name:        "reject stale version",
+			change:      (*Usecase).Reject,
+			version:     2,
+			wantErr:     model.ErrVersionConflict,
+			wantStatus:  model.PROPOSED,
+			wantVersion: 3,
+		},
+		{
+			name:        "cancel stale version",
+			change:      (*Usecase).Cancel,
+			version:     4,
+			wantErr:     model.ErrVersionConflict,
+			wantStatus:  model.PROPOSED,
+			wantVersion: 3,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+			loan := memoryLoan(model.PROPOSED)
+			loan.Version = 3
+			uc, repo := newMemoryUsecase(loan)
+
+			err := tc.change(uc, ctx, model.StatusChange{ID: 1, ActorID: 7, Reason: "duplicate", Version: tc.version})
+			if tc.wantErr != nil {
+				require.ErrorIs(t, err, tc.wantErr)
+			} else {
+				require.NoError(t, err)
+			}
+
+			loan, err = repo.GetByID(ctx, 1)
+			require.NoError(t, err)
+			require.Equal(t, tc.wantStatus, loan.Status)
+			require.Equal(t, tc.wantVersion, loan.Version)
+		})
+	}
+}
+
+func TestDisburseVersion(t *testing.T) {
+	ctx := context.Background()
+	uc, repo := newMemoryUsecase(memoryLoan(model.INVESTED))
+
+	// the client read the loan before it was signed
+	_, err := uc.RequestSignature(ctx, 1, 1)
+	require.NoError(t, err)
+	_, err = uc.Sign(ctx, model.Sign{LoanID: 1, Token: sentToken(t, uc), SignerName: "Budi", AgreementLetterSHA256: letterSHA256, Version: 2})
+	require.NoError(t, err)
+
+	_, err = uc.Disburse(ctx, model.Disburse{LoanID: 1, DisburseEmployeeID: 9, Version: 1})
+	var conflict *model.VersionConflictError
+	require.ErrorAs(t, err, &conflict)
+	require.Equal(t, 3, conflict.Actual)
+
+	disburses, err := repo.GetDisburseByID(ctx, 1)
+	require.NoError(t, err)
+	require.Empty(t, disburses)
+
+	_, err = uc.Disburse(ctx, model.Disburse{LoanID: 1, DisburseEmployeeID: 9, Version: 3})
+	require.NoError(t, err)
+
+	loan, err := repo.GetByID(ctx, 1)
+	require.NoError(t, err)
+	require.Equal(t, model.DISBURSED, loan.Status)
+	require.Equal(t, 4, loan.Version)
+}
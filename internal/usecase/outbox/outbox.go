@@ -0,0 +1,226 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"simple-app/internal/model"
+	"time"
+)
+
+const (
+	// DefaultMaxAttempts is how many times a message is tried before it is dead
+	DefaultMaxAttempts = 5
+	// DefaultBatchSize is how many messages are claimed per dispatch
+	DefaultBatchSize = 50
+
+	baseBackoff = 30 * time.Second
+	maxBackoff  = time.Hour
+	// claimLease is how long claimed messages are left to one dispatcher, they are claimed again after it
+	claimLease = 10 * time.Minute
+)
+
+// Usecase instance struct for outbox
+type Usecase struct {
+	tx          txManager
+	outboxRepo  outboxRepo
+	channels    map[string]Channel
+	routes      map[string]string
+	links       links
+	maxAttempts int
+	batchSize   int
+}
+
+// txManager runs fn inside a transaction, repositories called with the ctx given to fn join the transaction
+type txManager interface {
+	WithTx(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+type outboxRepo interface {
+	ClaimDue(ctx context.Context, now, until time.Time, limit int) (messages []model.OutboxMessage, err error)
+
+	Create(ctx context.Context, messages []model.OutboxMessage) (err error)
+	Update(ctx context.Context, message model.OutboxMessage) (err error)
+}
+
+// links make the download URL of the attachment of message
+type links interface {
+	DownloadURL(key string) string
+}
+
+// Channel deliver message to its recipient, e.g. by email
+type Channel interface {
+	Deliver(ctx context.Context, message model.OutboxMessage) error
+}
+
+// New will instantiate new outbox usecase, routes maps the topic of message to the name of channel in channels.
+// Links make the download URL of attachments, it can be nil when no message has one
+func New(tx txManager, outboxRepo outboxRepo, channels map[string]Channel, routes map[string]string, links links, maxAttempts, batchSize int) *Usecase {
+	if maxAttempts <= 0 {
+		maxAttempts = DefaultMaxAttempts
+	}
+	if batchSize <= 0 {
+		batchSize = DefaultBatchSize
+	}
+
+	return &Usecase{
+		tx:          tx,
+		outboxRepo:  outboxRepo,
+		channels:    channels,
+		routes:      routes,
+		links:       links,
+		maxAttempts: maxAttempts,
+		batchSize:   batchSize,
+	}
+}
+
+// Enqueue write messages inside the transaction of the caller, so they are only delivered when it commits
+func (u *Usecase) Enqueue(ctx context.Context, messages []model.OutboxMessage) (err error) {
+	if len(messages) == 0 {
+		return nil
+	}
+
+	for i := range messages {
+		if messages[i].Channel == "" {
+			messages[i].Channel = u.routes[messages[i].Topic]
+		}
+		if messages[i].Channel == "" {
+			return fmt.Errorf("no channel for topic %q", messages[i].Topic)
+		}
+		if messages[i].MaxAttempts <= 0 {
+			messages[i].MaxAttempts = u.maxAttempts
+		}
+	}
+
+	return u.outboxRepo.Create(ctx, messages)
+}
+
+// Dispatch deliver the messages that are due. A failed message is tried again after a backoff
+// that doubles every attempt, and is dead once it runs out of attempts.
+// The messages are claimed in a short transaction, then every message is delivered outside of it and its state
+// is recorded in its own transaction, so a failed update does not send the others again.
+// Delivery is at least once, a message whose state fails to commit is claimed again once its claim runs out
+func (u *Usecase) Dispatch(ctx context.Context, now time.Time) (sent, dead int, err error) {
+	var messages []model.OutboxMessage
+	err = u.tx.WithTx(ctx, func(ctx context.Context) (err error) {
+		messages, err = u.outboxRepo.ClaimDue(ctx, now, now.Add(claimLease), u.batchSize)
+		return err
+	})
+	if err != nil {
+		return 0, 0, err
+	}
+
+	for _, message := range messages {
+		message = u.deliver(ctx, message, now)
+
+		updateErr := u.tx.WithTx(ctx, func(ctx context.Context) error {
+			return u.outboxRepo.Update(ctx, message)
+		})
+		if updateErr != nil {
+			log.Printf("failed to record outbox message %d, it is tried again after its claim, err = %s", message.ID, updateErr)
+			if err == nil {
+				err = updateErr
+			}
+			continue
+		}
+
+		switch message.Status {
+		case model.OutboxSent:
+			sent++
+		case model.OutboxDead:
+			dead++
+			log.Printf("outbox message %d is dead after %d attempts, err = %s", message.ID, message.Attempts, message.LastError)
+		}
+	}
+
+	return sent, dead, err
+}
+
+// deliver try the message once and return its new state
+func (u *Usecase) deliver(ctx context.Context, message model.OutboxMessage, now time.Time) model.OutboxMessage {
+	message.Attempts++
+
+	err := fmt.Errorf("unknown channel %q", message.Channel)
+	if ch, ok := u.channels[message.Channel]; ok {
+		err = ch.Deliver(ctx, u.attach(message))
+	}
+
+	if err == nil {
+		message.Status = model.OutboxSent
+		message.LastError = ""
+		message.SentAt = &now
+		return redact(message)
+	}
+
+	message.LastError = err.Error()
+	if message.Attempts >= message.MaxAttempts {
+		message.Status = model.OutboxDead
+		return redact(message)
+	}
+
+	next := now.Add(Backoff(message.Attempts))
+	message.NextAttemptAt = &next
+	return message
+}
+
+// attach put the download URL of the attachment and the secret in the payload of the delivered copy,
+// the URL is made at every delivery because it expires. The message is kept with the key only
+func (u *Usecase) attach(message model.OutboxMessage) model.OutboxMessage {
+	var content model.Message
+	if json.Unmarshal(message.Payload, &content) != nil {
+		return message
+	}
+
+	attached := false
+	if u.links != nil && content.AttachmentKey != "" {
+		content.AttachmentURL = u.links.DownloadURL(content.AttachmentKey)
+		attached = true
+	}
+	if content.Secret != "" {
+		content.Body += "\n" + content.Secret
+		content.Secret = ""
+		attached = true
+	}
+	if !attached {
+		return message
+	}
+
+	payload, err := json.Marshal(content)
+	if err != nil {
+		return message
+	}
+
+	message.Payload = payload
+	return message
+}
+
+// redact remove the secret from the stored payload once the message is sent or dead, it is not needed anymore
+func redact(message model.OutboxMessage) model.OutboxMessage {
+	var content model.Message
+	if json.Unmarshal(message.Payload, &content) != nil || content.Secret == "" {
+		return message
+	}
+
+	content.Secret = ""
+	payload, err := json.Marshal(content)
+	if err != nil {
+		return message
+	}
+
+	message.Payload = payload
+	return message
+}
+
+// Backoff is the wait after the given failed attempt, 30s, 1m, 2m, ... up to 1h
+func Backoff(attempt int) time.Duration {
+	backoff := baseBackoff
+	for i := 1; i < attempt; i++ {
+		backoff *= 2
+		if backoff >= maxBackoff {
+			return maxBackoff
+		}
+	}
+
+	return backoff
+}
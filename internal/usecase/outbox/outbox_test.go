@@ -0,0 +1,318 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"simple-app/internal/model"
+
+	"github.com/stretchr/testify/require"
+)
+
+// fakeTx runs fn without a transaction, the fake repository has nothing to roll back
+type fakeTx struct{}
+
+func (fakeTx) WithTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	return fn(ctx)
+}
+
+// fakeRepo keeps messages and their claims in memory, the update of a message in failUpdate fails
+type fakeRepo struct {
+	messages   []model.OutboxMessage
+	claims     map[int]time.Time
+	failUpdate map[int]bool
+}
+
+func newFakeRepo(messages ...model.OutboxMessage) *fakeRepo {
+	return &fakeRepo{
+		messages:   messages,
+		claims:     map[int]time.Time{},
+		failUpdate: map[int]bool{},
+	}
+}
+
+func (r *fakeRepo) ClaimDue(ctx context.Context, now, until time.Time, limit int) (messages []model.OutboxMessage, err error) {
+	for _, message := range r.messages {
+		claimed, ok := r.claims[message.ID]
+		if message.Status == model.OutboxPending && !message.NextAttemptAt.After(now) && (!ok || !claimed.After(now)) && len(messages) < limit {
+			r.claims[message.ID] = until
+			messages = append(messages, message)
+		}
+	}
+	return messages, nil
+}
+
+func (r *fakeRepo) Create(ctx context.Context, messages []model.OutboxMessage) error {
+	for _, message := range messages {
+		message.ID = len(r.messages) + 1
+		message.Status = model.OutboxPending
+		r.messages = append(r.messages, message)
+	}
+	return nil
+}
+
+func (r *fakeRepo) Update(ctx context.Context, message model.OutboxMessage) error {
+	if r.failUpdate[message.ID] {
+		return errors.New("connection reset")
+	}
+
+	delete(r.claims, message.ID)
+	for i := range r.messages {
+		if r.messages[i].ID == message.ID {
+			r.messages[i] = message
+		}
+	}
+	return nil
+}
+
+// fakeChannel fails the first failures deliveries
+type fakeChannel struct {
+	failures  int
+	delivered []int
+	payloads  []json.RawMessage
+}
+
+func (c *fakeChannel) Deliver(ctx context.Context, message model.OutboxMessage) error {
+	if c.failures > 0 {
+		c.failures--
+		return errors.New("smtp is down")
+	}
+	c.delivered = append(c.delivered, message.ID)
+	c.payloads = append(c.payloads, message.Payload)
+	return nil
+}
+
+// fakeLinks make a download URL that changes at every call, like one that expires
+type fakeLinks struct {
+	calls int
+}
+
+func (l *fakeLinks) DownloadURL(key string) string {
+	l.calls++
+	return fmt.Sprintf("http://localhost:4040/files/%s?expires=%d", key, l.calls)
+}
+
+func TestDispatch(t *testing.T) {
+	now := time.Date(2026, 10, 18, 10, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		name         string
+		channel      string
+		failures     int
+		attempts     int
+		wantStatus   model.OutboxStatus
+		wantAttempts int
+		wantNext     time.Time
+		wantSent     int
+		wantDead     int
+	}{
+		{
+			name:         "delivered",
+			channel:      "email",
+			wantStatus:   model.OutboxSent,
+			wantAttempts: 1,
+			wantNext:     now,
+			wantSent:     1,
+		},
+		{
+			name:         "retried with backoff",
+			channel:      "email",
+			failures:     1,
+			attempts:     2,
+			wantStatus:   model.OutboxPending,
+			wantAttempts: 3,
+			wantNext:     now.Add(2 * time.Minute),
+		},
+		{
+			name:         "dead after last attempt",
+			channel:      "email",
+			failures:     1,
+			attempts:     4,
+			wantStatus:   model.OutboxDead,
+			wantAttempts: 5,
+			wantNext:     now,
+			wantDead:     1,
+		},
+		{
+			name:         "unknown channel",
+			channel:      "pigeon",
+			wantStatus:   model.OutboxPending,
+			wantAttempts: 1,
+			wantNext:     now.Add(30 * time.Second),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			repo := newFakeRepo(model.OutboxMessage{
+				ID:            1,
+				Topic:         model.TopicAgreementLetter,
+				Channel:       tc.channel,
+				RecipientID:   7,
+				Status:        model.OutboxPending,
+				Attempts:      tc.attempts,
+				MaxAttempts:   5,
+				NextAttemptAt: &now,
+			})
+			ch := &fakeChannel{failures: tc.failures}
+			uc := New(fakeTx{}, repo, map[string]Channel{"email": ch}, nil, nil, 0, 0)
+
+			sent, dead, err := uc.Dispatch(context.Background(), now)
+			require.NoError(t, err)
+			require.Equal(t, tc.wantSent, sent)
+			require.Equal(t, tc.wantDead, dead)
+
+			message := repo.messages[0]
+			require.Equal(t, tc.wantStatus, message.Status)
+			require.Equal(t, tc.wantAttempts, message.Attempts)
+			require.Equal(t, tc.wantNext, *message.NextAttemptAt)
+			if tc.wantStatus == model.OutboxSent {
+				require.Empty(t, message.LastError)
+				require.NotNil(t, message.SentAt)
+			} else {
+				require.NotEmpty(t, message.LastError)
+			}
+		})
+	}
+}
+
+func TestDispatchSkipsMessagesNotDue(t *testing.T) {
+	now := time.Date(2026, 10, 18, 10, 0, 0, 0, time.UTC)
+	later := now.Add(time.Minute)
+
+	repo := newFakeRepo(model.OutboxMessage{ID: 1, Channel: "email", Status: model.OutboxPending, MaxAttempts: 5, NextAttemptAt: &later})
+	ch := &fakeChannel{}
+	uc := New(fakeTx{}, repo, map[string]Channel{"email": ch}, nil, nil, 0, 0)
+
+	sent, _, err := uc.Dispatch(context.Background(), now)
+	require.NoError(t, err)
+	require.Zero(t, sent)
+	require.Empty(t, ch.delivered)
+}
+
+func TestDispatchUpdateFails(t *testing.T) {
+	now := time.Date(2026, 10, 18, 10, 0, 0, 0, time.UTC)
+
+	repo := newFakeRepo(
+		model.OutboxMessage{ID: 1, Channel: "email", Status: model.OutboxPending, MaxAttempts: 5, NextAttemptAt: &now},
+		model.OutboxMessage{ID: 2, Channel: "email", Status: model.OutboxPending, MaxAttempts: 5, NextAttemptAt: &now},
+	)
+	repo.failUpdate[1] = true
+	ch := &fakeChannel{}
+	uc := New(fakeTx{}, repo, map[string]Channel{"email": ch}, nil, nil, 0, 0)
+
+	// the other message is still recorded as sent
+	sent, _, err := uc.Dispatch(context.Background(), now)
+	require.Error(t, err)
+	require.Equal(t, 1, sent)
+	require.Equal(t, []int{1, 2}, ch.delivered)
+	require.Equal(t, model.OutboxPending, repo.messages[0].Status)
+	require.Equal(t, model.OutboxSent, repo.messages[1].Status)
+
+	// the message stays claimed until its claim runs out, then it is delivered again
+	repo.failUpdate[1] = false
+	sent, _, err = uc.Dispatch(context.Background(), now.Add(time.Minute))
+	require.NoError(t, err)
+	require.Zero(t, sent)
+
+	sent, _, err = uc.Dispatch(context.Background(), now.Add(claimLease))
+	require.NoError(t, err)
+	require.Equal(t, 1, sent)
+	require.Equal(t, []int{1, 2, 1}, ch.delivered)
+	require.Equal(t, model.OutboxSent, repo.messages[0].Status)
+}
+
+func TestDispatchAttachment(t *testing.T) {
+	now := time.Date(2026, 10, 18, 10, 0, 0, 0, time.UTC)
+	payload, err := json.Marshal(model.Message{Subject: "Agreement letter of loan 1", AttachmentKey: "agreement-letters/v1/a.pdf"})
+	require.NoError(t, err)
+
+	repo := newFakeRepo(model.OutboxMessage{ID: 1, Channel: "email", Payload: payload, Status: model.OutboxPending, MaxAttempts: 5, NextAttemptAt: &now})
+	ch := &fakeChannel{failures: 1}
+	uc := New(fakeTx{}, repo, map[string]Channel{"email": ch}, nil, &fakeLinks{}, 0, 0)
+
+	// the URL is made again for the retry, and the message keeps the key only
+	_, _, err = uc.Dispatch(context.Background(), now)
+	require.NoError(t, err)
+	sent, _, err := uc.Dispatch(context.Background(), now.Add(time.Hour))
+	require.NoError(t, err)
+	require.Equal(t, 1, sent)
+	require.JSONEq(t, string(payload), string(repo.messages[0].Payload))
+
+	var content model.Message
+	require.NoError(t, json.Unmarshal(ch.payloads[0], &content))
+	require.Equal(t, "agreement-letters/v1/a.pdf", content.AttachmentKey)
+	require.Equal(t, "http://localhost:4040/files/agreement-letters/v1/a.pdf?expires=2", content.AttachmentURL)
+}
+
+func TestDispatchSecret(t *testing.T) {
+	now := time.Date(2026, 10, 18, 10, 0, 0, 0, time.UTC)
+	payload, err := json.Marshal(model.Message{Subject: "Sign the agreement letter of loan 1", Body: "Sign with this token:", Secret: "s3cr3t-token"})
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name       string
+		failures   int
+		wantStatus model.OutboxStatus
+	}{
+		{name: "sent", failures: 1, wantStatus: model.OutboxSent},
+		{name: "dead", failures: 2, wantStatus: model.OutboxDead},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			repo := newFakeRepo(model.OutboxMessage{ID: 1, Channel: "email", Payload: payload, Status: model.OutboxPending, MaxAttempts: 2, NextAttemptAt: &now})
+			ch := &fakeChannel{failures: tc.failures}
+			uc := New(fakeTx{}, repo, map[string]Channel{"email": ch}, nil, nil, 0, 0)
+
+			// the secret is kept for the retry
+			_, _, err := uc.Dispatch(context.Background(), now)
+			require.NoError(t, err)
+			require.JSONEq(t, string(payload), string(repo.messages[0].Payload))
+
+			_, _, err = uc.Dispatch(context.Background(), now.Add(time.Hour))
+			require.NoError(t, err)
+			require.Equal(t, tc.wantStatus, repo.messages[0].Status)
+			require.NotContains(t, string(repo.messages[0].Payload), "s3cr3t-token")
+
+			var content model.Message
+			require.NoError(t, json.Unmarshal(repo.messages[0].Payload, &content))
+			require.Equal(t, "Sign with this token:", content.Body)
+
+			for _, delivered := range ch.payloads {
+				require.NoError(t, json.Unmarshal(delivered, &content))
+				require.Equal(t, "Sign with this token:\ns3cr3t-token", content.Body)
+				require.Empty(t, content.Secret)
+			}
+		})
+	}
+}
+
+func TestEnqueue(t *testing.T) {
+	repo := newFakeRepo()
+	uc := New(fakeTx{}, repo, nil, map[string]string{model.TopicAgreementLetter: "email"}, nil, 3, 0)
+
+	err := uc.Enqueue(context.Background(), []model.OutboxMessage{
+		{Topic: model.TopicAgreementLetter, RecipientID: 1},
+		{Topic: model.TopicAgreementLetter, Channel: "webhook", RecipientID: 2},
+	})
+	require.NoError(t, err)
+	require.Len(t, repo.messages, 2)
+	require.Equal(t, "email", repo.messages[0].Channel)
+	require.Equal(t, "webhook", repo.messages[1].Channel)
+	require.Equal(t, 3, repo.messages[0].MaxAttempts)
+
+	err = uc.Enqueue(context.Background(), []model.OutboxMessage{{Topic: "unknown", RecipientID: 1}})
+	require.Error(t, err)
+}
+
+func TestBackoff(t *testing.T) {
+	require.Equal(t, 30*time.Second, Backoff(1))
+	require.Equal(t, time.Minute, Backoff(2))
+	require.Equal(t, 4*time.Minute, Backoff(4))
+	require.Equal(t, time.Hour, Backoff(10))
+}
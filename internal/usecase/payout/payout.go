@@ -0,0 +1,69 @@
+package payout
+
+import (
+	"context"
+	"simple-app/internal/model"
+	"simple-app/internal/pkg/money"
+	"simple-app/internal/pkg/prorata"
+)
+
+// Usecase instance struct for payout
+type Usecase struct {
+	payoutRepo payoutRepo
+}
+
+type payoutRepo interface {
+	GetByInvestorID(ctx context.Context, ID int) (payouts []model.Payout, err error)
+
+	Create(ctx context.Context, payouts []model.Payout) (err error)
+}
+
+// New will instantiate new payout usecase
+func New(payoutRepo payoutRepo) *Usecase {
+	return &Usecase{
+		payoutRepo: payoutRepo,
+	}
+}
+
+// Distribute split the principal of a repayment to the investors of the loan, proportional to their amount.
+// Every investor also gets return of the loan's ROI over the principal they get back.
+// It must be called inside the repayment transaction so payouts are never recorded for a rolled back repayment
+func (u *Usecase) Distribute(ctx context.Context, loan model.Loan, invests []model.Invest, repayment model.Repayment) (payouts []model.Payout, err error) {
+	if !repayment.PrincipalAmount.IsPositive() || len(invests) == 0 {
+		return payouts, nil
+	}
+
+	weights := make([]money.Money, len(invests))
+	for i, invest := range invests {
+		weights[i] = invest.Amount
+	}
+
+	returnAmount := repayment.PrincipalAmount.MulRate(loan.Roi)
+
+	principals := prorata.Split(repayment.PrincipalAmount, weights)
+	returns := prorata.Split(returnAmount, weights)
+
+	for i, invest := range invests {
+		payouts = append(payouts, model.Payout{
+			LoanID:          loan.ID,
+			RepaymentID:     repayment.ID,
+			InvestID:        invest.ID,
+			InvestorID:      invest.InvestorID,
+			PrincipalAmount: principals[i],
+			ReturnAmount:    returns[i],
+			Amount:          principals[i].Add(returns[i]),
+		})
+	}
+
+	err = u.payoutRepo.Create(ctx, payouts)
+	if err != nil {
+		return payouts, err
+	}
+
+	return payouts, nil
+}
+
+// GetByInvestor get list of payouts received by investor
+func (u *Usecase) GetByInvestor(ctx context.Context, investorID int) (payouts []model.Payout, err error) {
+	return u.payoutRepo.GetByInvestorID(ctx, investorID)
+}
@@ -3,6 +3,7 @@ module simple-app/pkg/dbmigrator
 go 1.18
 
 require (
+	github.com/go-sql-driver/mysql v1.6.0
 	github.com/jmoiron/sqlx v1.3.5
 	github.com/joho/godotenv v1.5.1
 	github.com/lib/pq v1.10.9
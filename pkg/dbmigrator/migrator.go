@@ -13,6 +13,7 @@ import (
 	"strings"
 	"time"
 
+	_ "github.com/go-sql-driver/mysql"
 	"github.com/jmoiron/sqlx"
 	"github.com/joho/godotenv"
 	_ "github.com/lib/pq"
@@ -31,6 +32,8 @@ type dbMigration struct {
 var (
 	db      *sqlx.DB
 	sqlPath = ""
+	// driver is postgres or mysql, set by DBM_DRIVER
+	driver = "postgres"
 )
 
 func main() {
@@ -38,6 +41,9 @@ func main() {
 	ctx := context.Background()
 
 	sqlPath = os.Getenv("DBM_SQL_PATH")
+	if d := os.Getenv("DBM_DRIVER"); d != "" {
+		driver = d
+	}
 
 	switch true {
 	case len(args) >= 1 && args[0] == "create":
@@ -59,6 +65,16 @@ func createChangelogTable() error {
 	);
 	`
 
+	if driver == "mysql" {
+		q = `CREATE TABLE IF NOT EXISTS db_migration (
+			id BIGINT AUTO_INCREMENT PRIMARY KEY,
+			name VARCHAR(255) NOT NULL,
+			created_time DATETIME,
+			CONSTRAINT unique_db_migration_name UNIQUE (name)
+		)
+		`
+	}
+
 	_, err := db.ExecContext(context.Background(), q)
 	return err
 }
@@ -102,7 +118,7 @@ func runMigration(ctx context.Context) {
 		}
 	}
 
-	db, err = sqlx.Connect("postgres", masterDB)
+	db, err = sqlx.Connect(driver, masterDB)
 	if err != nil {
 		log.Fatal("Could not get Database connection :" + err.Error())
 		return
@@ -174,7 +190,7 @@ func executeSQLFile(sqlFile string) error {
 
 func isFileMigrated(name string) (bool, error) {
 	data := dbMigration{}
-	err := db.Get(&data, `SELECT id FROM db_migration WHERE name = $1`, name)
+	err := db.Get(&data, db.Rebind(`SELECT id FROM db_migration WHERE name = ?`), name)
 	if err != nil && err != sql.ErrNoRows {
 		return false, err
 	}